@@ -4,13 +4,20 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"os"
 	"strconv"
 	"time"
 
 	adapters "example.com/practice/fiber/internal/adapters/auth/jwt"
+	"example.com/practice/fiber/internal/adapters/auth/oidc"
 	http "example.com/practice/fiber/internal/adapters/http/handlers"
 	"example.com/practice/fiber/internal/adapters/http/middleware"
+	"example.com/practice/fiber/internal/adapters/memory"
+	"example.com/practice/fiber/internal/adapters/notify"
+	"example.com/practice/fiber/internal/adapters/password"
 	"example.com/practice/fiber/internal/adapters/repo"
+	"example.com/practice/fiber/internal/domain"
+	"example.com/practice/fiber/internal/ports"
 	usecaseBook "example.com/practice/fiber/internal/usecase/book"
 	usecaseUser "example.com/practice/fiber/internal/usecase/user"
 	"example.com/practice/fiber/pkg"
@@ -26,9 +33,10 @@ func main() {
 	}
 	app := fiber.New()
 	app.Use(cors.New(cors.Config{
-		AllowOrigins: "http://localhost:3000",
-		AllowMethods: "GET,POST,HEAD,PUT,DELETE",
-		AllowHeaders: "Origin, Content-Type, Accept",
+		AllowOrigins:  "http://localhost:3000",
+		AllowMethods:  "GET,POST,HEAD,PUT,PATCH,DELETE",
+		AllowHeaders:  "Origin, Content-Type, Accept, If-Match, If-None-Match",
+		ExposeHeaders: "ETag, Location, Accept-Patch",
 	}))
 	cfgPool, err := pgxpool.ParseConfig(fmt.Sprintf("postgres://%s:%s@%s:%d/%s?%s",
 		cfg.DB.User, cfg.DB.Password, cfg.DB.Host, cfg.DB.Port, cfg.DB.DBName, cfg.DB.Options))
@@ -38,26 +46,214 @@ func main() {
 	cfgPool.MaxConns = 10
 	cfgPool.MinConns = 1
 	cfgPool.MaxConnLifetime = time.Hour
-	pool, err := pgxpool.NewWithConfig(context.Background(), cfgPool)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pool, err := pgxpool.NewWithConfig(ctx, cfgPool)
 	if err != nil {
 		log.Fatal(err)
 	}
 	defer pool.Close()
 
 	appV1 := app.Group("/v1")
-	authProvider := adapters.NewProvider([]byte(cfg.Auth.JWT.Secret), 15*time.Minute)
-	appProtectV1 := app.Group("/v1/auth", middleware.NewAuthMiddleware(authProvider).Protect)
+	accessTTL := cfg.Auth.JWT.AccessTTL
+	if accessTTL == 0 {
+		accessTTL = 15 * time.Minute
+	}
+	refreshTTL := cfg.Auth.JWT.RefreshTTL
+	if refreshTTL == 0 {
+		refreshTTL = 30 * 24 * time.Hour
+	}
+	resetTTL := cfg.Auth.PasswordReset.TTL
+	if resetTTL == 0 {
+		resetTTL = 30 * time.Minute
+	}
+	verificationTTL := cfg.Auth.EmailVerification.TTL
+	if verificationTTL == 0 {
+		verificationTTL = 48 * time.Hour
+	}
+	mfaIssuer := cfg.Auth.MFA.Issuer
+	if mfaIssuer == "" {
+		mfaIssuer = cfg.App.Name
+	}
+	authProvider, err := newTokenProvider(cfg.Auth.JWT, accessTTL)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var revocationStore ports.TokenRevocationStore = repo.NewTokenRevocationRepo(pool)
+	if cfg.Auth.Revocation.Store == "memory" {
+		revocationStore = memory.NewTokenRevocationStore()
+	}
+	authMiddleware := middleware.NewAuthMiddleware(authProvider)
+	authMiddleware.Revocations = revocationStore
+	if len(cfg.Auth.RBAC.Roles) > 0 {
+		authMiddleware.Policy = domain.RolePolicy(cfg.Auth.RBAC.Roles)
+	}
+	http.NewJWKSHandler(authProvider).RegisterRoutes(app)
+	appProtectV1 := app.Group("/v1/auth", authMiddleware.Protect)
+	purgeInterval := cfg.Trash.PurgeInterval
+	if purgeInterval == 0 {
+		purgeInterval = time.Hour
+	}
 	{
 		bookRepo := repo.NewBookRepo(pool)
-		bookService := usecaseBook.NewBookService(bookRepo)
-		http.NewBookHandler(bookService).RegisterRoutes(appProtectV1)
+		bookService := usecaseBook.NewBookService(bookRepo,
+			usecaseBook.WithSearch(repo.NewBookSearchRepo(pool)),
+			usecaseBook.WithTrashRetention(cfg.Trash.Retention),
+		)
+		http.NewBookHandler(bookService).RegisterRoutes(appProtectV1, authMiddleware)
+
+		go pkg.Every(ctx, purgeInterval, func(ctx context.Context) {
+			if _, err := bookService.PurgeDeletedBooks(ctx); err != nil {
+				log.Printf("book trash purge: %v", err)
+			}
+		})
+	}
+
+	var notifier ports.Notifier = notify.NewLogNotifier(os.Stdout)
+	if cfg.Notifier.File != "" {
+		notifyFile, err := os.OpenFile(cfg.Notifier.File, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer notifyFile.Close()
+		notifier = notify.NewLogNotifier(notifyFile)
+	}
+
+	var loginAttempts ports.LoginAttemptStore = repo.NewLoginAttemptRepo(pool)
+	if cfg.Auth.Lockout.Store == "memory" {
+		loginAttempts = memory.NewLoginAttemptStore()
+	}
+
+	var breachedPasswords ports.BreachedPasswordChecker
+	if cfg.Auth.PasswordPolicy.BreachedPasswordsFile != "" {
+		breachedPasswords, err = password.NewBreachedPasswordFile(cfg.Auth.PasswordPolicy.BreachedPasswordsFile)
+		if err != nil {
+			log.Fatal(err)
+		}
 	}
 
 	{
 		userRepo := repo.NewUserRepo(pool)
-		userService := usecaseUser.NewUserService(userRepo, authProvider)
+		refreshTokenRepo := repo.NewRefreshTokenRepo(pool)
+		userService := usecaseUser.NewUserService(userRepo, authProvider,
+			usecaseUser.WithPasswordHasher(passwordHasher(cfg.Auth.PasswordHashing)),
+			usecaseUser.WithPasswordPolicy(domain.PasswordPolicy{
+				MinLength:          cfg.Auth.PasswordPolicy.MinLength,
+				MaxLength:          cfg.Auth.PasswordPolicy.MaxLength,
+				RequireUpper:       cfg.Auth.PasswordPolicy.RequireUpper,
+				RequireLower:       cfg.Auth.PasswordPolicy.RequireLower,
+				RequireDigit:       cfg.Auth.PasswordPolicy.RequireDigit,
+				RequireSymbol:      cfg.Auth.PasswordPolicy.RequireSymbol,
+				ForbidPersonalInfo: cfg.Auth.PasswordPolicy.ForbidPersonalInfo,
+				History:            cfg.Auth.PasswordPolicy.History,
+			}, repo.NewPasswordHistoryRepo(pool)),
+			usecaseUser.WithBreachedPasswordCheck(breachedPasswords),
+			usecaseUser.WithRefreshTokens(refreshTokenRepo, refreshTTL),
+			usecaseUser.WithRolePolicy(authMiddleware.Policy),
+			usecaseUser.WithTokenRevocation(revocationStore, accessTTL),
+			usecaseUser.WithNotifier(notifier),
+			usecaseUser.WithPasswordReset(repo.NewPasswordResetRepo(pool), resetTTL, cfg.Auth.PasswordReset.URL),
+			usecaseUser.WithEmailVerification(repo.NewEmailVerificationRepo(pool), verificationTTL,
+				cfg.Auth.EmailVerification.URL, cfg.Auth.EmailVerification.Required),
+			usecaseUser.WithMFA(repo.NewMFARepo(pool), mfaIssuer, cfg.Auth.MFA.RequiredRoles),
+			usecaseUser.WithLoginThrottle(loginAttempts, domain.LockoutPolicy{
+				MaxAttempts:   cfg.Auth.Lockout.MaxAttempts,
+				IPMaxAttempts: cfg.Auth.Lockout.IPMaxAttempts,
+				Window:        cfg.Auth.Lockout.Window,
+				BaseLockout:   cfg.Auth.Lockout.BaseLockout,
+				MaxLockout:    cfg.Auth.Lockout.MaxLockout,
+			}),
+			usecaseUser.WithAPIKeys(repo.NewAPIKeyRepo(pool)),
+			usecaseUser.WithSessions(repo.NewSessionRepo(pool)),
+			usecaseUser.WithImpersonation(repo.NewImpersonationAuditRepo(pool), cfg.Auth.Impersonation.TTL),
+			usecaseUser.WithOAuth(repo.NewUserIdentityRepo(pool), repo.NewOAuthStateRepo(pool), identityProviders(cfg.Auth.OIDC)),
+			usecaseUser.WithTrashRetention(cfg.Trash.Retention))
+		authMiddleware.APIKeys = userService
+		authMiddleware.Sessions = userService
+		authMiddleware.Audit = userService
 		http.NewUserHandler(userService).RegisterNotProtected(appV1)
-		http.NewUserHandler(userService).RegisterProtected(appProtectV1)
+		http.NewUserHandler(userService).RegisterProtected(appProtectV1, authMiddleware)
+
+		cleanupInterval := cfg.Auth.Revocation.CleanupInterval
+		if cleanupInterval == 0 {
+			cleanupInterval = 10 * time.Minute
+		}
+		go pkg.Every(ctx, cleanupInterval, func(ctx context.Context) {
+			if _, err := userService.CleanupRevocations(ctx); err != nil {
+				log.Printf("revocation cleanup: %v", err)
+			}
+			if _, err := userService.CleanupLoginAttempts(ctx); err != nil {
+				log.Printf("login attempt cleanup: %v", err)
+			}
+			if _, err := userService.CleanupOAuthStates(ctx); err != nil {
+				log.Printf("oauth state cleanup: %v", err)
+			}
+			if _, err := userService.CleanupSessions(ctx); err != nil {
+				log.Printf("session cleanup: %v", err)
+			}
+		})
+		go pkg.Every(ctx, purgeInterval, func(ctx context.Context) {
+			if _, err := userService.PurgeDeletedUsers(ctx); err != nil {
+				log.Printf("user trash purge: %v", err)
+			}
+		})
 	}
 	log.Fatal(app.Listen(":" + strconv.Itoa(cfg.App.Port)))
 }
+
+// newTokenProvider signs with the configured key file, or with the shared
+// secret when no key is configured.
+func newTokenProvider(cfg pkg.JWTConfig, accessTTL time.Duration) (*adapters.Provider, error) {
+	provider := adapters.NewProvider([]byte(cfg.Secret), accessTTL)
+	if cfg.SigningKey.File != "" {
+		signingKey, err := adapters.LoadKey(cfg.SigningKey.ID, cfg.SigningKey.File)
+		if err != nil {
+			return nil, err
+		}
+		var verificationKeys []*adapters.Key
+		for _, keyCfg := range cfg.VerificationKeys {
+			key, err := adapters.LoadKey(keyCfg.ID, keyCfg.File)
+			if err != nil {
+				return nil, err
+			}
+			verificationKeys = append(verificationKeys, key)
+		}
+		provider, err = adapters.NewKeyProvider(signingKey, verificationKeys, accessTTL)
+		if err != nil {
+			return nil, err
+		}
+	}
+	provider.Issuer = cfg.Issuer
+	provider.Audience = cfg.Audience
+	return provider, nil
+}
+
+// identityProviders builds an OpenID Connect client for every configured
+// provider.
+func identityProviders(cfg pkg.OIDCConfig) map[string]ports.IdentityProvider {
+	providers := map[string]ports.IdentityProvider{}
+	for name, providerCfg := range cfg.Providers {
+		providers[name] = oidc.NewProvider(oidc.Config{
+			Issuer:       providerCfg.Issuer,
+			ClientID:     providerCfg.ClientID,
+			ClientSecret: providerCfg.ClientSecret,
+			RedirectURL:  providerCfg.RedirectURL,
+			Scopes:       providerCfg.Scopes,
+		})
+	}
+	return providers
+}
+
+// passwordHasher returns the hasher for new passwords.
+func passwordHasher(cfg pkg.PasswordHashingConfig) ports.PasswordHasher {
+	if cfg.Algorithm == "argon2id" {
+		return password.NewArgon2idHasher(password.Argon2idParams{
+			Memory:      cfg.Argon2id.Memory,
+			Iterations:  cfg.Argon2id.Iterations,
+			Parallelism: cfg.Argon2id.Parallelism,
+			SaltLength:  cfg.Argon2id.SaltLength,
+			KeyLength:   cfg.Argon2id.KeyLength,
+		})
+	}
+	return password.NewBcryptHasher(cfg.BcryptCost)
+}
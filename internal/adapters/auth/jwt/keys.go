@@ -0,0 +1,128 @@
+package adapters
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"example.com/practice/fiber/internal/domain"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Key is a signing or verification key. ID is sent as the "kid" header and
+// Method is the only algorithm accepted for tokens signed with this key.
+type Key struct {
+	ID      string
+	Method  jwt.SigningMethod
+	private crypto.PrivateKey
+	public  crypto.PublicKey
+}
+
+// LoadKey reads a PEM encoded RSA or Ed25519 key. A private key can sign and
+// verify; a public key can only verify. RSA keys use RS256 and Ed25519 keys
+// use EdDSA. When id is empty the RFC 7638 thumbprint is used.
+func LoadKey(id, path string) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseKey(id, data)
+}
+
+// ParseKey is LoadKey for PEM data already in memory.
+func ParseKey(id string, data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("jwt key: no PEM block found")
+	}
+	key := &Key{}
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		key.private = parsed
+	case "RSA PRIVATE KEY":
+		parsed, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		key.private = parsed
+	case "PUBLIC KEY":
+		parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		key.public = parsed
+	default:
+		return nil, fmt.Errorf("jwt key: unsupported PEM block %q", block.Type)
+	}
+	switch private := key.private.(type) {
+	case *rsa.PrivateKey:
+		key.public = &private.PublicKey
+	case ed25519.PrivateKey:
+		key.public = private.Public()
+	case nil:
+	default:
+		return nil, fmt.Errorf("jwt key: unsupported private key type %T", private)
+	}
+	switch key.public.(type) {
+	case *rsa.PublicKey:
+		key.Method = jwt.SigningMethodRS256
+	case ed25519.PublicKey:
+		key.Method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("jwt key: unsupported public key type %T", key.public)
+	}
+	key.ID = id
+	if key.ID == "" {
+		key.ID = key.thumbprint()
+	}
+	return key, nil
+}
+
+// CanSign reports whether the key holds a private key.
+func (k *Key) CanSign() bool {
+	return k.private != nil
+}
+
+// JWK returns the public part of the key.
+func (k *Key) JWK() domain.JSONWebKey {
+	jwk := domain.JSONWebKey{Kid: k.ID, Use: "sig", Alg: k.Method.Alg()}
+	switch public := k.public.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(public)
+	}
+	return jwk
+}
+
+// thumbprint computes the RFC 7638 JWK thumbprint. encoding/json sorts map
+// keys, which gives the required member order.
+func (k *Key) thumbprint() string {
+	jwk := k.JWK()
+	members := map[string]string{"kty": jwk.Kty}
+	if jwk.Kty == "RSA" {
+		members["n"], members["e"] = jwk.N, jwk.E
+	} else {
+		members["crv"], members["x"] = jwk.Crv, jwk.X
+	}
+	data, _ := json.Marshal(members)
+	sum := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
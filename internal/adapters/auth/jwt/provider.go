@@ -1,14 +1,44 @@
 package adapters
 
 import (
+	"errors"
+	"sort"
 	"time"
 
+	"example.com/practice/fiber/internal/domain"
+	"example.com/practice/fiber/pkg"
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// Provider issues and validates JWTs. It signs either with a shared HS256
+// secret (NewProvider) or with an RSA/Ed25519 key (NewKeyProvider). Issuer
+// and Audience are added to new tokens and, when set, required on
+// validation.
 type Provider struct {
 	SecretKey []byte
 	Expire    time.Duration
+	Issuer    string
+	Audience  string
+
+	signingKey *Key
+	// keys are the verification keys by kid, including the signing key.
+	keys map[string]*Key
+}
+
+type tokenClaims struct {
+	Role      string       `json:"role,omitempty"`
+	Purpose   string       `json:"purpose,omitempty"`
+	SessionID string       `json:"sid,omitempty"`
+	Actor     *actorClaims `json:"act,omitempty"`
+	// IssuedAtMicro is iat in microseconds. iat itself is whole seconds, too
+	// coarse to tell a token from a revocation in the same second.
+	IssuedAtMicro int64 `json:"iat_us,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// actorClaims is the RFC 8693 "act" claim.
+type actorClaims struct {
+	Subject string `json:"sub"`
 }
 
 func NewProvider(secretKey []byte, expire time.Duration) *Provider {
@@ -18,28 +48,153 @@ func NewProvider(secretKey []byte, expire time.Duration) *Provider {
 	}
 }
 
-func (p *Provider) GenerateToken(userId string) (string, error) {
-	claims := jwt.MapClaims{
-		"sub": userId,
-		"exp": time.Now().Add(p.Expire).Unix(),
+// NewKeyProvider signs with signingKey and accepts tokens signed by it or by
+// any of verificationKeys, e.g. keys being rotated out.
+func NewKeyProvider(signingKey *Key, verificationKeys []*Key, expire time.Duration) (*Provider, error) {
+	if signingKey == nil || !signingKey.CanSign() {
+		return nil, errors.New("jwt: signing key must be a private key")
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(p.SecretKey)
+	p := &Provider{
+		Expire:     expire,
+		signingKey: signingKey,
+		keys:       map[string]*Key{signingKey.ID: signingKey},
+	}
+	for _, key := range verificationKeys {
+		if _, exists := p.keys[key.ID]; exists {
+			return nil, errors.New("jwt: duplicate key id " + key.ID)
+		}
+		p.keys[key.ID] = key
+	}
+	return p, nil
+}
+
+func (p *Provider) GenerateToken(claims domain.Claims) (string, error) {
+	tokenID := claims.ID
+	if tokenID == "" {
+		var err error
+		tokenID, err = pkg.GenerateRandomToken(16)
+		if err != nil {
+			return "", err
+		}
+	}
+	now := time.Now().Truncate(time.Microsecond)
+	expiresAt := claims.ExpiresAt
+	if expiresAt.IsZero() {
+		expiresAt = now.Add(p.Expire)
+	}
+	registered := jwt.RegisteredClaims{
+		ID:        tokenID,
+		Issuer:    p.Issuer,
+		Subject:   claims.Subject,
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+	}
+	if p.Audience != "" {
+		registered.Audience = jwt.ClaimStrings{p.Audience}
+	}
+	body := tokenClaims{
+		Role:             claims.Role,
+		Purpose:          claims.Purpose,
+		SessionID:        claims.SessionID,
+		IssuedAtMicro:    now.UnixMicro(),
+		RegisteredClaims: registered,
+	}
+	if claims.Actor != "" {
+		body.Actor = &actorClaims{Subject: claims.Actor}
+	}
+	if p.signingKey == nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, body).SignedString(p.SecretKey)
+	}
+	token := jwt.NewWithClaims(p.signingKey.Method, body)
+	token.Header["kid"] = p.signingKey.ID
+	return token.SignedString(p.signingKey.private)
+}
+
+func (p *Provider) ValidateToken(tokenString string) (*domain.Claims, error) {
+	claims := &tokenClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, p.verificationKey, p.parserOptions()...)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+	result := &domain.Claims{
+		ID:        claims.ID,
+		Subject:   claims.Subject,
+		Role:      claims.Role,
+		Purpose:   claims.Purpose,
+		SessionID: claims.SessionID,
+	}
+	if claims.Actor != nil {
+		result.Actor = claims.Actor.Subject
+	}
+	if claims.IssuedAt != nil {
+		result.IssuedAt = claims.IssuedAt.Time
+		// The precise time only counts when it agrees with iat
+		precise := time.UnixMicro(claims.IssuedAtMicro)
+		if claims.IssuedAtMicro != 0 && precise.Truncate(time.Second).Equal(claims.IssuedAt.Time) {
+			result.IssuedAt = precise
+		}
+	}
+	if claims.ExpiresAt != nil {
+		result.ExpiresAt = claims.ExpiresAt.Time
+	}
+	return result, nil
+}
+
+// JWKS returns the public verification keys. It is empty for HS256.
+func (p *Provider) JWKS() domain.JSONWebKeySet {
+	set := domain.JSONWebKeySet{Keys: []domain.JSONWebKey{}}
+	if p.signingKey == nil {
+		return set
+	}
+	for id, key := range p.keys {
+		if id != p.signingKey.ID {
+			set.Keys = append(set.Keys, key.JWK())
+		}
 	}
-	return tokenString, nil
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].Kid < set.Keys[j].Kid })
+	// The current signing key comes first.
+	set.Keys = append([]domain.JSONWebKey{p.signingKey.JWK()}, set.Keys...)
+	return set
 }
 
-func (p *Provider) ValidateToken(tokenString string) (string, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+// verificationKey picks the key named by the kid header and checks that the
+// token uses that key's algorithm, so a token cannot choose how it is
+// verified.
+func (p *Provider) verificationKey(token *jwt.Token) (interface{}, error) {
+	if p.signingKey == nil {
+		if token.Method != jwt.SigningMethodHS256 {
+			return nil, errors.New("unexpected signing method")
+		}
 		return p.SecretKey, nil
-	})
-	if err != nil || !token.Valid {
-		return "", err
 	}
-	if claims, ok := token.Claims.(jwt.MapClaims); ok {
-		return claims["sub"].(string), nil
+	kid, _ := token.Header["kid"].(string)
+	key, ok := p.keys[kid]
+	if !ok {
+		return nil, errors.New("unknown key id")
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, errors.New("unexpected signing method")
+	}
+	return key.public, nil
+}
+
+func (p *Provider) parserOptions() []jwt.ParserOption {
+	methods := []string{jwt.SigningMethodHS256.Alg()}
+	if p.signingKey != nil {
+		methods = nil
+		for _, key := range p.keys {
+			methods = append(methods, key.Method.Alg())
+		}
+	}
+	opts := []jwt.ParserOption{jwt.WithValidMethods(methods), jwt.WithExpirationRequired()}
+	if p.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(p.Issuer))
+	}
+	if p.Audience != "" {
+		opts = append(opts, jwt.WithAudience(p.Audience))
 	}
-	return "", nil
+	return opts
 }
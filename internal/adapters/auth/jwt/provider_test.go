@@ -0,0 +1,224 @@
+package adapters
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"example.com/practice/fiber/internal/domain"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// testKey returns a private key for signing and its public half for
+// verification only.
+func testKey(t *testing.T, id string, private crypto.PrivateKey) (*Key, *Key) {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signing, err := ParseKey(id, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err = x509.MarshalPKIXPublicKey(signing.public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	public, err := ParseKey(id, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signing, public
+}
+
+func ed25519Key(t *testing.T, id string) (*Key, *Key) {
+	t.Helper()
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testKey(t, id, private)
+}
+
+// sign builds a token with the registered claims a Provider expects.
+func sign(t *testing.T, method jwt.SigningMethod, kid string, key interface{}, mutate func(*tokenClaims)) string {
+	t.Helper()
+	claims := &tokenClaims{
+		Role: domain.RoleAdmin,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "1",
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		},
+	}
+	if mutate != nil {
+		mutate(claims)
+	}
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestProvider_HS256(t *testing.T) {
+	secret := []byte("test-secret")
+	p := NewProvider(secret, time.Minute)
+	token, err := p.GenerateToken(domain.Claims{Subject: "1", Role: domain.RoleUser, SessionID: "s1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := p.ValidateToken(token)
+	if err != nil || claims.Subject != "1" || claims.Role != domain.RoleUser || claims.SessionID != "s1" || claims.ID == "" {
+		t.Fatalf("ValidateToken() = %+v, %v", claims, err)
+	}
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rejected := map[string]string{
+		"alg none":     sign(t, jwt.SigningMethodNone, "", jwt.UnsafeAllowNoneSignatureType, nil),
+		"HS512":        sign(t, jwt.SigningMethodHS512, "", secret, nil),
+		"RS256":        sign(t, jwt.SigningMethodRS256, "", rsaKey, nil),
+		"wrong secret": sign(t, jwt.SigningMethodHS256, "", []byte("other-secret"), nil),
+		"expired": sign(t, jwt.SigningMethodHS256, "", secret, func(c *tokenClaims) {
+			c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+		}),
+		"no expiry": sign(t, jwt.SigningMethodHS256, "", secret, func(c *tokenClaims) { c.ExpiresAt = nil }),
+	}
+	for name, token := range rejected {
+		if _, err := p.ValidateToken(token); err == nil {
+			t.Errorf("%s: ValidateToken() accepted the token", name)
+		}
+	}
+}
+
+func TestProvider_IssuedAt(t *testing.T) {
+	secret := []byte("test-secret")
+	p := NewProvider(secret, time.Minute)
+	before := time.Now().Truncate(time.Microsecond)
+	token, err := p.GenerateToken(domain.Claims{Subject: "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := p.ValidateToken(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.IssuedAt.Before(before) || claims.IssuedAt.After(time.Now()) {
+		t.Errorf("IssuedAt = %v, want microseconds after %v", claims.IssuedAt, before)
+	}
+
+	// iat_us disagreeing with iat is ignored
+	issued := time.Now().Add(-time.Hour)
+	claims, err = p.ValidateToken(sign(t, jwt.SigningMethodHS256, "", secret, func(c *tokenClaims) {
+		c.IssuedAt = jwt.NewNumericDate(issued)
+		c.IssuedAtMicro = time.Now().Add(time.Hour).UnixMicro()
+	}))
+	if err != nil || !claims.IssuedAt.Equal(issued.Truncate(time.Second)) {
+		t.Errorf("ValidateToken() IssuedAt = %v, %v, want %v", claims, err, issued.Truncate(time.Second))
+	}
+}
+
+func TestProvider_IssuerAudience(t *testing.T) {
+	secret := []byte("test-secret")
+	p := NewProvider(secret, time.Minute)
+	p.Issuer, p.Audience = "https://api.example.com", "books"
+	token, err := p.GenerateToken(domain.Claims{Subject: "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.ValidateToken(token); err != nil {
+		t.Fatalf("ValidateToken() error = %v", err)
+	}
+
+	rejected := map[string]func(*tokenClaims){
+		"wrong issuer":   func(c *tokenClaims) { c.Issuer, c.Audience = "https://evil.example.com", jwt.ClaimStrings{"books"} },
+		"missing issuer": func(c *tokenClaims) { c.Audience = jwt.ClaimStrings{"books"} },
+		"wrong audience": func(c *tokenClaims) { c.Issuer, c.Audience = p.Issuer, jwt.ClaimStrings{"billing"} },
+		"no audience":    func(c *tokenClaims) { c.Issuer = p.Issuer },
+	}
+	for name, mutate := range rejected {
+		if _, err := p.ValidateToken(sign(t, jwt.SigningMethodHS256, "", secret, mutate)); err == nil {
+			t.Errorf("%s: ValidateToken() accepted the token", name)
+		}
+	}
+}
+
+func TestProvider_Keys(t *testing.T) {
+	current, _ := ed25519Key(t, "current")
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old, oldPublic := testKey(t, "old", rsaKey)
+	_, stranger := ed25519Key(t, "stranger")
+	impostor, _ := ed25519Key(t, "current")
+
+	p, err := NewKeyProvider(current, []*Key{oldPublic}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := p.GenerateToken(domain.Claims{Subject: "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.ValidateToken(token); err != nil {
+		t.Fatalf("ValidateToken() error = %v", err)
+	}
+
+	// Tokens signed before the rotation stay valid until they expire
+	previous, err := NewKeyProvider(old, nil, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rotated, err := previous.GenerateToken(domain.Claims{Subject: "2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims, err := p.ValidateToken(rotated); err != nil || claims.Subject != "2" {
+		t.Fatalf("ValidateToken() of a rotated-out key = %+v, %v", claims, err)
+	}
+	withoutOld, err := NewKeyProvider(current, nil, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := withoutOld.ValidateToken(rotated); err == nil {
+		t.Errorf("ValidateToken() accepted a key that was dropped")
+	}
+
+	rejected := map[string]string{
+		"alg none": sign(t, jwt.SigningMethodNone, "current", jwt.UnsafeAllowNoneSignatureType, nil),
+		// The public key used as an HMAC secret
+		"HS256":        sign(t, jwt.SigningMethodHS256, "old", x509PublicKey(t, oldPublic), nil),
+		"no kid":       sign(t, jwt.SigningMethodEdDSA, "", current.private, nil),
+		"unknown kid":  sign(t, jwt.SigningMethodEdDSA, stranger.ID, current.private, nil),
+		"other method": sign(t, jwt.SigningMethodRS256, "current", rsaKey, nil),
+		"wrong key":    sign(t, jwt.SigningMethodEdDSA, "current", impostor.private, nil),
+	}
+	for name, token := range rejected {
+		if _, err := p.ValidateToken(token); err == nil {
+			t.Errorf("%s: ValidateToken() accepted the token", name)
+		}
+	}
+}
+
+func x509PublicKey(t *testing.T, key *Key) []byte {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(key.public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
@@ -0,0 +1,71 @@
+package oidc
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+
+	"example.com/practice/fiber/internal/domain"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// publicKey is a provider signing key and the only algorithm accepted for it.
+type publicKey struct {
+	method jwt.SigningMethod
+	public crypto.PublicKey
+}
+
+// parseKeySet keeps the RSA and Ed25519 signing keys of set by kid. Keys of
+// other types are skipped.
+func parseKeySet(set domain.JSONWebKeySet) map[string]*publicKey {
+	keys := map[string]*publicKey{}
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key := parseKey(jwk)
+		if key == nil || (jwk.Alg != "" && jwk.Alg != key.method.Alg()) {
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	return keys
+}
+
+func parseKey(jwk domain.JSONWebKey) *publicKey {
+	switch {
+	case jwk.Kty == "RSA":
+		n, errN := base64.RawURLEncoding.DecodeString(jwk.N)
+		e, errE := base64.RawURLEncoding.DecodeString(jwk.E)
+		if errN != nil || errE != nil || len(n) == 0 || len(e) == 0 || len(e) > 4 {
+			return nil
+		}
+		return &publicKey{
+			method: jwt.SigningMethodRS256,
+			public: &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())},
+		}
+	case jwk.Kty == "OKP" && jwk.Crv == "Ed25519":
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil
+		}
+		return &publicKey{method: jwt.SigningMethodEdDSA, public: ed25519.PublicKey(x)}
+	}
+	return nil
+}
+
+// findKey returns the key with kid. A token without kid is accepted only
+// when the provider publishes a single key.
+func findKey(keys map[string]*publicKey, kid string) *publicKey {
+	if key, ok := keys[kid]; ok {
+		return key
+	}
+	if kid == "" && len(keys) == 1 {
+		for _, key := range keys {
+			return key
+		}
+	}
+	return nil
+}
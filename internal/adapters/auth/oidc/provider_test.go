@@ -0,0 +1,353 @@
+package oidc
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"example.com/practice/fiber/internal/domain"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// testIssuer is a stand-in provider that publishes keys in its JWKS. Its
+// token endpoint returns an id_token signed with signingKey under
+// signingKid, after mutate has been applied to the token.
+type testIssuer struct {
+	server *httptest.Server
+	// issuer overrides the issuer in the discovery document.
+	issuer     string
+	keys       map[string]ed25519.PrivateKey
+	signingKid string
+	signingKey ed25519.PrivateKey
+	mutate     func(*jwt.Token, *idTokenClaims)
+
+	discoveryHits, jwksHits int
+	tokenRequest            *http.Request
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	t.Helper()
+	i := &testIssuer{keys: map[string]ed25519.PrivateKey{}}
+	i.addKey(t, "k1")
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		i.discoveryHits++
+		issuer := i.issuer
+		if issuer == "" {
+			issuer = i.server.URL
+		}
+		json.NewEncoder(w).Encode(metadata{
+			Issuer:                issuer,
+			AuthorizationEndpoint: i.server.URL + "/authorize?prompt=login",
+			TokenEndpoint:         i.server.URL + "/token",
+			JWKSURI:               i.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		i.jwksHits++
+		set := domain.JSONWebKeySet{}
+		for kid, key := range i.keys {
+			set.Keys = append(set.Keys, ed25519JWK(kid, key))
+		}
+		json.NewEncoder(w).Encode(set)
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		i.tokenRequest = r
+		claims := &idTokenClaims{
+			Nonce:         "n1",
+			Email:         "jane@example.com",
+			EmailVerified: true,
+			GivenName:     "Jane",
+			FamilyName:    "Doe",
+			RegisteredClaims: jwt.RegisteredClaims{
+				Issuer:    i.server.URL,
+				Subject:   "idp-user-1",
+				Audience:  jwt.ClaimStrings{"shop"},
+				IssuedAt:  jwt.NewNumericDate(time.Now()),
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+			},
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+		token.Header["kid"] = i.signingKid
+		var key interface{} = i.signingKey
+		if i.mutate != nil {
+			i.mutate(token, claims)
+		}
+		if token.Method == jwt.SigningMethodHS256 {
+			key = []byte("shop-secret")
+		}
+		idToken, err := token.SignedString(key)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(tokenResponse{AccessToken: "at", TokenType: "Bearer", IDToken: idToken})
+	})
+	i.server = httptest.NewServer(mux)
+	t.Cleanup(i.server.Close)
+	return i
+}
+
+// addKey publishes a new key and signs with it from now on.
+func (i *testIssuer) addKey(t *testing.T, kid string) {
+	t.Helper()
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	i.keys[kid] = private
+	i.signingKid, i.signingKey = kid, private
+}
+
+func (i *testIssuer) provider(secret string) *Provider {
+	return NewProvider(Config{
+		Issuer:       i.server.URL + "/",
+		ClientID:     "shop",
+		ClientSecret: secret,
+		RedirectURL:  "https://app.example.com/callback",
+	})
+}
+
+func ed25519JWK(kid string, key ed25519.PrivateKey) domain.JSONWebKey {
+	return domain.JSONWebKey{
+		Kty: "OKP",
+		Kid: kid,
+		Use: "sig",
+		Alg: "EdDSA",
+		Crv: "Ed25519",
+		X:   base64.RawURLEncoding.EncodeToString(key.Public().(ed25519.PublicKey)),
+	}
+}
+
+func TestProvider_AuthCodeURL(t *testing.T) {
+	issuer := newTestIssuer(t)
+	p := issuer.provider("")
+	raw, err := p.AuthCodeURL(context.Background(), "st", "n1", "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"prompt":                "login",
+		"response_type":         "code",
+		"client_id":             "shop",
+		"redirect_uri":          "https://app.example.com/callback",
+		"scope":                 "openid email profile",
+		"state":                 "st",
+		"nonce":                 "n1",
+		"code_challenge":        "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM", // RFC 7636 appendix B
+		"code_challenge_method": "S256",
+	}
+	for name, value := range want {
+		if got := u.Query().Get(name); got != value {
+			t.Errorf("AuthCodeURL() %s = %q, want %q", name, got, value)
+		}
+	}
+	if _, err := p.AuthCodeURL(context.Background(), "st2", "n2", "v2"); err != nil {
+		t.Fatal(err)
+	}
+	if issuer.discoveryHits != 1 {
+		t.Errorf("discovery fetched %d times, want 1", issuer.discoveryHits)
+	}
+}
+
+func TestProvider_Discovery(t *testing.T) {
+	issuer := newTestIssuer(t)
+	issuer.issuer = "https://evil.example.com"
+	if _, err := issuer.provider("").AuthCodeURL(context.Background(), "st", "n1", "v"); err == nil {
+		t.Errorf("AuthCodeURL() accepted a discovery document for another issuer")
+	}
+
+	if _, err := NewProvider(Config{Issuer: issuer.server.URL + "/missing"}).AuthCodeURL(context.Background(), "st", "n1", "v"); err == nil {
+		t.Errorf("AuthCodeURL() accepted a missing discovery document")
+	}
+
+	empty := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(metadata{Issuer: "http://" + r.Host})
+	}))
+	defer empty.Close()
+	if _, err := NewProvider(Config{Issuer: empty.URL}).AuthCodeURL(context.Background(), "st", "n1", "v"); err == nil {
+		t.Errorf("AuthCodeURL() accepted a discovery document without endpoints")
+	}
+}
+
+func TestProvider_Exchange(t *testing.T) {
+	issuer := newTestIssuer(t)
+	identity, err := issuer.provider("s3cret").Exchange(context.Background(), "code-1", "verifier-1", "n1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := domain.ExternalIdentity{Subject: "idp-user-1", Email: "jane@example.com", EmailVerified: true, GivenName: "Jane", FamilyName: "Doe"}
+	if *identity != want {
+		t.Errorf("Exchange() = %+v, want %+v", *identity, want)
+	}
+	form := issuer.tokenRequest.PostForm
+	if form.Get("grant_type") != "authorization_code" || form.Get("code") != "code-1" || form.Get("code_verifier") != "verifier-1" ||
+		form.Get("redirect_uri") != "https://app.example.com/callback" || form.Has("client_id") {
+		t.Errorf("unexpected token request: %v", form)
+	}
+	if user, pass, _ := issuer.tokenRequest.BasicAuth(); user != "shop" || pass != "s3cret" {
+		t.Errorf("token request credentials = %q, %q", user, pass)
+	}
+
+	// Public clients name themselves in the form instead
+	if _, err := issuer.provider("").Exchange(context.Background(), "code-2", "verifier-2", "n1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, ok := issuer.tokenRequest.BasicAuth(); ok || issuer.tokenRequest.PostForm.Get("client_id") != "shop" {
+		t.Errorf("unexpected public client token request: %v", issuer.tokenRequest.PostForm)
+	}
+}
+
+func TestProvider_KeyRotation(t *testing.T) {
+	issuer := newTestIssuer(t)
+	p := issuer.provider("s3cret")
+	exchange := func() error {
+		_, err := p.Exchange(context.Background(), "code", "verifier", "n1")
+		return err
+	}
+	if err := exchange(); err != nil {
+		t.Fatal(err)
+	}
+	if err := exchange(); err != nil {
+		t.Fatal(err)
+	}
+	if issuer.jwksHits != 1 {
+		t.Fatalf("jwks fetched %d times, want 1", issuer.jwksHits)
+	}
+
+	// A new kid is looked up once
+	retired := issuer.keys["k1"]
+	delete(issuer.keys, "k1")
+	issuer.addKey(t, "k2")
+	if err := exchange(); err != nil {
+		t.Fatalf("Exchange() with a rotated key: %v", err)
+	}
+	if issuer.jwksHits != 2 {
+		t.Fatalf("jwks fetched %d times, want 2", issuer.jwksHits)
+	}
+	// The refetched set replaced the cached one
+	issuer.signingKid, issuer.signingKey = "k1", retired
+	if err := exchange(); err == nil {
+		t.Errorf("Exchange() accepted a token signed with a retired key")
+	}
+	issuer.signingKid = "k3"
+	if err := exchange(); err == nil {
+		t.Errorf("Exchange() accepted an unknown kid")
+	}
+	if issuer.jwksHits != 4 {
+		t.Errorf("jwks fetched %d times, want 4", issuer.jwksHits)
+	}
+}
+
+func TestProvider_RejectsIDTokens(t *testing.T) {
+	rejected := map[string]func(*jwt.Token, *idTokenClaims){
+		"wrong nonce":  func(_ *jwt.Token, c *idTokenClaims) { c.Nonce = "n2" },
+		"no nonce":     func(_ *jwt.Token, c *idTokenClaims) { c.Nonce = "" },
+		"wrong issuer": func(_ *jwt.Token, c *idTokenClaims) { c.Issuer = "https://evil.example.com" },
+		"no issuer":    func(_ *jwt.Token, c *idTokenClaims) { c.Issuer = "" },
+		"wrong aud":    func(_ *jwt.Token, c *idTokenClaims) { c.Audience = jwt.ClaimStrings{"other"} },
+		"no aud":       func(_ *jwt.Token, c *idTokenClaims) { c.Audience = nil },
+		"no azp":       func(_ *jwt.Token, c *idTokenClaims) { c.Audience = jwt.ClaimStrings{"shop", "other"} },
+		"wrong azp": func(_ *jwt.Token, c *idTokenClaims) {
+			c.Audience, c.AuthorizedParty = jwt.ClaimStrings{"shop", "other"}, "other"
+		},
+		"no subject": func(_ *jwt.Token, c *idTokenClaims) { c.Subject = "" },
+		"no expiry":  func(_ *jwt.Token, c *idTokenClaims) { c.ExpiresAt = nil },
+		"expired": func(_ *jwt.Token, c *idTokenClaims) {
+			c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-2 * time.Minute))
+		},
+		"HS256":     func(token *jwt.Token, _ *idTokenClaims) { token.Method = jwt.SigningMethodHS256 },
+		"other key": func(token *jwt.Token, _ *idTokenClaims) { token.Header["kid"] = "k2" },
+		"no kid":    func(token *jwt.Token, _ *idTokenClaims) { delete(token.Header, "kid") },
+	}
+	for name, mutate := range rejected {
+		issuer := newTestIssuer(t)
+		signingKey := issuer.signingKey
+		issuer.addKey(t, "k2")
+		issuer.signingKid, issuer.signingKey = "k1", signingKey
+		issuer.mutate = mutate
+		if _, err := issuer.provider("s3cret").Exchange(context.Background(), "code", "verifier", "n1"); err == nil {
+			t.Errorf("%s: Exchange() accepted the id_token", name)
+		}
+	}
+
+	// Several audiences are fine when the token was issued to us
+	issuer := newTestIssuer(t)
+	issuer.mutate = func(_ *jwt.Token, c *idTokenClaims) {
+		c.Audience, c.AuthorizedParty = jwt.ClaimStrings{"shop", "other"}, "shop"
+	}
+	if _, err := issuer.provider("s3cret").Exchange(context.Background(), "code", "verifier", "n1"); err != nil {
+		t.Errorf("Exchange() with azp: %v", err)
+	}
+}
+
+func TestParseKeySet(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaJWK := domain.JSONWebKey{
+		Kty: "RSA",
+		Kid: "rsa",
+		N:   base64.RawURLEncoding.EncodeToString(rsaKey.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(rsaKey.E)).Bytes()),
+	}
+	encryption := ed25519JWK("enc", edKey)
+	encryption.Use = "enc"
+	wrongAlg := rsaJWK
+	wrongAlg.Kid, wrongAlg.Alg = "rs512", "RS512"
+	badExponent := rsaJWK
+	badExponent.Kid, badExponent.E = "bad-e", base64.RawURLEncoding.EncodeToString([]byte{1, 0, 0, 0, 1})
+	shortX := ed25519JWK("short", edKey)
+	shortX.X = shortX.X[:10]
+
+	keys := parseKeySet(domain.JSONWebKeySet{Keys: []domain.JSONWebKey{
+		rsaJWK,
+		ed25519JWK("ed", edKey),
+		encryption,
+		wrongAlg,
+		badExponent,
+		shortX,
+		{Kty: "EC", Kid: "ec", Crv: "P-256"},
+	}})
+	var kids []string
+	for kid := range keys {
+		kids = append(kids, kid)
+	}
+	if len(keys) != 2 || keys["rsa"] == nil || keys["ed"] == nil {
+		t.Fatalf("parseKeySet() kept %v, want rsa and ed", kids)
+	}
+	if keys["rsa"].method != jwt.SigningMethodRS256 || keys["ed"].method != jwt.SigningMethodEdDSA {
+		t.Errorf("parseKeySet() methods = %s, %s", keys["rsa"].method.Alg(), keys["ed"].method.Alg())
+	}
+	if public, ok := keys["rsa"].public.(*rsa.PublicKey); !ok || !public.Equal(&rsaKey.PublicKey) {
+		t.Errorf("parseKeySet() RSA key does not match")
+	}
+
+	// A token without kid only matches a single published key
+	if findKey(keys, "") != nil {
+		t.Errorf("findKey() picked one of several keys for an empty kid")
+	}
+	single := map[string]*publicKey{"ed": keys["ed"]}
+	if findKey(single, "") != keys["ed"] || findKey(single, "other") != nil {
+		t.Errorf("findKey() with a single key misbehaved")
+	}
+}
@@ -0,0 +1,73 @@
+package http
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"example.com/practice/fiber/internal/adapters/http/middleware"
+	"example.com/practice/fiber/internal/domain"
+	"example.com/practice/fiber/pkg"
+	"github.com/gofiber/fiber/v2"
+)
+
+func (h *UserHandler) CreateAPIKey(c *fiber.Ctx) error {
+	var req domain.CreateAPIKeyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	key, err := h.service.CreateAPIKey(ctx, middleware.CurrentPrincipal(c), &req)
+	if err != nil {
+		if err == pkg.ErrForbidden {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+		}
+		if err == pkg.ErrValidationError || err == pkg.ErrInvalidAPIKeyScope {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		if err == pkg.ErrFeatureDisabled {
+			return c.Status(fiber.StatusNotImplemented).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(fiber.StatusCreated).JSON(key)
+}
+
+func (h *UserHandler) ListAPIKeys(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	keys, err := h.service.ListAPIKeys(ctx, middleware.CurrentPrincipal(c))
+	if err != nil {
+		if err == pkg.ErrForbidden {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+		}
+		if err == pkg.ErrFeatureDisabled {
+			return c.Status(fiber.StatusNotImplemented).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(fiber.StatusOK).JSON(keys)
+}
+
+func (h *UserHandler) RevokeAPIKey(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := h.service.RevokeAPIKey(ctx, middleware.CurrentPrincipal(c), id); err != nil {
+		if err == pkg.ErrForbidden {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+		}
+		if err == pkg.ErrAPIKeyNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+		}
+		if err == pkg.ErrFeatureDisabled {
+			return c.Status(fiber.StatusNotImplemented).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "api key revoked"})
+}
@@ -2,14 +2,22 @@ package http
 
 import (
 	"context"
+	"errors"
 	"strconv"
+	"strings"
 	"time"
 
+	"example.com/practice/fiber/internal/adapters/http/middleware"
 	domains "example.com/practice/fiber/internal/domain"
 	usecase "example.com/practice/fiber/internal/usecase/book"
+	"example.com/practice/fiber/pkg"
 	"github.com/gofiber/fiber/v2"
 )
 
+// bookRoute names the route of a single book, which Location headers point
+// at.
+const bookRoute = "books.get"
+
 type BookHandler struct {
 	service *usecase.BookService
 }
@@ -18,14 +26,96 @@ func NewBookHandler(service *usecase.BookService) *BookHandler {
 	return &BookHandler{service: service}
 }
 
-func (h *BookHandler) RegisterRoutes(app fiber.Router) {
-	app.Get("/books", h.GetAllBooks)
-	app.Get("/books/:id", h.GetBookByID)
-	app.Post("/books", h.CreateBook)
-	app.Put("/books/:id", h.UpdateBook)
-	app.Delete("/books/:id", h.DeleteBook)
+func (h *BookHandler) RegisterRoutes(app fiber.Router, auth *middleware.AuthMiddleware) {
+	canRead := auth.RequirePermission(domains.PermBooksRead)
+	canWrite := auth.RequirePermission(domains.PermBooksWrite)
+	canManage := auth.RequirePermission(domains.PermBooksManage)
+	app.Get("/books", canRead, h.GetAllBooks)
+	app.Get("/books/search", canRead, h.SearchBooks)
+	app.Get("/books/trash", canManage, h.GetDeletedBooks)
+	app.Get("/books/:id", canRead, h.GetBookByID).Name(bookRoute)
+	app.Post("/books", canWrite, h.CreateBook)
+	app.Put("/books/:id", canWrite, h.UpdateBook)
+	app.Patch("/books/:id", canWrite, h.PatchBook)
+	app.Delete("/books/:id", canWrite, h.DeleteBook)
+	app.Post("/books/:id/restore", canManage, h.RestoreBook)
+}
+
+// bookError maps err to a response. Unexpected errors are reported as
+// failure without details.
+func bookError(c *fiber.Ctx, err error, failure string) error {
+	var invalid *pkg.ValidationError
+	if errors.As(err, &invalid) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":  err.Error(),
+			"fields": invalid.Fields,
+		})
+	}
+	var status int
+	switch err {
+	case pkg.ErrValidationError, pkg.ErrInvalidCursor, pkg.ErrInvalidPatch:
+		status = fiber.StatusBadRequest
+	case pkg.ErrBookNotFound:
+		status = fiber.StatusNotFound
+	case pkg.ErrBookAlreadyExists, pkg.ErrEditConflict:
+		status = fiber.StatusConflict
+	case pkg.ErrConflict:
+		status = fiber.StatusPreconditionFailed
+	case pkg.ErrPatchFailed:
+		status = fiber.StatusUnprocessableEntity
+	case pkg.ErrUnsupportedPatch:
+		c.Set("Accept-Patch", acceptPatch)
+		status = fiber.StatusUnsupportedMediaType
+	case pkg.ErrFeatureDisabled:
+		status = fiber.StatusNotImplemented
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": failure,
+		})
+	}
+	return c.Status(status).JSON(fiber.Map{
+		"error": err.Error(),
+	})
+}
+
+// bookETag is the entity tag of a book at version.
+func bookETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
 }
 
+// ifMatchVersion returns the book version the If-Match header asks for, or
+// zero when the header is absent or "*". If-Match uses strong comparison, so
+// weak tags, tags of other resources and lists of several tags can never
+// match and fail with pkg.ErrConflict.
+func ifMatchVersion(c *fiber.Ctx) (int, error) {
+	tag := strings.TrimSpace(c.Get(fiber.HeaderIfMatch))
+	if tag == "" || tag == "*" {
+		return 0, nil
+	}
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return 0, pkg.ErrConflict
+	}
+	version, err := strconv.Atoi(tag[1 : len(tag)-1])
+	if err != nil || version < 1 {
+		return 0, pkg.ErrConflict
+	}
+	return version, nil
+}
+
+// noneMatch reports whether the If-None-Match header lists etag, using the
+// weak comparison conditional GETs call for.
+func noneMatch(c *fiber.Ctx, etag string) bool {
+	for _, tag := range strings.Split(c.Get(fiber.HeaderIfNoneMatch), ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// UpdateBook replaces a book. With an If-Match header the book must still
+// have that ETag, otherwise the response is 412.
 func (h *BookHandler) UpdateBook(c *fiber.Ctx) error {
 	id, err := strconv.Atoi(c.Params("id"))
 	if err != nil {
@@ -33,6 +123,10 @@ func (h *BookHandler) UpdateBook(c *fiber.Ctx) error {
 			"error": "Invalid book ID",
 		})
 	}
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		return bookError(c, err, "Failed to update book")
+	}
 	book := new(domains.Book)
 	if err := c.BodyParser(book); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -42,14 +136,39 @@ func (h *BookHandler) UpdateBook(c *fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	book.ID = id
-	if err := h.service.UpdateBook(ctx, book); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to update book",
+	book.Version = version
+	updated, err := h.service.UpdateBook(ctx, book)
+	if err != nil {
+		return bookError(c, err, "Failed to update book")
+	}
+	c.Set(fiber.HeaderETag, bookETag(updated.Version))
+	return c.Status(fiber.StatusOK).JSON(updated)
+}
+
+// PatchBook changes some fields of a book with a JSON merge patch or JSON
+// patch, chosen by Content-Type. If-Match works as for UpdateBook.
+func (h *BookHandler) PatchBook(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid book ID",
 		})
 	}
-	return c.Status(fiber.StatusOK).JSON(book)
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		return bookError(c, err, "Failed to patch book")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	patched, err := h.service.PatchBook(ctx, id, version, requestPatch(c))
+	if err != nil {
+		return bookError(c, err, "Failed to patch book")
+	}
+	c.Set(fiber.HeaderETag, bookETag(patched.Version))
+	return c.Status(fiber.StatusOK).JSON(patched)
 }
 
+// DeleteBook deletes a book, honouring If-Match like UpdateBook.
 func (h *BookHandler) DeleteBook(c *fiber.Ctx) error {
 	id, err := strconv.Atoi(c.Params("id"))
 	if err != nil {
@@ -57,30 +176,161 @@ func (h *BookHandler) DeleteBook(c *fiber.Ctx) error {
 			"error": "Invalid book ID",
 		})
 	}
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		return bookError(c, err, "Failed to delete book")
+	}
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
-	if err := h.service.DeleteBook(ctx, id); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to delete book",
-		})
+	if err := h.service.DeleteBook(ctx, id, version); err != nil {
+		return bookError(c, err, "Failed to delete book")
 	}
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
 		"message": "Book deleted successfully",
 	})
 }
 
+// GetDeletedBooks lists the books in the trash.
+func (h *BookHandler) GetDeletedBooks(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	books, err := h.service.GetDeletedBooks(ctx)
+	if err != nil {
+		return bookError(c, err, "Failed to get deleted books")
+	}
+	return c.Status(fiber.StatusOK).JSON(books)
+}
+
+// RestoreBook takes a book out of the trash.
+func (h *BookHandler) RestoreBook(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid book ID",
+		})
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	book, err := h.service.RestoreBook(ctx, id)
+	if err != nil {
+		return bookError(c, err, "Failed to restore book")
+	}
+	c.Set(fiber.HeaderETag, bookETag(book.Version))
+	return c.Status(fiber.StatusOK).JSON(book)
+}
+
+// GetAllBooks lists books a page at a time. Query parameters: limit, cursor,
+// author, title, min_price, max_price, in_stock, sort (created_at, price or
+// title) and order (asc or desc).
 func (h *BookHandler) GetAllBooks(c *fiber.Ctx) error {
+	query, err := bookQuery(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
-	books, err := h.service.GetAllBooks(ctx)
+	page, err := h.service.GetAllBooks(ctx, query)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to get all books",
+		return bookError(c, err, "Failed to get all books")
+	}
+	return c.Status(fiber.StatusOK).JSON(page)
+}
+
+func bookQuery(c *fiber.Ctx) (domains.BookQuery, error) {
+	query := domains.BookQuery{
+		Filter: domains.BookFilter{
+			Author: c.Query("author"),
+			Title:  c.Query("title"),
+		},
+		Sort:   c.Query("sort"),
+		Cursor: c.Query("cursor"),
+	}
+	switch c.Query("order") {
+	case "", "asc":
+	case "desc":
+		query.Desc = true
+	default:
+		return query, errors.New("order must be asc or desc")
+	}
+	var err error
+	if v := c.Query("limit"); v != "" {
+		if query.Limit, err = strconv.Atoi(v); err != nil {
+			return query, errors.New("invalid limit")
+		}
+	}
+	query.Filter.Currency = strings.ToUpper(c.Query("currency"))
+	if query.Filter.MinPrice, err = moneyQuery(c, "min_price", query.Filter.Currency); err != nil {
+		return query, err
+	}
+	if query.Filter.MaxPrice, err = moneyQuery(c, "max_price", query.Filter.Currency); err != nil {
+		return query, err
+	}
+	if v := c.Query("in_stock"); v != "" {
+		inStock, err := strconv.ParseBool(v)
+		if err != nil {
+			return query, errors.New("invalid in_stock")
+		}
+		query.Filter.InStock = &inStock
+	}
+	return query, nil
+}
+
+// intQuery returns the integer query parameter key, or nil when it is absent.
+func intQuery(c *fiber.Ctx, key string) (*int, error) {
+	v := c.Query(key)
+	if v == "" {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return nil, errors.New("invalid " + key)
+	}
+	return &n, nil
+}
+
+// moneyQuery returns the decimal query parameter key as an amount of
+// currency, or nil when it is absent.
+func moneyQuery(c *fiber.Ctx, key, currency string) (*domains.Money, error) {
+	v := c.Query(key)
+	if v == "" {
+		return nil, nil
+	}
+	if currency == "" {
+		return nil, errors.New(key + " requires currency")
+	}
+	amount, err := domains.ParseMoney(v, currency)
+	if err != nil {
+		return nil, errors.New("invalid " + key)
+	}
+	return &amount, nil
+}
+
+// SearchBooks runs a full-text search for the q query parameter and returns
+// up to limit hits.
+func (h *BookHandler) SearchBooks(c *fiber.Ctx) error {
+	limit, err := intQuery(c, "limit")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
 		})
 	}
-	return c.Status(fiber.StatusOK).JSON(books)
+	query := domains.BookSearchQuery{Q: c.Query("q")}
+	if limit != nil {
+		query.Limit = *limit
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	result, err := h.service.SearchBooks(ctx, query)
+	if err != nil {
+		return bookError(c, err, "Failed to search books")
+	}
+	return c.Status(fiber.StatusOK).JSON(result)
 }
 
+// GetBookByID returns a book with its version as ETag, or 304 when the
+// If-None-Match header already has that ETag.
 func (h *BookHandler) GetBookByID(c *fiber.Ctx) error {
 	id, err := strconv.Atoi(c.Params("id"))
 	if err != nil {
@@ -92,9 +342,12 @@ func (h *BookHandler) GetBookByID(c *fiber.Ctx) error {
 	defer cancel()
 	book, err := h.service.GetBookByID(ctx, id)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to get book by ID",
-		})
+		return bookError(c, err, "Failed to get book by ID")
+	}
+	etag := bookETag(book.Version)
+	c.Set(fiber.HeaderETag, etag)
+	if noneMatch(c, etag) {
+		return c.SendStatus(fiber.StatusNotModified)
 	}
 	return c.Status(fiber.StatusOK).JSON(book)
 }
@@ -108,10 +361,13 @@ func (h *BookHandler) CreateBook(c *fiber.Ctx) error {
 	}
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
-	if err := h.service.CreateBook(ctx, book); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to create book",
-		})
+	created, err := h.service.CreateBook(ctx, book)
+	if err != nil {
+		return bookError(c, err, "Failed to create book")
 	}
-	return c.Status(fiber.StatusOK).JSON(book)
+	if location, err := c.GetRouteURL(bookRoute, fiber.Map{"id": created.ID}); err == nil && location != "" {
+		c.Location(location)
+	}
+	c.Set(fiber.HeaderETag, bookETag(created.Version))
+	return c.Status(fiber.StatusCreated).JSON(created)
 }
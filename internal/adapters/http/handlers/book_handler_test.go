@@ -8,11 +8,14 @@ import (
 	"net/http/httptest"
 	"sync"
 	"testing"
+	"time"
 
 	"example.com/practice/fiber/internal/adapters/http/middleware"
+	"example.com/practice/fiber/internal/adapters/memory"
 	domain "example.com/practice/fiber/internal/domain"
 	"example.com/practice/fiber/internal/ports"
 	usecase "example.com/practice/fiber/internal/usecase/book"
+	"example.com/practice/fiber/pkg"
 	"github.com/gofiber/fiber/v2"
 )
 
@@ -21,24 +24,42 @@ type mockBookRepo struct {
 	createErr     error
 	getByIDResult *domain.Book
 	getByIDErr    error
+	existing      *domain.Book
+	created       *domain.Book
 	getAllResult  []*domain.Book
 	getAllErr     error
+	lastQuery     domain.BookQuery
 	updateErr     error
 	deleteErr     error
-	mu            sync.Mutex
+	// version is the stored version UpdateBook and DeleteBook check.
+	version int
+	// fields are the fields UpdateBookFields last wrote.
+	fields []string
+	// deleted are the books in the trash.
+	deleted    []*domain.Book
+	restoreErr error
+	mu         sync.Mutex
 }
 
-func (m *mockBookRepo) CreateBook(ctx context.Context, book *domain.Book) error {
+func (m *mockBookRepo) CreateBook(ctx context.Context, book *domain.Book) (*domain.Book, error) {
 	if m.createErr != nil {
-		return m.createErr
+		return nil, m.createErr
 	}
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	// simulate ID assignment
-	if book.ID == 0 {
-		book.ID = 1
+	// simulate the columns the database fills in
+	stored := *book
+	stored.ID = 1
+	stored.CreatedAt = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	stored.UpdatedAt = stored.CreatedAt
+	m.created = &stored
+	return &stored, nil
+}
+func (m *mockBookRepo) GetBookByISBN(ctx context.Context, isbn13 string) (*domain.Book, error) {
+	if m.existing != nil && m.existing.ISBN13 == isbn13 {
+		return m.existing, nil
 	}
-	return nil
+	return nil, nil
 }
 func (m *mockBookRepo) GetBookByID(ctx context.Context, id int) (*domain.Book, error) {
 	if m.getByIDErr != nil {
@@ -46,41 +67,83 @@ func (m *mockBookRepo) GetBookByID(ctx context.Context, id int) (*domain.Book, e
 	}
 	return m.getByIDResult, nil
 }
-func (m *mockBookRepo) GetAllBooks(ctx context.Context) ([]*domain.Book, error) {
+func (m *mockBookRepo) GetAllBooks(ctx context.Context, query domain.BookQuery) ([]*domain.Book, error) {
+	m.lastQuery = query
 	if m.getAllErr != nil {
 		return nil, m.getAllErr
 	}
 	return m.getAllResult, nil
 }
-func (m *mockBookRepo) UpdateBook(ctx context.Context, book *domain.Book) error {
-	return m.updateErr
+func (m *mockBookRepo) CountBooks(ctx context.Context, filter domain.BookFilter) (int, error) {
+	return len(m.getAllResult), nil
+}
+func (m *mockBookRepo) UpdateBook(ctx context.Context, book *domain.Book) (*domain.Book, error) {
+	if m.updateErr != nil {
+		return nil, m.updateErr
+	}
+	if book.Version != 0 && book.Version != m.version {
+		return nil, pkg.ErrConflict
+	}
+	stored := *book
+	stored.UpdatedAt = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	stored.Version = m.version + 1
+	return &stored, nil
+}
+func (m *mockBookRepo) UpdateBookFields(ctx context.Context, book *domain.Book, fields []string) (*domain.Book, error) {
+	m.fields = fields
+	return m.UpdateBook(ctx, book)
 }
-func (m *mockBookRepo) DeleteBook(ctx context.Context, id int) error {
+func (m *mockBookRepo) DeleteBook(ctx context.Context, id, version int) error {
+	if m.deleteErr == nil && version != 0 && version != m.version {
+		return pkg.ErrConflict
+	}
 	return m.deleteErr
 }
+func (m *mockBookRepo) GetDeletedBooks(ctx context.Context) ([]*domain.Book, error) {
+	return m.deleted, nil
+}
+func (m *mockBookRepo) RestoreBook(ctx context.Context, id int) (*domain.Book, error) {
+	if m.restoreErr != nil {
+		return nil, m.restoreErr
+	}
+	for _, book := range m.deleted {
+		if book.ID == id {
+			restored := *book
+			restored.DeletedAt = nil
+			restored.Version++
+			return &restored, nil
+		}
+	}
+	return nil, pkg.ErrBookNotFound
+}
+func (m *mockBookRepo) PurgeDeletedBooks(ctx context.Context, before time.Time) (int64, error) {
+	return 0, nil
+}
 
 // Helper to build protected app for books
 func buildBookApp(repo ports.BookRepository, tp ports.TokenProvider) *fiber.App {
 	app := fiber.New()
 	svc := usecase.NewBookService(repo)
 	h := NewBookHandler(svc)
-	v1Protected := app.Group("/v1/auth", middleware.NewAuthMiddleware(tp).Protect)
-	h.RegisterRoutes(v1Protected)
+	auth := middleware.NewAuthMiddleware(tp)
+	v1Protected := app.Group("/v1/auth", auth.Protect)
+	h.RegisterRoutes(v1Protected, auth)
 	return app
 }
 
 // Mock TokenProvider (renamed to avoid package-level name collision)
 type mockBookTP struct {
 	validateUserID string
+	validateRole   string
 	validateErr    error
 }
 
-func (m *mockBookTP) GenerateToken(userId string) (string, error) { return "", nil }
-func (m *mockBookTP) ValidateToken(token string) (string, error) {
+func (m *mockBookTP) GenerateToken(claims domain.Claims) (string, error) { return "", nil }
+func (m *mockBookTP) ValidateToken(token string) (*domain.Claims, error) {
 	if m.validateErr != nil {
-		return "", m.validateErr
+		return nil, m.validateErr
 	}
-	return m.validateUserID, nil
+	return &domain.Claims{Subject: m.validateUserID, Role: m.validateRole}, nil
 }
 
 // --- Auth middleware cases ---
@@ -109,10 +172,38 @@ func TestBooks_Auth_InvalidToken(t *testing.T) {
 	}
 }
 
+func TestBooks_Auth_UserCannotWrite(t *testing.T) {
+	repo := &mockBookRepo{}
+	tp := &mockBookTP{validateUserID: "3", validateRole: "user"}
+	app := buildBookApp(repo, tp)
+
+	body := `{"title":"T","author":"A","price":{"amount":"10.00","currency":"USD"},"stock":1}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/auth/books", bytes.NewBufferString(body))
+	req.Header.Set("Authorization", "Bearer good")
+	req.Header.Set("Content-Type", "application/json")
+	resp, _ := app.Test(req)
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d", resp.StatusCode)
+	}
+}
+
+func TestBooks_Auth_UnknownRoleCannotRead(t *testing.T) {
+	repo := &mockBookRepo{}
+	tp := &mockBookTP{validateUserID: "3", validateRole: "guest"}
+	app := buildBookApp(repo, tp)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/auth/books", nil)
+	req.Header.Set("Authorization", "Bearer good")
+	resp, _ := app.Test(req)
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d", resp.StatusCode)
+	}
+}
+
 // --- GET /books ---
 func TestGetAllBooks_Success(t *testing.T) {
-	repo := &mockBookRepo{getAllResult: []*domain.Book{{ID: 2, Title: "B", Author: "A", Price: 10, Stock: 1}}}
-	tp := &mockBookTP{validateUserID: "3"}
+	repo := &mockBookRepo{getAllResult: []*domain.Book{{ID: 2, Title: "B", Author: "A", Price: domain.Money{Amount: 10, Currency: "USD"}, Stock: 1}}}
+	tp := &mockBookTP{validateUserID: "3", validateRole: "user"}
 	app := buildBookApp(repo, tp)
 
 	req := httptest.NewRequest(http.MethodGet, "/v1/auth/books", nil)
@@ -121,16 +212,51 @@ func TestGetAllBooks_Success(t *testing.T) {
 	if resp.StatusCode != http.StatusOK {
 		t.Fatalf("expected 200, got %d", resp.StatusCode)
 	}
-	var got []*domain.Book
+	var got domain.BookPage
 	_ = json.NewDecoder(resp.Body).Decode(&got)
-	if len(got) != 1 || got[0].ID != 2 {
-		t.Fatalf("unexpected books: %#v", got)
+	if len(got.Books) != 1 || got.Books[0].ID != 2 || got.Total != 1 || got.NextCursor != "" {
+		t.Fatalf("unexpected page: %#v", got)
+	}
+}
+
+func TestGetAllBooks_QueryParameters(t *testing.T) {
+	repo := &mockBookRepo{getAllResult: []*domain.Book{}}
+	tp := &mockBookTP{validateUserID: "3", validateRole: "user"}
+	app := buildBookApp(repo, tp)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/auth/books?limit=5&author=Ann+Leckie&title=ancillary&currency=usd&min_price=1.50&max_price=900&in_stock=true&sort=price&order=desc", nil)
+	req.Header.Set("Authorization", "Bearer good")
+	resp, _ := app.Test(req)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	q := repo.lastQuery
+	if q.Limit != 6 || q.Sort != domain.BookSortPrice || !q.Desc {
+		t.Errorf("unexpected paging: %+v", q)
+	}
+	f := q.Filter
+	if f.Author != "Ann Leckie" || f.Title != "ancillary" || f.Currency != "USD" || f.MinPrice.Amount != 150 || f.MaxPrice.Amount != 90000 || !*f.InStock {
+		t.Errorf("unexpected filter: %+v", f)
+	}
+}
+
+func TestGetAllBooks_InvalidQuery(t *testing.T) {
+	tp := &mockBookTP{validateUserID: "3", validateRole: "user"}
+	app := buildBookApp(&mockBookRepo{}, tp)
+
+	for _, query := range []string{"sort=stock", "limit=1000", "limit=ten", "min_price=5", "currency=usd&min_price=cheap", "currency=usd&min_price=1.999", "in_stock=maybe", "order=up", "cursor=garbage"} {
+		req := httptest.NewRequest(http.MethodGet, "/v1/auth/books?"+query, nil)
+		req.Header.Set("Authorization", "Bearer good")
+		resp, _ := app.Test(req)
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, resp.StatusCode)
+		}
 	}
 }
 
 func TestGetAllBooks_RepoError(t *testing.T) {
 	repo := &mockBookRepo{getAllErr: fiber.ErrInternalServerError}
-	tp := &mockBookTP{validateUserID: "3"}
+	tp := &mockBookTP{validateUserID: "3", validateRole: "user"}
 	app := buildBookApp(repo, tp)
 
 	req := httptest.NewRequest(http.MethodGet, "/v1/auth/books", nil)
@@ -143,8 +269,8 @@ func TestGetAllBooks_RepoError(t *testing.T) {
 
 // --- GET /books/:id ---
 func TestGetBookByID_Success(t *testing.T) {
-	repo := &mockBookRepo{getByIDResult: &domain.Book{ID: 5, Title: "X", Author: "Y", Price: 1, Stock: 2}}
-	tp := &mockBookTP{validateUserID: "3"}
+	repo := &mockBookRepo{getByIDResult: &domain.Book{ID: 5, Title: "X", Author: "Y", Price: domain.Money{Amount: 1, Currency: "USD"}, Stock: 2}}
+	tp := &mockBookTP{validateUserID: "3", validateRole: "user"}
 	app := buildBookApp(repo, tp)
 
 	req := httptest.NewRequest(http.MethodGet, "/v1/auth/books/5", nil)
@@ -160,9 +286,38 @@ func TestGetBookByID_Success(t *testing.T) {
 	}
 }
 
+func TestGetBookByID_ETag(t *testing.T) {
+	repo := &mockBookRepo{getByIDResult: &domain.Book{ID: 5, Title: "X", Author: "Y", Version: 3}}
+	tp := &mockBookTP{validateUserID: "3", validateRole: "user"}
+	app := buildBookApp(repo, tp)
+
+	cases := map[string]int{
+		``:         http.StatusOK,
+		`"2"`:      http.StatusOK,
+		`"3"`:      http.StatusNotModified,
+		`W/"3"`:    http.StatusNotModified,
+		`"1", "3"`: http.StatusNotModified,
+		`*`:        http.StatusNotModified,
+	}
+	for ifNoneMatch, want := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/v1/auth/books/5", nil)
+		req.Header.Set("Authorization", "Bearer good")
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		resp, _ := app.Test(req)
+		if resp.StatusCode != want {
+			t.Errorf("If-None-Match %s: expected %d, got %d", ifNoneMatch, want, resp.StatusCode)
+		}
+		if etag := resp.Header.Get("ETag"); etag != `"3"` {
+			t.Errorf("If-None-Match %s: expected ETag \"3\", got %q", ifNoneMatch, etag)
+		}
+	}
+}
+
 func TestGetBookByID_InvalidID(t *testing.T) {
 	repo := &mockBookRepo{getByIDResult: &domain.Book{ID: 5}}
-	tp := &mockBookTP{validateUserID: "3"}
+	tp := &mockBookTP{validateUserID: "3", validateRole: "user"}
 	app := buildBookApp(repo, tp)
 
 	req := httptest.NewRequest(http.MethodGet, "/v1/auth/books/abc", nil)
@@ -175,7 +330,7 @@ func TestGetBookByID_InvalidID(t *testing.T) {
 
 func TestGetBookByID_RepoError(t *testing.T) {
 	repo := &mockBookRepo{getByIDErr: fiber.ErrInternalServerError}
-	tp := &mockBookTP{validateUserID: "3"}
+	tp := &mockBookTP{validateUserID: "3", validateRole: "user"}
 	app := buildBookApp(repo, tp)
 
 	req := httptest.NewRequest(http.MethodGet, "/v1/auth/books/5", nil)
@@ -189,27 +344,175 @@ func TestGetBookByID_RepoError(t *testing.T) {
 // --- POST /books ---
 func TestCreateBook_Success(t *testing.T) {
 	repo := &mockBookRepo{}
-	tp := &mockBookTP{validateUserID: "3"}
+	tp := &mockBookTP{validateUserID: "3", validateRole: "admin"}
 	app := buildBookApp(repo, tp)
 
-	body := `{"title":"T","author":"A","price":10,"stock":1}`
+	body := `{"title":"T","author":"A","price":{"amount":"10.00","currency":"USD"},"stock":1}`
 	req := httptest.NewRequest(http.MethodPost, "/v1/auth/books", bytes.NewBufferString(body))
 	req.Header.Set("Authorization", "Bearer good")
 	req.Header.Set("Content-Type", "application/json")
 	resp, _ := app.Test(req)
-	if resp.StatusCode != http.StatusOK {
-		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", resp.StatusCode)
+	}
+	if loc := resp.Header.Get("Location"); loc != "/v1/auth/books/1" {
+		t.Fatalf("expected Location /v1/auth/books/1, got %q", loc)
 	}
 	var got domain.Book
 	_ = json.NewDecoder(resp.Body).Decode(&got)
-	if got.ID == 0 || got.Title != "T" {
+	if got.ID != 1 || got.Title != "T" || got.CreatedAt.IsZero() || got.UpdatedAt.IsZero() {
+		t.Fatalf("unexpected book: %#v", got)
+	}
+}
+
+func TestCreateBook_Price(t *testing.T) {
+	repo := &mockBookRepo{}
+	tp := &mockBookTP{validateUserID: "3", validateRole: "admin"}
+	app := buildBookApp(repo, tp)
+
+	cases := map[string]int{
+		`{"amount":"19.99","currency":"usd"}`:  http.StatusCreated,
+		`{"amount":19.99,"currency":"USD"}`:    http.StatusCreated,
+		`{"amount":"-1.00","currency":"USD"}`:  http.StatusBadRequest,
+		`{"amount":"19.999","currency":"USD"}`: http.StatusBadRequest,
+		`{"amount":"19.99","currency":"XYZ"}`:  http.StatusBadRequest,
+		`19.99`:                                http.StatusBadRequest,
+		`null`:                                 http.StatusBadRequest,
+	}
+	for price, want := range cases {
+		body := `{"title":"T","author":"A","price":` + price + `,"stock":1}`
+		req := httptest.NewRequest(http.MethodPost, "/v1/auth/books", bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer good")
+		req.Header.Set("Content-Type", "application/json")
+		resp, _ := app.Test(req)
+		if resp.StatusCode != want {
+			t.Errorf("%s: expected %d, got %d", price, want, resp.StatusCode)
+			continue
+		}
+		if want != http.StatusOK {
+			continue
+		}
+		var got map[string]json.RawMessage
+		_ = json.NewDecoder(resp.Body).Decode(&got)
+		if string(got["price"]) != `{"amount":"19.99","currency":"USD"}` {
+			t.Errorf("%s: unexpected price %s", price, got["price"])
+		}
+	}
+}
+
+func TestCreateBook_Details(t *testing.T) {
+	repo := &mockBookRepo{}
+	tp := &mockBookTP{validateUserID: "3", validateRole: "admin"}
+	app := buildBookApp(repo, tp)
+
+	body := `{"title":"T","author":"A","price":{"amount":"10.00","currency":"USD"},"stock":1,
+		"published":"1984-07-01","isbn13":"0-441-56959-5","publisher":"Ace","language":"en-US","page_count":271}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/auth/books", bytes.NewBufferString(body))
+	req.Header.Set("Authorization", "Bearer good")
+	req.Header.Set("Content-Type", "application/json")
+	resp, _ := app.Test(req)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", resp.StatusCode)
+	}
+	var got map[string]interface{}
+	_ = json.NewDecoder(resp.Body).Decode(&got)
+	if got["published"] != "1984-07-01" || got["isbn13"] != "9780441569595" || got["isbn10"] != "0441569595" || got["publisher"] != "Ace" || got["language"] != "en-US" || got["page_count"] != float64(271) {
 		t.Fatalf("unexpected book: %#v", got)
 	}
+	if published := repo.created.Published; published == nil || *published != domain.NewDate(1984, time.July, 1) {
+		t.Errorf("unexpected published date: %v", published)
+	}
+}
+
+func TestCreateBook_InvalidDetails(t *testing.T) {
+	repo := &mockBookRepo{}
+	tp := &mockBookTP{validateUserID: "3", validateRole: "admin"}
+	app := buildBookApp(repo, tp)
+
+	for _, details := range []string{`"isbn13":"978-0-441-56959-4"`, `"isbn13":"0441569596"`, `"published":"01/07/1984"`, `"page_count":-1`, `"language":"english"`} {
+		body := `{"title":"T","author":"A","price":{"amount":"10.00","currency":"USD"},` + details + `}`
+		req := httptest.NewRequest(http.MethodPost, "/v1/auth/books", bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer good")
+		req.Header.Set("Content-Type", "application/json")
+		resp, _ := app.Test(req)
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", details, resp.StatusCode)
+		}
+	}
+}
+
+func TestCreateBook_DuplicateISBN(t *testing.T) {
+	repo := &mockBookRepo{existing: &domain.Book{ID: 7, ISBN13: "9780441569595"}}
+	tp := &mockBookTP{validateUserID: "3", validateRole: "admin"}
+	app := buildBookApp(repo, tp)
+
+	body := `{"title":"T","author":"A","price":{"amount":"10.00","currency":"USD"},"isbn13":"978-0-441-56959-5"}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/auth/books", bytes.NewBufferString(body))
+	req.Header.Set("Authorization", "Bearer good")
+	req.Header.Set("Content-Type", "application/json")
+	resp, _ := app.Test(req)
+	if resp.StatusCode != http.StatusConflict {
+		t.Fatalf("expected 409, got %d", resp.StatusCode)
+	}
+
+	// A race past the check surfaces as the repository's unique violation
+	repo = &mockBookRepo{createErr: pkg.ErrBookAlreadyExists}
+	app = buildBookApp(repo, tp)
+	req = httptest.NewRequest(http.MethodPost, "/v1/auth/books", bytes.NewBufferString(body))
+	req.Header.Set("Authorization", "Bearer good")
+	req.Header.Set("Content-Type", "application/json")
+	resp, _ = app.Test(req)
+	if resp.StatusCode != http.StatusConflict {
+		t.Fatalf("expected 409, got %d", resp.StatusCode)
+	}
+}
+
+func TestCreateBook_ValidationFields(t *testing.T) {
+	repo := &mockBookRepo{}
+	tp := &mockBookTP{validateUserID: "3", validateRole: "admin"}
+	app := buildBookApp(repo, tp)
+
+	body := `{"title":"","author":"A","price":{"amount":"10.00","currency":"USD"},"stock":-1}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/auth/books", bytes.NewBufferString(body))
+	req.Header.Set("Authorization", "Bearer good")
+	req.Header.Set("Content-Type", "application/json")
+	resp, _ := app.Test(req)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", resp.StatusCode)
+	}
+	var got struct {
+		Error  string           `json:"error"`
+		Fields []pkg.FieldError `json:"fields"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&got)
+	if len(got.Fields) != 2 || got.Fields[0].Field != "title" || got.Fields[0].Rule != "required" || got.Fields[1].Field != "stock" || got.Fields[1].Message == "" {
+		t.Fatalf("unexpected response: %+v", got)
+	}
+	if repo.created != nil {
+		t.Fatal("an invalid book must not be stored")
+	}
+}
+
+func TestBooks_NotFound(t *testing.T) {
+	repo := &mockBookRepo{updateErr: pkg.ErrBookNotFound, deleteErr: pkg.ErrBookNotFound}
+	tp := &mockBookTP{validateUserID: "3", validateRole: "admin"}
+	app := buildBookApp(repo, tp)
+
+	body := `{"title":"T","author":"A","price":{"amount":"10.00","currency":"USD"}}`
+	for _, method := range []string{http.MethodGet, http.MethodPut, http.MethodDelete} {
+		req := httptest.NewRequest(method, "/v1/auth/books/404", bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer good")
+		req.Header.Set("Content-Type", "application/json")
+		resp, _ := app.Test(req)
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("%s: expected 404, got %d", method, resp.StatusCode)
+		}
+	}
 }
 
 func TestCreateBook_InvalidJSON(t *testing.T) {
 	repo := &mockBookRepo{}
-	tp := &mockBookTP{validateUserID: "3"}
+	tp := &mockBookTP{validateUserID: "3", validateRole: "admin"}
 	app := buildBookApp(repo, tp)
 
 	req := httptest.NewRequest(http.MethodPost, "/v1/auth/books", bytes.NewBufferString("{"))
@@ -223,10 +526,10 @@ func TestCreateBook_InvalidJSON(t *testing.T) {
 
 func TestCreateBook_RepoError(t *testing.T) {
 	repo := &mockBookRepo{createErr: fiber.ErrInternalServerError}
-	tp := &mockBookTP{validateUserID: "3"}
+	tp := &mockBookTP{validateUserID: "3", validateRole: "admin"}
 	app := buildBookApp(repo, tp)
 
-	body := `{"title":"T","author":"A","price":10,"stock":1}`
+	body := `{"title":"T","author":"A","price":{"amount":"10.00","currency":"USD"},"stock":1}`
 	req := httptest.NewRequest(http.MethodPost, "/v1/auth/books", bytes.NewBufferString(body))
 	req.Header.Set("Authorization", "Bearer good")
 	req.Header.Set("Content-Type", "application/json")
@@ -239,10 +542,10 @@ func TestCreateBook_RepoError(t *testing.T) {
 // --- PUT /books/:id ---
 func TestUpdateBook_Success(t *testing.T) {
 	repo := &mockBookRepo{}
-	tp := &mockBookTP{validateUserID: "3"}
+	tp := &mockBookTP{validateUserID: "3", validateRole: "admin"}
 	app := buildBookApp(repo, tp)
 
-	body := `{"title":"T2","author":"A","price":11,"stock":2}`
+	body := `{"title":"T2","author":"A","price":{"amount":"11.00","currency":"USD"},"stock":2}`
 	req := httptest.NewRequest(http.MethodPut, "/v1/auth/books/7", bytes.NewBufferString(body))
 	req.Header.Set("Authorization", "Bearer good")
 	req.Header.Set("Content-Type", "application/json")
@@ -257,12 +560,92 @@ func TestUpdateBook_Success(t *testing.T) {
 	}
 }
 
+func TestUpdateBook_IfMatch(t *testing.T) {
+	repo := &mockBookRepo{version: 3}
+	tp := &mockBookTP{validateUserID: "3", validateRole: "admin"}
+	app := buildBookApp(repo, tp)
+
+	cases := map[string]int{
+		``:      http.StatusOK,
+		`*`:     http.StatusOK,
+		`"3"`:   http.StatusOK,
+		`"2"`:   http.StatusPreconditionFailed,
+		`W/"3"`: http.StatusPreconditionFailed,
+		`3`:     http.StatusPreconditionFailed,
+	}
+	body := `{"title":"T2","author":"A","price":{"amount":"11.00","currency":"USD"},"stock":2}`
+	for ifMatch, want := range cases {
+		req := httptest.NewRequest(http.MethodPut, "/v1/auth/books/7", bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer good")
+		req.Header.Set("Content-Type", "application/json")
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		resp, _ := app.Test(req)
+		if resp.StatusCode != want {
+			t.Errorf("If-Match %s: expected %d, got %d", ifMatch, want, resp.StatusCode)
+			continue
+		}
+		if want == http.StatusOK && resp.Header.Get("ETag") != `"4"` {
+			t.Errorf("If-Match %s: expected ETag \"4\", got %q", ifMatch, resp.Header.Get("ETag"))
+		}
+	}
+}
+
+func TestPatchBook(t *testing.T) {
+	tp := &mockBookTP{validateUserID: "3", validateRole: "admin"}
+	cases := []struct {
+		contentType, ifMatch, body string
+		want                       int
+	}{
+		{"application/merge-patch+json", "", `{"stock":5}`, http.StatusOK},
+		{"application/json-patch+json", `"3"`, `[{"op":"replace","path":"/price/amount","value":"12.00"}]`, http.StatusOK},
+		{"application/merge-patch+json", `"2"`, `{"stock":5}`, http.StatusPreconditionFailed},
+		{"application/merge-patch+json", "", `{"stock":-1}`, http.StatusBadRequest},
+		{"application/merge-patch+json", "", `[`, http.StatusBadRequest},
+		{"application/json-patch+json", "", `[{"op":"remove","path":"/isbn13"}]`, http.StatusUnprocessableEntity},
+		{"application/json", "", `{"stock":5}`, http.StatusUnsupportedMediaType},
+	}
+	for _, tc := range cases {
+		repo := &mockBookRepo{
+			getByIDResult: &domain.Book{ID: 7, Title: "T", Author: "A", Price: domain.Money{Amount: 1000, Currency: "USD"}, Stock: 2, Version: 3},
+			version:       3,
+		}
+		app := buildBookApp(repo, tp)
+		req := httptest.NewRequest(http.MethodPatch, "/v1/auth/books/7", bytes.NewBufferString(tc.body))
+		req.Header.Set("Authorization", "Bearer good")
+		req.Header.Set("Content-Type", tc.contentType)
+		if tc.ifMatch != "" {
+			req.Header.Set("If-Match", tc.ifMatch)
+		}
+		resp, _ := app.Test(req)
+		if resp.StatusCode != tc.want {
+			t.Errorf("%s %s: expected %d, got %d", tc.contentType, tc.body, tc.want, resp.StatusCode)
+			continue
+		}
+		if tc.want == http.StatusUnsupportedMediaType && resp.Header.Get("Accept-Patch") == "" {
+			t.Errorf("expected Accept-Patch on 415")
+		}
+		if tc.want != http.StatusOK {
+			continue
+		}
+		if len(repo.fields) != 1 || resp.Header.Get("ETag") != `"4"` {
+			t.Errorf("%s: wrote %v with ETag %q", tc.body, repo.fields, resp.Header.Get("ETag"))
+		}
+		var got domain.Book
+		_ = json.NewDecoder(resp.Body).Decode(&got)
+		if got.ID != 7 || got.Title != "T" {
+			t.Errorf("%s: unexpected book: %#v", tc.body, got)
+		}
+	}
+}
+
 func TestUpdateBook_InvalidID(t *testing.T) {
 	repo := &mockBookRepo{}
-	tp := &mockBookTP{validateUserID: "3"}
+	tp := &mockBookTP{validateUserID: "3", validateRole: "admin"}
 	app := buildBookApp(repo, tp)
 
-	body := `{"title":"T2","author":"A","price":11,"stock":2}`
+	body := `{"title":"T2","author":"A","price":{"amount":"11.00","currency":"USD"},"stock":2}`
 	req := httptest.NewRequest(http.MethodPut, "/v1/auth/books/xyz", bytes.NewBufferString(body))
 	req.Header.Set("Authorization", "Bearer good")
 	req.Header.Set("Content-Type", "application/json")
@@ -274,7 +657,7 @@ func TestUpdateBook_InvalidID(t *testing.T) {
 
 func TestUpdateBook_InvalidJSON(t *testing.T) {
 	repo := &mockBookRepo{}
-	tp := &mockBookTP{validateUserID: "3"}
+	tp := &mockBookTP{validateUserID: "3", validateRole: "admin"}
 	app := buildBookApp(repo, tp)
 
 	req := httptest.NewRequest(http.MethodPut, "/v1/auth/books/7", bytes.NewBufferString("{"))
@@ -288,10 +671,10 @@ func TestUpdateBook_InvalidJSON(t *testing.T) {
 
 func TestUpdateBook_RepoError(t *testing.T) {
 	repo := &mockBookRepo{updateErr: fiber.ErrInternalServerError}
-	tp := &mockBookTP{validateUserID: "3"}
+	tp := &mockBookTP{validateUserID: "3", validateRole: "admin"}
 	app := buildBookApp(repo, tp)
 
-	body := `{"title":"T2","author":"A","price":11,"stock":2}`
+	body := `{"title":"T2","author":"A","price":{"amount":"11.00","currency":"USD"},"stock":2}`
 	req := httptest.NewRequest(http.MethodPut, "/v1/auth/books/7", bytes.NewBufferString(body))
 	req.Header.Set("Authorization", "Bearer good")
 	req.Header.Set("Content-Type", "application/json")
@@ -304,7 +687,7 @@ func TestUpdateBook_RepoError(t *testing.T) {
 // --- DELETE /books/:id ---
 func TestDeleteBook_Success(t *testing.T) {
 	repo := &mockBookRepo{}
-	tp := &mockBookTP{validateUserID: "3"}
+	tp := &mockBookTP{validateUserID: "3", validateRole: "admin"}
 	app := buildBookApp(repo, tp)
 
 	req := httptest.NewRequest(http.MethodDelete, "/v1/auth/books/9", nil)
@@ -320,9 +703,25 @@ func TestDeleteBook_Success(t *testing.T) {
 	}
 }
 
+func TestDeleteBook_IfMatch(t *testing.T) {
+	repo := &mockBookRepo{version: 3}
+	tp := &mockBookTP{validateUserID: "3", validateRole: "admin"}
+	app := buildBookApp(repo, tp)
+
+	for ifMatch, want := range map[string]int{`"2"`: http.StatusPreconditionFailed, `"3"`: http.StatusOK} {
+		req := httptest.NewRequest(http.MethodDelete, "/v1/auth/books/7", nil)
+		req.Header.Set("Authorization", "Bearer good")
+		req.Header.Set("If-Match", ifMatch)
+		resp, _ := app.Test(req)
+		if resp.StatusCode != want {
+			t.Errorf("If-Match %s: expected %d, got %d", ifMatch, want, resp.StatusCode)
+		}
+	}
+}
+
 func TestDeleteBook_InvalidID(t *testing.T) {
 	repo := &mockBookRepo{}
-	tp := &mockBookTP{validateUserID: "3"}
+	tp := &mockBookTP{validateUserID: "3", validateRole: "admin"}
 	app := buildBookApp(repo, tp)
 
 	req := httptest.NewRequest(http.MethodDelete, "/v1/auth/books/abc", nil)
@@ -335,7 +734,7 @@ func TestDeleteBook_InvalidID(t *testing.T) {
 
 func TestDeleteBook_RepoError(t *testing.T) {
 	repo := &mockBookRepo{deleteErr: fiber.ErrInternalServerError}
-	tp := &mockBookTP{validateUserID: "3"}
+	tp := &mockBookTP{validateUserID: "3", validateRole: "admin"}
 	app := buildBookApp(repo, tp)
 
 	req := httptest.NewRequest(http.MethodDelete, "/v1/auth/books/9", nil)
@@ -346,13 +745,78 @@ func TestDeleteBook_RepoError(t *testing.T) {
 	}
 }
 
+func TestGetDeletedBooks(t *testing.T) {
+	deletedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	repo := &mockBookRepo{deleted: []*domain.Book{{ID: 7, Title: "T", Version: 2, DeletedAt: &deletedAt}}}
+
+	for role, want := range map[string]int{"admin": http.StatusOK, "user": http.StatusForbidden} {
+		app := buildBookApp(repo, &mockBookTP{validateUserID: "3", validateRole: role})
+		req := httptest.NewRequest(http.MethodGet, "/v1/auth/books/trash", nil)
+		req.Header.Set("Authorization", "Bearer good")
+		resp, _ := app.Test(req)
+		if resp.StatusCode != want {
+			t.Fatalf("%s: expected %d, got %d", role, want, resp.StatusCode)
+		}
+		if want != http.StatusOK {
+			continue
+		}
+		var books []domain.Book
+		if err := json.NewDecoder(resp.Body).Decode(&books); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if len(books) != 1 || books[0].ID != 7 || books[0].DeletedAt == nil {
+			t.Fatalf("unexpected trash: %+v", books)
+		}
+	}
+}
+
+func TestRestoreBook(t *testing.T) {
+	deletedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	repo := &mockBookRepo{deleted: []*domain.Book{{ID: 7, Title: "T", Version: 2, DeletedAt: &deletedAt}}}
+	app := buildBookApp(repo, &mockBookTP{validateUserID: "3", validateRole: "admin"})
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/auth/books/7/restore", nil)
+	req.Header.Set("Authorization", "Bearer good")
+	resp, _ := app.Test(req)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	if etag := resp.Header.Get("ETag"); etag != `"3"` {
+		t.Fatalf("expected ETag \"3\", got %q", etag)
+	}
+	var book domain.Book
+	if err := json.NewDecoder(resp.Body).Decode(&book); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if book.ID != 7 || book.DeletedAt != nil {
+		t.Fatalf("unexpected book: %+v", book)
+	}
+
+	for path, want := range map[string]int{"/v1/auth/books/8/restore": http.StatusNotFound, "/v1/auth/books/abc/restore": http.StatusBadRequest} {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req.Header.Set("Authorization", "Bearer good")
+		resp, _ := app.Test(req)
+		if resp.StatusCode != want {
+			t.Errorf("%s: expected %d, got %d", path, want, resp.StatusCode)
+		}
+	}
+
+	repo.restoreErr = pkg.ErrBookAlreadyExists
+	req = httptest.NewRequest(http.MethodPost, "/v1/auth/books/7/restore", nil)
+	req.Header.Set("Authorization", "Bearer good")
+	resp, _ = app.Test(req)
+	if resp.StatusCode != http.StatusConflict {
+		t.Fatalf("expected 409 when the ISBN is taken, got %d", resp.StatusCode)
+	}
+}
+
 // --- Concurrency safety ---
 func TestCreateBook_Concurrent(t *testing.T) {
 	repo := &mockBookRepo{}
-	tp := &mockBookTP{validateUserID: "3"}
+	tp := &mockBookTP{validateUserID: "3", validateRole: "admin"}
 	app := buildBookApp(repo, tp)
 
-	body := []byte(`{"title":"T","author":"A","price":10,"stock":1}`)
+	body := []byte(`{"title":"T","author":"A","price":{"amount":"10.00","currency":"USD"},"stock":1}`)
 	const N = 10
 	var wg sync.WaitGroup
 	wg.Add(N)
@@ -363,7 +827,7 @@ func TestCreateBook_Concurrent(t *testing.T) {
 			req.Header.Set("Authorization", "Bearer good")
 			req.Header.Set("Content-Type", "application/json")
 			resp, err := app.Test(req)
-			if err != nil || resp.StatusCode != http.StatusOK {
+			if err != nil || resp.StatusCode != http.StatusCreated {
 				t.Errorf("concurrent create failed: err=%v status=%d", err, resp.StatusCode)
 			}
 		}()
@@ -373,8 +837,8 @@ func TestCreateBook_Concurrent(t *testing.T) {
 
 // --- Benchmarks ---
 func BenchmarkGetAllBooks(b *testing.B) {
-	repo := &mockBookRepo{getAllResult: []*domain.Book{{ID: 1, Title: "B", Author: "A", Price: 10, Stock: 1}}}
-	tp := &mockBookTP{validateUserID: "3"}
+	repo := &mockBookRepo{getAllResult: []*domain.Book{{ID: 1, Title: "B", Author: "A", Price: domain.Money{Amount: 10, Currency: "USD"}, Stock: 1}}}
+	tp := &mockBookTP{validateUserID: "3", validateRole: "user"}
 	app := buildBookApp(repo, tp)
 
 	b.ResetTimer()
@@ -390,10 +854,10 @@ func BenchmarkGetAllBooks(b *testing.B) {
 
 func BenchmarkCreateBook(b *testing.B) {
 	repo := &mockBookRepo{}
-	tp := &mockBookTP{validateUserID: "3"}
+	tp := &mockBookTP{validateUserID: "3", validateRole: "admin"}
 	app := buildBookApp(repo, tp)
 
-	body := []byte(`{"title":"T","author":"A","price":10,"stock":1}`)
+	body := []byte(`{"title":"T","author":"A","price":{"amount":"10.00","currency":"USD"},"stock":1}`)
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		req := httptest.NewRequest(http.MethodPost, "/v1/auth/books", bytes.NewBuffer(body))
@@ -405,3 +869,137 @@ func BenchmarkCreateBook(b *testing.B) {
 		}
 	}
 }
+
+// --- API keys ---
+type mockAPIKeyAuth struct {
+	principal *domain.Principal
+}
+
+func (m *mockAPIKeyAuth) AuthenticateAPIKey(ctx context.Context, key string) (*domain.Principal, error) {
+	if m.principal == nil || key != "ak_valid" {
+		return nil, pkg.ErrInvalidAPIKey
+	}
+	return m.principal, nil
+}
+
+func buildBookAppWithAPIKeys(repo ports.BookRepository, keys ports.APIKeyAuthenticator) *fiber.App {
+	app := fiber.New()
+	h := NewBookHandler(usecase.NewBookService(repo))
+	auth := middleware.NewAuthMiddleware(&mockBookTP{validateErr: fiber.ErrUnauthorized})
+	auth.APIKeys = keys
+	h.RegisterRoutes(app.Group("/v1/auth", auth.Protect), auth)
+	return app
+}
+
+func TestBooks_APIKey_ScopeLimitsRole(t *testing.T) {
+	repo := &mockBookRepo{getAllResult: []*domain.Book{{ID: 1, Title: "T"}}}
+	keys := &mockAPIKeyAuth{principal: &domain.Principal{UserID: 1, Role: "admin", APIKeyID: 7, Scopes: []string{domain.PermBooksRead}}}
+	app := buildBookAppWithAPIKeys(repo, keys)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/auth/books", nil)
+	req.Header.Set("X-API-Key", "ak_valid")
+	resp, _ := app.Test(req)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+
+	body := `{"title":"T","author":"A","price":{"amount":"10.00","currency":"USD"},"stock":1}`
+	req = httptest.NewRequest(http.MethodPost, "/v1/auth/books", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-API-Key", "ak_valid")
+	resp, _ = app.Test(req)
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d", resp.StatusCode)
+	}
+}
+
+func TestBooks_APIKey_Invalid(t *testing.T) {
+	app := buildBookAppWithAPIKeys(&mockBookRepo{}, &mockAPIKeyAuth{})
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/auth/books", nil)
+	req.Header.Set("X-API-Key", "ak_unknown")
+	resp, _ := app.Test(req)
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", resp.StatusCode)
+	}
+}
+
+func buildBookSearchApp(search ports.BookSearch) *fiber.App {
+	app := fiber.New()
+	h := NewBookHandler(usecase.NewBookService(&mockBookRepo{}, usecase.WithSearch(search)))
+	auth := middleware.NewAuthMiddleware(&mockBookTP{validateUserID: "3", validateRole: "user"})
+	h.RegisterRoutes(app.Group("/v1/auth", auth.Protect), auth)
+	return app
+}
+
+func searchBooks(t *testing.T, app *fiber.App, query string) (int, domain.BookSearchResult) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/v1/auth/books/search?"+query, nil)
+	req.Header.Set("Authorization", "Bearer good")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	var result domain.BookSearchResult
+	_ = json.NewDecoder(resp.Body).Decode(&result)
+	return resp.StatusCode, result
+}
+
+func TestSearchBooks_RanksAndHighlights(t *testing.T) {
+	app := buildBookSearchApp(memory.NewBookSearch(
+		&domain.Book{ID: 1, Title: "Dune Messiah", Author: "Frank Herbert"},
+		&domain.Book{ID: 2, Title: "Frank & Dune <2>", Author: "Herbert Dune"},
+		&domain.Book{ID: 3, Title: "Neuromancer", Author: "William Gibson"},
+	))
+
+	status, result := searchBooks(t, app, "q=dune")
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d", status)
+	}
+	if len(result.Hits) != 2 || result.Hits[0].Book.ID != 2 || result.Hits[1].Book.ID != 1 {
+		t.Fatalf("unexpected hits: %+v", result.Hits)
+	}
+	if got := result.Hits[0].Highlights; got.Title != "Frank &amp; <mark>Dune</mark> &lt;2&gt;" || got.Author != "Herbert <mark>Dune</mark>" {
+		t.Errorf("unexpected highlights: %+v", got)
+	}
+	if len(result.Suggestions) != 0 {
+		t.Errorf("no suggestions expected with hits, got %v", result.Suggestions)
+	}
+
+	_, result = searchBooks(t, app, "q=dune&limit=1")
+	if len(result.Hits) != 1 {
+		t.Errorf("expected 1 hit with limit=1, got %d", len(result.Hits))
+	}
+}
+
+func TestSearchBooks_SuggestsOnTypo(t *testing.T) {
+	app := buildBookSearchApp(memory.NewBookSearch(
+		&domain.Book{ID: 1, Title: "Neuromancer", Author: "William Gibson"},
+		&domain.Book{ID: 2, Title: "Dune", Author: "Frank Herbert"},
+	))
+
+	status, result := searchBooks(t, app, "q=neuromancr")
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d", status)
+	}
+	if len(result.Hits) != 0 || len(result.Suggestions) != 1 || result.Suggestions[0] != "Neuromancer" {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+}
+
+func TestSearchBooks_InvalidQuery(t *testing.T) {
+	app := buildBookSearchApp(memory.NewBookSearch())
+	for _, query := range []string{"", "q=+++", "q=dune&limit=0x", "q=dune&limit=500"} {
+		if status, _ := searchBooks(t, app, query); status != http.StatusBadRequest {
+			t.Errorf("%q: expected 400, got %d", query, status)
+		}
+	}
+}
+
+func TestSearchBooks_NotEnabled(t *testing.T) {
+	tp := &mockBookTP{validateUserID: "3", validateRole: "user"}
+	app := buildBookApp(&mockBookRepo{}, tp)
+	if status, _ := searchBooks(t, app, "q=dune"); status != http.StatusNotImplemented {
+		t.Fatalf("expected 501, got %d", status)
+	}
+}
@@ -0,0 +1,60 @@
+package http
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"example.com/practice/fiber/internal/adapters/http/middleware"
+	"example.com/practice/fiber/internal/domain"
+	"example.com/practice/fiber/pkg"
+	"github.com/gofiber/fiber/v2"
+)
+
+func impersonationError(c *fiber.Ctx, err error) error {
+	status := fiber.StatusInternalServerError
+	switch err {
+	case pkg.ErrValidationError:
+		status = fiber.StatusBadRequest
+	case pkg.ErrForbidden, pkg.ErrAccountSuspended:
+		status = fiber.StatusForbidden
+	case pkg.ErrUserNotFound:
+		status = fiber.StatusNotFound
+	case pkg.ErrFeatureDisabled:
+		status = fiber.StatusNotImplemented
+	}
+	return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+}
+
+func (h *UserHandler) Impersonate(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("userId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	var req domain.ImpersonationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	req.ClientIP = c.IP()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	resp, err := h.service.Impersonate(ctx, middleware.CurrentPrincipal(c), id, &req)
+	if err != nil {
+		return impersonationError(c, err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(resp)
+}
+
+func (h *UserHandler) ListImpersonations(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	entries, err := h.service.ListImpersonations(ctx, middleware.CurrentPrincipal(c), id)
+	if err != nil {
+		return impersonationError(c, err)
+	}
+	return c.Status(fiber.StatusOK).JSON(entries)
+}
@@ -0,0 +1,24 @@
+package http
+
+import (
+	"example.com/practice/fiber/internal/ports"
+	"github.com/gofiber/fiber/v2"
+)
+
+type JWKSHandler struct {
+	keys ports.KeySet
+}
+
+func NewJWKSHandler(keys ports.KeySet) *JWKSHandler {
+	return &JWKSHandler{keys: keys}
+}
+
+func (h *JWKSHandler) RegisterRoutes(app fiber.Router) {
+	app.Get("/.well-known/jwks.json", h.GetJWKS)
+}
+
+// GetJWKS publishes the public keys that verify our access tokens.
+func (h *JWKSHandler) GetJWKS(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return c.Status(fiber.StatusOK).JSON(h.keys.JWKS())
+}
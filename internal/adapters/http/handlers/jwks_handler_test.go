@@ -0,0 +1,73 @@
+package http
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	jwtadapter "example.com/practice/fiber/internal/adapters/auth/jwt"
+	"example.com/practice/fiber/internal/domain"
+	"github.com/gofiber/fiber/v2"
+)
+
+func newEd25519Key(t *testing.T, id string) *jwtadapter.Key {
+	t.Helper()
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey error: %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		t.Fatalf("MarshalPKCS8PrivateKey error: %v", err)
+	}
+	key, err := jwtadapter.ParseKey(id, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	if err != nil {
+		t.Fatalf("ParseKey error: %v", err)
+	}
+	return key
+}
+
+func getJWKS(t *testing.T, keys *jwtadapter.Provider) domain.JSONWebKeySet {
+	t.Helper()
+	app := fiber.New()
+	NewJWKSHandler(keys).RegisterRoutes(app)
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+	if err != nil {
+		t.Fatalf("app.Test error: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	var got domain.JSONWebKeySet
+	_ = json.NewDecoder(resp.Body).Decode(&got)
+	return got
+}
+
+func TestJWKS_PublishesSigningAndRotatedKeys(t *testing.T) {
+	current := newEd25519Key(t, "current")
+	previous := newEd25519Key(t, "previous")
+	provider, err := jwtadapter.NewKeyProvider(current, []*jwtadapter.Key{previous}, time.Minute)
+	if err != nil {
+		t.Fatalf("NewKeyProvider error: %v", err)
+	}
+	got := getJWKS(t, provider)
+	if len(got.Keys) != 2 || got.Keys[0].Kid != "current" || got.Keys[1].Kid != "previous" {
+		t.Fatalf("unexpected key set: %+v", got)
+	}
+	if got.Keys[0].Kty != "OKP" || got.Keys[0].Alg != "EdDSA" || got.Keys[0].X == "" {
+		t.Fatalf("unexpected key: %+v", got.Keys[0])
+	}
+}
+
+func TestJWKS_EmptyForSharedSecret(t *testing.T) {
+	got := getJWKS(t, jwtadapter.NewProvider([]byte("secret"), time.Minute))
+	if len(got.Keys) != 0 {
+		t.Fatalf("expected no keys, got %+v", got)
+	}
+}
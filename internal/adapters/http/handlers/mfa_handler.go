@@ -0,0 +1,120 @@
+package http
+
+import (
+	"context"
+	"time"
+
+	"example.com/practice/fiber/internal/adapters/http/middleware"
+	"example.com/practice/fiber/internal/domain"
+	"example.com/practice/fiber/pkg"
+	"github.com/gofiber/fiber/v2"
+)
+
+// mfaError maps two-factor errors to a response.
+func mfaError(c *fiber.Ctx, err error) error {
+	status := fiber.StatusInternalServerError
+	switch err {
+	case pkg.ErrValidationError, pkg.ErrMFANotEnabled, pkg.ErrInvalidCurrentPassword:
+		status = fiber.StatusBadRequest
+	case pkg.ErrInvalidMFAToken, pkg.ErrInvalidMFACode:
+		status = fiber.StatusUnauthorized
+	case pkg.ErrForbidden, pkg.ErrMFARequired, pkg.ErrAccountSuspended:
+		status = fiber.StatusForbidden
+	case pkg.ErrUserNotFound:
+		status = fiber.StatusNotFound
+	case pkg.ErrMFAAlreadyEnabled:
+		status = fiber.StatusConflict
+	case pkg.ErrAccountLocked:
+		status = fiber.StatusLocked
+	case pkg.ErrFeatureDisabled:
+		status = fiber.StatusNotImplemented
+	}
+	return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+}
+
+func (h *UserHandler) LoginMFA(c *fiber.Ctx) error {
+	var req domain.MFALoginRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	req.ClientIP = c.IP()
+	req.UserAgent = c.Get(fiber.HeaderUserAgent)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	resp, err := h.service.CompleteMFALogin(ctx, &req)
+	if err != nil {
+		return mfaError(c, err)
+	}
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+func (h *UserHandler) LoginMFASetup(c *fiber.Ctx) error {
+	var req domain.MFALoginRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	resp, err := h.service.SetupMFAEnrollment(ctx, &req)
+	if err != nil {
+		return mfaError(c, err)
+	}
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+func (h *UserHandler) LoginMFAConfirm(c *fiber.Ctx) error {
+	var req domain.MFALoginRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	req.ClientIP = c.IP()
+	req.UserAgent = c.Get(fiber.HeaderUserAgent)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	resp, err := h.service.ConfirmMFAEnrollment(ctx, &req)
+	if err != nil {
+		return mfaError(c, err)
+	}
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+func (h *UserHandler) SetupMFA(c *fiber.Ctx) error {
+	var req domain.MFASetupRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	resp, err := h.service.SetupMFA(ctx, middleware.CurrentPrincipal(c), &req)
+	if err != nil {
+		return mfaError(c, err)
+	}
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+func (h *UserHandler) ConfirmMFA(c *fiber.Ctx) error {
+	var req domain.MFACodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	resp, err := h.service.ConfirmMFA(ctx, middleware.CurrentPrincipal(c), &req)
+	if err != nil {
+		return mfaError(c, err)
+	}
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+func (h *UserHandler) DisableMFA(c *fiber.Ctx) error {
+	var req domain.MFACodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := h.service.DisableMFA(ctx, middleware.CurrentPrincipal(c), &req); err != nil {
+		return mfaError(c, err)
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "two-factor authentication disabled"})
+}
@@ -0,0 +1,82 @@
+package http
+
+import (
+	"context"
+	"crypto/subtle"
+	"time"
+
+	"example.com/practice/fiber/internal/domain"
+	"example.com/practice/fiber/pkg"
+	"github.com/gofiber/fiber/v2"
+)
+
+// oauthStateCookie binds a sign-in to the browser that started it, so a
+// callback URL cannot be replayed in someone else's browser.
+const oauthStateCookie = "oauth_state"
+
+// oauthError maps external sign-in errors to a response.
+func oauthError(c *fiber.Ctx, err error) error {
+	status := fiber.StatusInternalServerError
+	switch err {
+	case pkg.ErrInvalidOAuthState, pkg.ErrOAuthDenied, pkg.ErrOAuthEmailRequired:
+		status = fiber.StatusBadRequest
+	case pkg.ErrOAuthProviderNotFound:
+		status = fiber.StatusNotFound
+	case pkg.ErrUserAlreadyExists:
+		status = fiber.StatusConflict
+	case pkg.ErrAccountSuspended, pkg.ErrEmailNotVerified:
+		status = fiber.StatusForbidden
+	case pkg.ErrOAuthExchangeFailed:
+		status = fiber.StatusBadGateway
+	case pkg.ErrFeatureDisabled:
+		status = fiber.StatusNotImplemented
+	}
+	return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+}
+
+func (h *UserHandler) StartOAuth(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	start, err := h.service.StartOAuth(ctx, c.Params("provider"))
+	if err != nil {
+		return oauthError(c, err)
+	}
+	setOAuthStateCookie(c, start.State, 600)
+	return c.Redirect(start.AuthorizationURL, fiber.StatusFound)
+}
+
+func (h *UserHandler) OAuthCallback(c *fiber.Ctx) error {
+	req := domain.OAuthCallbackRequest{
+		Provider:  c.Params("provider"),
+		State:     c.Query("state"),
+		Code:      c.Query("code"),
+		Error:     c.Query("error"),
+		ClientIP:  c.IP(),
+		UserAgent: c.Get(fiber.HeaderUserAgent),
+	}
+	cookie := c.Cookies(oauthStateCookie)
+	setOAuthStateCookie(c, "", -1)
+	if cookie == "" || subtle.ConstantTimeCompare([]byte(cookie), []byte(req.State)) != 1 {
+		return oauthError(c, pkg.ErrInvalidOAuthState)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	resp, err := h.service.CompleteOAuth(ctx, &req)
+	if err != nil {
+		return oauthError(c, err)
+	}
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// setOAuthStateCookie sets the state cookie; a negative maxAge deletes it.
+func setOAuthStateCookie(c *fiber.Ctx, state string, maxAge int) {
+	c.Cookie(&fiber.Cookie{
+		Name:     oauthStateCookie,
+		Value:    state,
+		Path:     "/v1/oauth",
+		MaxAge:   maxAge,
+		Secure:   c.Protocol() == "https",
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+}
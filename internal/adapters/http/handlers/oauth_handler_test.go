@@ -0,0 +1,237 @@
+package http
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"example.com/practice/fiber/internal/adapters/auth/oidc"
+	"example.com/practice/fiber/internal/domain"
+	"example.com/practice/fiber/internal/ports"
+	usercase "example.com/practice/fiber/internal/usecase/user"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// --- Stand-in identity provider ---
+type standInIdP struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	// codes maps issued authorization codes to the PKCE challenge and nonce
+	// of the request they were issued for.
+	codes map[string][2]string
+}
+
+func newStandInIdP(t *testing.T) *standInIdP {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp := &standInIdP{key: key, codes: map[string][2]string{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 idp.server.URL,
+			"authorization_endpoint": idp.server.URL + "/authorize",
+			"token_endpoint":         idp.server.URL + "/token",
+			"jwks_uri":               idp.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(domain.JSONWebKeySet{Keys: []domain.JSONWebKey{{
+			Kty: "RSA",
+			Kid: "idp-key",
+			Use: "sig",
+			Alg: "RS256",
+			N:   base64.RawURLEncoding.EncodeToString(key.PublicKey.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.PublicKey.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		clientID, secret, _ := r.BasicAuth()
+		request, ok := idp.codes[r.FormValue("code")]
+		sum := sha256.Sum256([]byte(r.FormValue("code_verifier")))
+		if !ok || clientID != "shop" || secret != "s3cret" || r.FormValue("grant_type") != "authorization_code" ||
+			base64.RawURLEncoding.EncodeToString(sum[:]) != request[0] {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		delete(idp.codes, r.FormValue("code"))
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+			"iss":            idp.server.URL,
+			"aud":            "shop",
+			"sub":            "idp-user-1",
+			"exp":            time.Now().Add(time.Minute).Unix(),
+			"iat":            time.Now().Unix(),
+			"nonce":          request[1],
+			"email":          "jane@example.com",
+			"email_verified": true,
+			"given_name":     "Jane",
+		})
+		token.Header["kid"] = "idp-key"
+		idToken, _ := token.SignedString(key)
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "at", "token_type": "Bearer", "id_token": idToken})
+	})
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+// --- Mocks ---
+type mockIdentityRepo struct {
+	identities []*domain.UserIdentity
+}
+
+func (m *mockIdentityRepo) GetUserIdentity(ctx context.Context, provider, subject string) (*domain.UserIdentity, error) {
+	for _, identity := range m.identities {
+		if identity.Provider == provider && identity.Subject == subject {
+			return identity, nil
+		}
+	}
+	return nil, nil
+}
+func (m *mockIdentityRepo) CreateUserIdentity(ctx context.Context, identity *domain.UserIdentity) error {
+	identity.ID = int64(len(m.identities) + 1)
+	m.identities = append(m.identities, identity)
+	return nil
+}
+func (m *mockIdentityRepo) CreateUserWithIdentity(ctx context.Context, user *domain.User, identity *domain.UserIdentity) error {
+	user.ID = 42
+	identity.UserID = user.ID
+	return m.CreateUserIdentity(ctx, identity)
+}
+func (m *mockIdentityRepo) TouchUserIdentity(ctx context.Context, id int64, loginAt time.Time) error {
+	return nil
+}
+
+type mockOAuthStateRepo struct {
+	states map[string]*domain.OAuthState
+}
+
+func (m *mockOAuthStateRepo) CreateOAuthState(ctx context.Context, state *domain.OAuthState) error {
+	m.states[state.StateHash] = state
+	return nil
+}
+func (m *mockOAuthStateRepo) ConsumeOAuthState(ctx context.Context, stateHash string) (*domain.OAuthState, error) {
+	state := m.states[stateHash]
+	delete(m.states, stateHash)
+	return state, nil
+}
+func (m *mockOAuthStateRepo) DeleteExpiredOAuthStates(ctx context.Context, before time.Time) (int64, error) {
+	return 0, nil
+}
+
+func buildOAuthApp(idp *standInIdP, repo ports.UserRepository) *fiber.App {
+	provider := oidc.NewProvider(oidc.Config{
+		Issuer:       idp.server.URL,
+		ClientID:     "shop",
+		ClientSecret: "s3cret",
+		RedirectURL:  "http://localhost:3000/v1/oauth/test/callback",
+	})
+	provider.HTTPClient = idp.server.Client()
+	app := fiber.New()
+	svc := usercase.NewUserService(repo, &mockTP{token: "jwt-token"},
+		usercase.WithOAuth(&mockIdentityRepo{}, &mockOAuthStateRepo{states: map[string]*domain.OAuthState{}},
+			map[string]ports.IdentityProvider{"test": provider}))
+	NewUserHandler(svc).RegisterNotProtected(app.Group("/v1"))
+	return app
+}
+
+// startOAuth follows /start and plays the provider's authorization endpoint:
+// it issues code for the request and returns the callback URL and cookie.
+func startOAuth(t *testing.T, app *fiber.App, idp *standInIdP, code string) (string, *http.Cookie) {
+	resp, _ := app.Test(httptest.NewRequest(http.MethodGet, "/v1/oauth/test/start", nil))
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("expected 302, got %d", resp.StatusCode)
+	}
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := location.Query()
+	if location.Path != "/authorize" || query.Get("code_challenge_method") != "S256" || query.Get("client_id") != "shop" {
+		t.Fatalf("unexpected authorization URL: %s", location)
+	}
+	idp.codes[code] = [2]string{query.Get("code_challenge"), query.Get("nonce")}
+	var cookie *http.Cookie
+	for _, c := range resp.Cookies() {
+		if c.Name == "oauth_state" {
+			cookie = c
+		}
+	}
+	if cookie == nil || !cookie.HttpOnly {
+		t.Fatalf("expected an HttpOnly state cookie")
+	}
+	return "/v1/oauth/test/callback?code=" + code + "&state=" + url.QueryEscape(query.Get("state")), cookie
+}
+
+func TestOAuth_SignInWithStandInIdP(t *testing.T) {
+	idp := newStandInIdP(t)
+	app := buildOAuthApp(idp, &mockUserRepo{})
+	callback, cookie := startOAuth(t, app, idp, "code-1")
+
+	req := httptest.NewRequest(http.MethodGet, callback, nil)
+	req.AddCookie(cookie)
+	resp, _ := app.Test(req)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	var body domain.AuthResponse
+	json.NewDecoder(resp.Body).Decode(&body)
+	if body.Token != "jwt-token" {
+		t.Fatalf("expected our token, got %+v", body)
+	}
+
+	// The state is single-use
+	req = httptest.NewRequest(http.MethodGet, callback, nil)
+	req.AddCookie(cookie)
+	resp, _ = app.Test(req)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 on replay, got %d", resp.StatusCode)
+	}
+}
+
+func TestOAuth_CallbackWithoutStateCookie(t *testing.T) {
+	idp := newStandInIdP(t)
+	app := buildOAuthApp(idp, &mockUserRepo{})
+	callback, _ := startOAuth(t, app, idp, "code-1")
+
+	resp, _ := app.Test(httptest.NewRequest(http.MethodGet, callback, nil))
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", resp.StatusCode)
+	}
+}
+
+func TestOAuth_RejectedCode(t *testing.T) {
+	idp := newStandInIdP(t)
+	app := buildOAuthApp(idp, &mockUserRepo{})
+	callback, cookie := startOAuth(t, app, idp, "code-1")
+	// The provider only accepts the verifier whose challenge it was given
+	idp.codes["code-1"] = [2]string{"other-challenge", ""}
+
+	req := httptest.NewRequest(http.MethodGet, callback, nil)
+	req.AddCookie(cookie)
+	resp, _ := app.Test(req)
+	if resp.StatusCode != http.StatusBadGateway {
+		t.Fatalf("expected 502, got %d", resp.StatusCode)
+	}
+}
+
+func TestOAuth_UnknownProvider(t *testing.T) {
+	app := buildOAuthApp(newStandInIdP(t), &mockUserRepo{})
+
+	resp, _ := app.Test(httptest.NewRequest(http.MethodGet, "/v1/oauth/nope/start", nil))
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", resp.StatusCode)
+	}
+}
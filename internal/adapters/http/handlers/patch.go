@@ -0,0 +1,20 @@
+package http
+
+import (
+	"strings"
+
+	domains "example.com/practice/fiber/internal/domain"
+	"github.com/gofiber/fiber/v2"
+)
+
+// acceptPatch lists the patch media types for the Accept-Patch header.
+const acceptPatch = domains.MergePatchType + ", " + domains.JSONPatchType
+
+// requestPatch returns the body of a PATCH request with its media type.
+func requestPatch(c *fiber.Ctx) domains.Patch {
+	mediaType, _, _ := strings.Cut(c.Get(fiber.HeaderContentType), ";")
+	return domains.Patch{
+		Type: strings.ToLower(strings.TrimSpace(mediaType)),
+		Body: c.Body(),
+	}
+}
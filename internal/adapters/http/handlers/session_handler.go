@@ -0,0 +1,44 @@
+package http
+
+import (
+	"context"
+	"time"
+
+	"example.com/practice/fiber/internal/adapters/http/middleware"
+	"example.com/practice/fiber/pkg"
+	"github.com/gofiber/fiber/v2"
+)
+
+func (h *UserHandler) ListSessions(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	sessions, err := h.service.ListSessions(ctx, middleware.CurrentPrincipal(c))
+	if err != nil {
+		if err == pkg.ErrForbidden {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+		}
+		if err == pkg.ErrFeatureDisabled {
+			return c.Status(fiber.StatusNotImplemented).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(fiber.StatusOK).JSON(sessions)
+}
+
+func (h *UserHandler) RevokeSession(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := h.service.RevokeSession(ctx, middleware.CurrentPrincipal(c), c.Params("id")); err != nil {
+		if err == pkg.ErrForbidden {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+		}
+		if err == pkg.ErrSessionNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+		}
+		if err == pkg.ErrFeatureDisabled {
+			return c.Status(fiber.StatusNotImplemented).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "session ended"})
+}
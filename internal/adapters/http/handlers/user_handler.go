@@ -2,9 +2,11 @@ package http
 
 import (
 	"context"
+	"errors"
 	"strconv"
 	"time"
 
+	"example.com/practice/fiber/internal/adapters/http/middleware"
 	"example.com/practice/fiber/internal/domain"
 	usercase "example.com/practice/fiber/internal/usecase/user"
 	"example.com/practice/fiber/pkg"
@@ -19,18 +21,77 @@ func NewUserHandler(service *usercase.UserService) *UserHandler {
 	return &UserHandler{service: service}
 }
 
+// userRoute names the route that reads a single user, for Location headers.
+const userRoute = "users.get"
+
 func (h *UserHandler) RegisterNotProtected(app fiber.Router) {
 	app.Post("/register", h.RegisterUser)
 	app.Post("/login", h.LoginUser)
+	app.Post("/login/mfa", h.LoginMFA)
+	app.Post("/login/mfa/setup", h.LoginMFASetup)
+	app.Post("/login/mfa/confirm", h.LoginMFAConfirm)
+	app.Post("/token/refresh", h.RefreshToken)
+	app.Post("/password/forgot", h.ForgotPassword)
+	app.Post("/password/reset", h.ResetPassword)
+	app.Get("/verify-email", h.VerifyEmail)
+	app.Post("/verify-email/resend", h.ResendVerification)
+	app.Get("/oauth/:provider/start", h.StartOAuth)
+	app.Get("/oauth/:provider/callback", h.OAuthCallback)
+}
+
+func (h *UserHandler) RegisterProtected(app fiber.Router, auth *middleware.AuthMiddleware) {
+	app.Get("/user/:id", auth.RequirePermission(domain.PermProfileRead), h.GetUserByID).Name(userRoute)
+	app.Get("/user/email/:email", auth.RequirePermission(domain.PermUsersRead), h.GetUserByEmail)
+	app.Get("/users", auth.RequirePermission(domain.PermUsersRead), h.GetAllUsers)
+	app.Put("/user/:id", auth.RequirePermission(domain.PermProfileWrite), h.UpdateUser)
+	app.Delete("/user/:id", auth.RequirePermission(domain.PermUsersManage), auth.DenyImpersonation, h.DeleteUser)
+	app.Put("/user/:id/password", auth.RequirePermission(domain.PermProfileWrite), auth.DenyImpersonation, h.UpdatePassword)
+
+	app.Get("/me", auth.RequirePermission(domain.PermProfileRead), h.GetUserByID)
+	app.Put("/me", auth.RequirePermission(domain.PermProfileWrite), h.UpdateUser)
+	app.Patch("/me", auth.RequirePermission(domain.PermProfileWrite), h.PatchUser)
+	app.Delete("/me", auth.RequirePermission(domain.PermProfileWrite), auth.DenyImpersonation, h.DeleteUser)
+	app.Put("/me/password", auth.RequirePermission(domain.PermProfileWrite), auth.DenyImpersonation, h.UpdatePassword)
+	app.Post("/me/2fa/setup", auth.RequirePermission(domain.PermProfileWrite), auth.DenyImpersonation, h.SetupMFA)
+	app.Post("/me/2fa/confirm", auth.RequirePermission(domain.PermProfileWrite), auth.DenyImpersonation, h.ConfirmMFA)
+	app.Post("/me/2fa/disable", auth.RequirePermission(domain.PermProfileWrite), auth.DenyImpersonation, h.DisableMFA)
+	app.Get("/me/api-keys", auth.RequirePermission(domain.PermProfileRead), h.ListAPIKeys)
+	app.Post("/me/api-keys", auth.RequirePermission(domain.PermProfileWrite), auth.DenyImpersonation, h.CreateAPIKey)
+	app.Delete("/me/api-keys/:id", auth.RequirePermission(domain.PermProfileWrite), auth.DenyImpersonation, h.RevokeAPIKey)
+	app.Get("/me/sessions", auth.RequirePermission(domain.PermProfileRead), h.ListSessions)
+	app.Delete("/me/sessions/:id", auth.RequirePermission(domain.PermProfileWrite), auth.DenyImpersonation, h.RevokeSession)
+
+	app.Post("/logout", h.Logout)
+	app.Post("/admin/users/:id/revoke-tokens", auth.RequirePermission(domain.PermUsersManage), h.RevokeUserTokens)
+	app.Post("/admin/users/:id/suspend", auth.RequirePermission(domain.PermUsersManage), h.SuspendUser)
+	app.Post("/admin/users/:id/reactivate", auth.RequirePermission(domain.PermUsersManage), h.ReactivateUser)
+	app.Post("/admin/impersonate/:userId", auth.RequirePermission(domain.PermUsersImpersonate), h.Impersonate)
+	app.Get("/admin/users/:id/impersonations", auth.RequirePermission(domain.PermUsersManage), h.ListImpersonations)
+	app.Get("/admin/users/trash", auth.RequirePermission(domain.PermUsersManage), h.GetDeletedUsers)
+	app.Post("/admin/users/:id/restore", auth.RequirePermission(domain.PermUsersManage), h.RestoreUser)
+}
+
+// targetUserID returns the :id path parameter, or the caller's own ID on the
+// /me routes.
+func targetUserID(c *fiber.Ctx) (int, error) {
+	if c.Params("id") == "" {
+		principal := middleware.CurrentPrincipal(c)
+		if principal == nil {
+			return 0, fiber.ErrUnauthorized
+		}
+		return principal.UserID, nil
+	}
+	return strconv.Atoi(c.Params("id"))
 }
 
-func (h *UserHandler) RegisterProtected(app fiber.Router) {
-	app.Get("/user/:id", h.GetUserByID)
-	app.Get("/user/email/:email", h.GetUserByEmail)
-	app.Get("/users", h.GetAllUsers)
-	app.Put("/user/:id", h.UpdateUser)
-	app.Delete("/user/:id", h.DeleteUser)
-	app.Put("/user/:id/password", h.UpdatePassword)
+// passwordViolations returns the broken password rules when err is a
+// *pkg.PasswordPolicyError.
+func passwordViolations(err error) ([]pkg.PasswordViolation, bool) {
+	var policyErr *pkg.PasswordPolicyError
+	if errors.As(err, &policyErr) {
+		return policyErr.Violations, true
+	}
+	return nil, false
 }
 
 func (h *UserHandler) RegisterUser(c *fiber.Ctx) error {
@@ -40,16 +101,24 @@ func (h *UserHandler) RegisterUser(c *fiber.Ctx) error {
 	}
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
-	if err := h.service.Register(ctx, &user); err != nil {
+	created, err := h.service.Register(ctx, &user)
+	if err != nil {
 		if err == pkg.ErrUserAlreadyExists {
 			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
 		}
+		if violations, ok := passwordViolations(err); ok {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error(), "violations": violations})
+		}
 		if err == pkg.ErrValidationError {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
-	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"message": "user registered"})
+	if location, err := c.GetRouteURL(userRoute, fiber.Map{"id": created.ID}); err == nil && location != "" {
+		c.Location(location)
+	}
+	created.Password = ""
+	return c.Status(fiber.StatusCreated).JSON(created)
 }
 
 func (h *UserHandler) LoginUser(c *fiber.Ctx) error {
@@ -57,35 +126,75 @@ func (h *UserHandler) LoginUser(c *fiber.Ctx) error {
 	if err := c.BodyParser(&user); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
+	user.ClientIP = c.IP()
+	user.UserAgent = c.Get(fiber.HeaderUserAgent)
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
-	token, err := h.service.Login(ctx, &user)
+	resp, err := h.service.Login(ctx, &user)
 	if err != nil {
-		if err == pkg.ErrUserNotFound {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+		if err == pkg.ErrInvalidCredentials {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
 		}
 		if err == pkg.ErrEmptyPassword || err == pkg.ErrValidationError {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 		}
+		if err == pkg.ErrEmailNotVerified || err == pkg.ErrAccountSuspended {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+		}
+		if err == pkg.ErrAccountLocked {
+			return c.Status(fiber.StatusLocked).JSON(fiber.Map{"error": err.Error()})
+		}
+		if err == pkg.ErrTooManyLoginAttempts {
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+func (h *UserHandler) RefreshToken(c *fiber.Ctx) error {
+	var req domain.RefreshRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	req.ClientIP = c.IP()
+	req.UserAgent = c.Get(fiber.HeaderUserAgent)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	resp, err := h.service.RefreshToken(ctx, &req)
+	if err != nil {
+		if err == pkg.ErrInvalidRefreshToken || err == pkg.ErrRefreshTokenReused {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+		}
+		if err == pkg.ErrAccountSuspended {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+		}
+		if err == pkg.ErrValidationError {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{"token": token})
+	return c.Status(fiber.StatusOK).JSON(resp)
 }
 
 func (h *UserHandler) GetUserByID(c *fiber.Ctx) error {
-	id, err := strconv.Atoi(c.Params("id"))
+	id, err := targetUserID(c)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
-	user, err := h.service.GetUserByID(ctx, strconv.Itoa(id))
+	user, err := h.service.GetUserByID(ctx, middleware.CurrentPrincipal(c), strconv.Itoa(id))
 	if err != nil {
+		if err == pkg.ErrForbidden {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+		}
 		if err == pkg.ErrUserNotFound {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
 		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
+	user.Password = ""
 	return c.Status(fiber.StatusOK).JSON(user)
 }
 
@@ -100,6 +209,7 @@ func (h *UserHandler) GetUserByEmail(c *fiber.Ctx) error {
 		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
+	user.Password = ""
 	return c.Status(fiber.StatusOK).JSON(user)
 }
 
@@ -113,11 +223,14 @@ func (h *UserHandler) GetAllUsers(c *fiber.Ctx) error {
 		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
+	for _, user := range users {
+		user.Password = ""
+	}
 	return c.Status(fiber.StatusOK).JSON(users)
 }
 
 func (h *UserHandler) UpdateUser(c *fiber.Ctx) error {
-	id, err := strconv.Atoi(c.Params("id"))
+	id, err := targetUserID(c)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
@@ -127,7 +240,11 @@ func (h *UserHandler) UpdateUser(c *fiber.Ctx) error {
 	}
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
-	if err := h.service.UpdateUser(ctx, id, &user); err != nil {
+	updated, err := h.service.UpdateUser(ctx, middleware.CurrentPrincipal(c), id, &user)
+	if err != nil {
+		if err == pkg.ErrForbidden {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+		}
 		if err == pkg.ErrUserNotFound {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
 		}
@@ -136,17 +253,55 @@ func (h *UserHandler) UpdateUser(c *fiber.Ctx) error {
 		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "user updated"})
+	updated.Password = ""
+	return c.Status(fiber.StatusOK).JSON(updated)
+}
+
+// PatchUser changes the caller's names with a JSON merge patch or JSON
+// patch, chosen by Content-Type.
+func (h *UserHandler) PatchUser(c *fiber.Ctx) error {
+	id, err := targetUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	patched, err := h.service.PatchUser(ctx, middleware.CurrentPrincipal(c), id, requestPatch(c))
+	if err != nil {
+		var invalid *pkg.ValidationError
+		if errors.As(err, &invalid) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error(), "fields": invalid.Fields})
+		}
+		switch err {
+		case pkg.ErrForbidden:
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+		case pkg.ErrUserNotFound:
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+		case pkg.ErrValidationError, pkg.ErrInvalidPatch:
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		case pkg.ErrPatchFailed:
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": err.Error()})
+		case pkg.ErrUnsupportedPatch:
+			c.Set("Accept-Patch", acceptPatch)
+			return c.Status(fiber.StatusUnsupportedMediaType).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	patched.Password = ""
+	return c.Status(fiber.StatusOK).JSON(patched)
 }
 
 func (h *UserHandler) DeleteUser(c *fiber.Ctx) error {
-	id, err := strconv.Atoi(c.Params("id"))
+	id, err := targetUserID(c)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
-	if err := h.service.DeleteUser(ctx, id); err != nil {
+	if err := h.service.DeleteUser(ctx, middleware.CurrentPrincipal(c), id); err != nil {
+		if err == pkg.ErrForbidden {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+		}
 		if err == pkg.ErrUserNotFound {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
 		}
@@ -156,7 +311,7 @@ func (h *UserHandler) DeleteUser(c *fiber.Ctx) error {
 }
 
 func (h *UserHandler) UpdatePassword(c *fiber.Ctx) error {
-	id, err := strconv.Atoi(c.Params("id"))
+	id, err := targetUserID(c)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
@@ -169,14 +324,206 @@ func (h *UserHandler) UpdatePassword(c *fiber.Ctx) error {
 	if err := pkg.ValidateStruct(ctx, &req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
-	if err := h.service.UpdatePassword(ctx, id, req); err != nil {
+	if err := h.service.UpdatePassword(ctx, middleware.CurrentPrincipal(c), id, req); err != nil {
+		if err == pkg.ErrForbidden {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+		}
 		if err == pkg.ErrUserNotFound {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
 		}
-		if err == pkg.ErrValidationError {
+		if violations, ok := passwordViolations(err); ok {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error(), "violations": violations})
+		}
+		if err == pkg.ErrValidationError || err == pkg.ErrInvalidCurrentPassword {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "password updated"})
 }
+
+func (h *UserHandler) Logout(c *fiber.Ctx) error {
+	var req domain.LogoutRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := h.service.Logout(ctx, middleware.CurrentPrincipal(c), &req); err != nil {
+		if err == pkg.ErrForbidden {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "logged out"})
+}
+
+func (h *UserHandler) RevokeUserTokens(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := h.service.RevokeUserTokens(ctx, middleware.CurrentPrincipal(c), id); err != nil {
+		if err == pkg.ErrForbidden {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "tokens revoked"})
+}
+
+func (h *UserHandler) SuspendUser(c *fiber.Ctx) error {
+	return h.setUserStatus(c, h.service.SuspendUser)
+}
+
+func (h *UserHandler) ReactivateUser(c *fiber.Ctx) error {
+	return h.setUserStatus(c, h.service.ReactivateUser)
+}
+
+func (h *UserHandler) setUserStatus(c *fiber.Ctx, apply func(context.Context, *domain.Principal, int, *domain.UserStatusRequest) (*domain.UserStatusChange, error)) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	var req domain.UserStatusRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	change, err := apply(ctx, middleware.CurrentPrincipal(c), id, &req)
+	if err != nil {
+		if err == pkg.ErrForbidden {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+		}
+		if err == pkg.ErrUserNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+		}
+		if err == pkg.ErrValidationError {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(fiber.StatusOK).JSON(change)
+}
+
+// GetDeletedUsers lists the users in the trash.
+func (h *UserHandler) GetDeletedUsers(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	users, err := h.service.GetDeletedUsers(ctx, middleware.CurrentPrincipal(c))
+	if err != nil {
+		if err == pkg.ErrForbidden {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	for _, user := range users {
+		user.Password = ""
+	}
+	return c.Status(fiber.StatusOK).JSON(users)
+}
+
+// RestoreUser takes a user out of the trash.
+func (h *UserHandler) RestoreUser(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	user, err := h.service.RestoreUser(ctx, middleware.CurrentPrincipal(c), id)
+	if err != nil {
+		if err == pkg.ErrForbidden {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+		}
+		if err == pkg.ErrUserNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+		}
+		if err == pkg.ErrUserAlreadyExists {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	user.Password = ""
+	return c.Status(fiber.StatusOK).JSON(user)
+}
+
+func (h *UserHandler) ForgotPassword(c *fiber.Ctx) error {
+	var req domain.ForgotPasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := h.service.ForgotPassword(ctx, &req); err != nil {
+		if err == pkg.ErrValidationError {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		if err == pkg.ErrFeatureDisabled {
+			return c.Status(fiber.StatusNotImplemented).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{"message": "if the account exists, a reset link has been sent"})
+}
+
+func (h *UserHandler) ResetPassword(c *fiber.Ctx) error {
+	var req domain.ResetPasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := h.service.ResetPassword(ctx, &req); err != nil {
+		if violations, ok := passwordViolations(err); ok {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error(), "violations": violations})
+		}
+		if err == pkg.ErrValidationError || err == pkg.ErrInvalidResetToken {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		if err == pkg.ErrFeatureDisabled {
+			return c.Status(fiber.StatusNotImplemented).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "password reset"})
+}
+
+func (h *UserHandler) VerifyEmail(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := h.service.VerifyEmail(ctx, c.Query("token")); err != nil {
+		if err == pkg.ErrValidationError || err == pkg.ErrInvalidVerificationToken {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		if err == pkg.ErrFeatureDisabled {
+			return c.Status(fiber.StatusNotImplemented).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "email verified"})
+}
+
+func (h *UserHandler) ResendVerification(c *fiber.Ctx) error {
+	var req domain.ResendVerificationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := h.service.ResendVerification(ctx, &req); err != nil {
+		if err == pkg.ErrValidationError {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		if err == pkg.ErrFeatureDisabled {
+			return c.Status(fiber.StatusNotImplemented).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{"message": "if the account needs verification, a link has been sent"})
+}
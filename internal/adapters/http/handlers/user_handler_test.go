@@ -7,8 +7,10 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"example.com/practice/fiber/internal/adapters/http/middleware"
+	"example.com/practice/fiber/internal/adapters/memory"
 	"example.com/practice/fiber/internal/domain"
 	"example.com/practice/fiber/internal/ports"
 	usercase "example.com/practice/fiber/internal/usecase/user"
@@ -28,11 +30,18 @@ type mockUserRepo struct {
 	updateUserErr        error
 	deleteUserErr        error
 	updatePasswordErr    error
+	deletedUsers         []*domain.User
+	restoreUserErr       error
 }
 
 // Implement ports.UserRepository
-func (m *mockUserRepo) CreateUser(ctx context.Context, user *domain.User) error {
-	return m.createUserErr
+func (m *mockUserRepo) CreateUser(ctx context.Context, user *domain.User) (*domain.User, error) {
+	if m.createUserErr != nil {
+		return nil, m.createUserErr
+	}
+	stored := *user
+	stored.ID = 1
+	return &stored, nil
 }
 func (m *mockUserRepo) GetUserByID(ctx context.Context, id string) (*domain.User, error) {
 	return m.getUserByIDResult, m.getUserByIDErr
@@ -43,39 +52,97 @@ func (m *mockUserRepo) GetUserByEmail(ctx context.Context, email string) (*domai
 func (m *mockUserRepo) GetAllUsers(ctx context.Context) ([]*domain.User, error) {
 	return m.getAllUsersResult, m.getAllUsersErr
 }
-func (m *mockUserRepo) UpdateUser(ctx context.Context, user *domain.User) error {
-	return m.updateUserErr
+func (m *mockUserRepo) UpdateUser(ctx context.Context, user *domain.User) (*domain.User, error) {
+	if m.updateUserErr != nil {
+		return nil, m.updateUserErr
+	}
+	stored := *user
+	return &stored, nil
+}
+func (m *mockUserRepo) UpdateUserFields(ctx context.Context, user *domain.User, fields []string) (*domain.User, error) {
+	if m.updateUserErr != nil {
+		return nil, m.updateUserErr
+	}
+	stored := *m.getUserByIDResult
+	stored.FirstName, stored.LastName = user.FirstName, user.LastName
+	return &stored, nil
 }
 func (m *mockUserRepo) DeleteUser(ctx context.Context, id int) error { return m.deleteUserErr }
 func (m *mockUserRepo) UpdatePassword(ctx context.Context, id int, password string) error {
 	return m.updatePasswordErr
 }
+func (m *mockUserRepo) RehashPassword(ctx context.Context, id int, oldHash, newHash string) error {
+	return nil
+}
+func (m *mockUserRepo) MarkEmailVerified(ctx context.Context, id int) error { return nil }
+func (m *mockUserRepo) SetUserStatus(ctx context.Context, change *domain.UserStatusChange) error {
+	return nil
+}
+func (m *mockUserRepo) GetDeletedUsers(ctx context.Context) ([]*domain.User, error) {
+	return m.deletedUsers, nil
+}
+func (m *mockUserRepo) RestoreUser(ctx context.Context, id int) (*domain.User, error) {
+	if m.restoreUserErr != nil {
+		return nil, m.restoreUserErr
+	}
+	for _, user := range m.deletedUsers {
+		if user.ID == id {
+			restored := *user
+			restored.DeletedAt = nil
+			return &restored, nil
+		}
+	}
+	return nil, pkg.ErrUserNotFound
+}
+func (m *mockUserRepo) PurgeDeletedUsers(ctx context.Context, before time.Time) (int64, error) {
+	return 0, nil
+}
 
 type mockTP struct {
-	token          string
-	validateUserID string
-	validateErr    error
+	token           string
+	validateUserID  string
+	validateRole    string
+	validateJTI     string
+	validatePurpose string
+	validateSession string
+	validateActor   string
+	validateErr     error
 }
 
-func (m *mockTP) GenerateToken(userId string) (string, error) { return m.token, nil }
-func (m *mockTP) ValidateToken(token string) (string, error) {
+func (m *mockTP) GenerateToken(claims domain.Claims) (string, error) { return m.token, nil }
+func (m *mockTP) ValidateToken(token string) (*domain.Claims, error) {
 	if m.validateErr != nil {
-		return "", m.validateErr
+		return nil, m.validateErr
+	}
+	role := m.validateRole
+	if role == "" {
+		role = domain.RoleUser
 	}
-	return m.validateUserID, nil
+	return &domain.Claims{
+		ID:        m.validateJTI,
+		Subject:   m.validateUserID,
+		Role:      role,
+		Purpose:   m.validatePurpose,
+		SessionID: m.validateSession,
+		Actor:     m.validateActor,
+		IssuedAt:  time.Now(),
+		ExpiresAt: time.Now().Add(time.Minute),
+	}, nil
 }
 
 // Helper to build app with routes
 func buildApp(repo ports.UserRepository, tp ports.TokenProvider) *fiber.App {
 	app := fiber.New()
-	svc := usercase.NewUserService(repo, tp)
+	svc := usercase.NewUserService(repo, tp,
+		usercase.WithPasswordPolicy(domain.PasswordPolicy{ForbidPersonalInfo: true}, nil))
 	h := NewUserHandler(svc)
 
 	v1 := app.Group("/v1")
 	h.RegisterNotProtected(v1)
 
-	v1Protected := app.Group("/v1/auth", middleware.NewAuthMiddleware(tp).Protect)
-	h.RegisterProtected(v1Protected)
+	auth := middleware.NewAuthMiddleware(tp)
+	v1Protected := app.Group("/v1/auth", auth.Protect)
+	h.RegisterProtected(v1Protected, auth)
 	return app
 }
 
@@ -95,10 +162,13 @@ func TestRegisterUser_Success(t *testing.T) {
 	if resp.StatusCode != http.StatusCreated {
 		t.Fatalf("expected 201, got %d", resp.StatusCode)
 	}
-	var got map[string]string
+	if loc := resp.Header.Get("Location"); loc != "/v1/auth/user/1" {
+		t.Fatalf("expected Location /v1/auth/user/1, got %q", loc)
+	}
+	var got domain.User
 	_ = json.NewDecoder(resp.Body).Decode(&got)
-	if got["message"] != "user registered" {
-		t.Fatalf("expected message 'user registered', got %v", got)
+	if got.ID != 1 || got.Email != "user@example.com" || got.Password != "" {
+		t.Fatalf("unexpected user: %#v", got)
 	}
 }
 
@@ -131,6 +201,26 @@ func TestRegisterUser_ValidationError(t *testing.T) {
 	}
 }
 
+func TestRegisterUser_WeakPassword(t *testing.T) {
+	app := buildApp(&mockUserRepo{}, &mockTP{})
+
+	body := `{"email":"user@example.com","password":"user1234","first_name":"User","last_name":"Example","username":"user123"}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/register", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, _ := app.Test(req)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", resp.StatusCode)
+	}
+	var got struct {
+		Error      string                  `json:"error"`
+		Violations []pkg.PasswordViolation `json:"violations"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&got)
+	if got.Error != pkg.ErrWeakPassword.Error() || len(got.Violations) == 0 {
+		t.Fatalf("expected password violations, got %+v", got)
+	}
+}
+
 func TestRegisterUser_InternalError(t *testing.T) {
 	repo := &mockUserRepo{createUserErr: fiber.ErrInternalServerError}
 	tp := &mockTP{}
@@ -148,7 +238,7 @@ func TestRegisterUser_InternalError(t *testing.T) {
 // --- LoginUser ---
 func TestLoginUser_Success(t *testing.T) {
 	hashed, _ := pkg.HashPassword("password123")
-	repo := &mockUserRepo{getUserByEmailResult: &domain.User{ID: 3, Email: "user@example.com", Password: hashed, Username: "user123"}}
+	repo := &mockUserRepo{getUserByEmailResult: &domain.User{ID: 3, Email: "user@example.com", Password: hashed, IsActive: true, Username: "user123"}}
 	tp := &mockTP{token: "token-123"}
 	app := buildApp(repo, tp)
 
@@ -201,7 +291,7 @@ func TestLoginUser_ValidationError(t *testing.T) {
 
 func TestLoginUser_WrongPassword(t *testing.T) {
 	hashed, _ := pkg.HashPassword("password123")
-	repo := &mockUserRepo{getUserByEmailResult: &domain.User{ID: 3, Email: "user@example.com", Password: hashed, Username: "user123"}}
+	repo := &mockUserRepo{getUserByEmailResult: &domain.User{ID: 3, Email: "user@example.com", Password: hashed, IsActive: true, Username: "user123"}}
 	tp := &mockTP{}
 	app := buildApp(repo, tp)
 
@@ -210,8 +300,50 @@ func TestLoginUser_WrongPassword(t *testing.T) {
 	req := httptest.NewRequest(http.MethodPost, "/v1/login", bytes.NewBuffer(b))
 	req.Header.Set("Content-Type", "application/json")
 	resp, _ := app.Test(req)
-	if resp.StatusCode != http.StatusInternalServerError {
-		t.Fatalf("expected 500, got %d", resp.StatusCode)
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", resp.StatusCode)
+	}
+}
+
+func TestLoginUser_UnknownEmail(t *testing.T) {
+	repo := &mockUserRepo{}
+	tp := &mockTP{}
+	app := buildApp(repo, tp)
+
+	payload := map[string]string{"email": "nobody@example.com", "password": "wrong"}
+	b, _ := json.Marshal(payload)
+	req := httptest.NewRequest(http.MethodPost, "/v1/login", bytes.NewBuffer(b))
+	req.Header.Set("Content-Type", "application/json")
+	resp, _ := app.Test(req)
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", resp.StatusCode)
+	}
+	var got map[string]string
+	_ = json.NewDecoder(resp.Body).Decode(&got)
+	if got["error"] != pkg.ErrInvalidCredentials.Error() {
+		t.Fatalf("expected the wrong-password error, got %v", got)
+	}
+}
+
+func TestLoginUser_Locked(t *testing.T) {
+	hashed, _ := pkg.HashPassword("password123")
+	repo := &mockUserRepo{getUserByEmailResult: &domain.User{ID: 3, Email: "user@example.com", Password: hashed, IsActive: true, Username: "user123"}}
+	tp := &mockTP{token: "token-123"}
+	app := fiber.New()
+	svc := usercase.NewUserService(repo, tp, usercase.WithLoginThrottle(memory.NewLoginAttemptStore(), domain.LockoutPolicy{MaxAttempts: 2}))
+	NewUserHandler(svc).RegisterNotProtected(app.Group("/v1"))
+
+	login := func(password string) int {
+		b, _ := json.Marshal(map[string]string{"email": "user@example.com", "password": password})
+		req := httptest.NewRequest(http.MethodPost, "/v1/login", bytes.NewBuffer(b))
+		req.Header.Set("Content-Type", "application/json")
+		resp, _ := app.Test(req)
+		return resp.StatusCode
+	}
+	login("wrong")
+	login("wrong")
+	if status := login("password123"); status != http.StatusLocked {
+		t.Fatalf("expected 423, got %d", status)
 	}
 }
 
@@ -242,7 +374,7 @@ func TestGetUserByID_InvalidToken(t *testing.T) {
 }
 
 func TestGetUserByID_Success(t *testing.T) {
-	repo := &mockUserRepo{getUserByIDResult: &domain.User{ID: 3, Email: "user@example.com"}}
+	repo := &mockUserRepo{getUserByIDResult: &domain.User{ID: 3, Email: "user@example.com", Password: "$2a$10$hash"}}
 	tp := &mockTP{validateUserID: "3"}
 	app := buildApp(repo, tp)
 
@@ -257,6 +389,9 @@ func TestGetUserByID_Success(t *testing.T) {
 	if u.ID != 3 {
 		t.Fatalf("expected user ID 3, got %d", u.ID)
 	}
+	if u.Password != "" {
+		t.Fatalf("expected no password hash, got %q", u.Password)
+	}
 }
 
 func TestGetUserByID_BadParam(t *testing.T) {
@@ -285,10 +420,37 @@ func TestGetUserByID_RepoError(t *testing.T) {
 	}
 }
 
+// --- Protected: role checks ---
+func TestGetAllUsers_ForbiddenForUser(t *testing.T) {
+	repo := &mockUserRepo{getAllUsersResult: []*domain.User{{ID: 1}}}
+	tp := &mockTP{validateUserID: "3", validateRole: "user"}
+	app := buildApp(repo, tp)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/auth/users", nil)
+	req.Header.Set("Authorization", "Bearer good")
+	resp, _ := app.Test(req)
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d", resp.StatusCode)
+	}
+}
+
+func TestDeleteUser_ForbiddenForUser(t *testing.T) {
+	repo := &mockUserRepo{}
+	tp := &mockTP{validateUserID: "3", validateRole: "user"}
+	app := buildApp(repo, tp)
+
+	req := httptest.NewRequest(http.MethodDelete, "/v1/auth/user/4", nil)
+	req.Header.Set("Authorization", "Bearer good")
+	resp, _ := app.Test(req)
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d", resp.StatusCode)
+	}
+}
+
 // --- Protected: GetUserByEmail ---
 func TestGetUserByEmail_Success(t *testing.T) {
-	repo := &mockUserRepo{getUserByEmailResult: &domain.User{ID: 3, Email: "user@example.com"}}
-	tp := &mockTP{validateUserID: "3"}
+	repo := &mockUserRepo{getUserByEmailResult: &domain.User{ID: 3, Email: "user@example.com", Password: "$2a$10$hash"}}
+	tp := &mockTP{validateUserID: "3", validateRole: "admin"}
 	app := buildApp(repo, tp)
 
 	req := httptest.NewRequest(http.MethodGet, "/v1/auth/user/email/user@example.com", nil)
@@ -297,11 +459,16 @@ func TestGetUserByEmail_Success(t *testing.T) {
 	if resp.StatusCode != http.StatusOK {
 		t.Fatalf("expected 200, got %d", resp.StatusCode)
 	}
+	var u domain.User
+	_ = json.NewDecoder(resp.Body).Decode(&u)
+	if u.Password != "" {
+		t.Fatalf("expected no password hash, got %q", u.Password)
+	}
 }
 
 func TestGetUserByEmail_RepoError(t *testing.T) {
 	repo := &mockUserRepo{getUserByEmailErr: fiber.ErrInternalServerError}
-	tp := &mockTP{validateUserID: "3"}
+	tp := &mockTP{validateUserID: "3", validateRole: "admin"}
 	app := buildApp(repo, tp)
 
 	req := httptest.NewRequest(http.MethodGet, "/v1/auth/user/email/user@example.com", nil)
@@ -314,8 +481,8 @@ func TestGetUserByEmail_RepoError(t *testing.T) {
 
 // --- Protected: GetAllUsers ---
 func TestGetAllUsers_Success(t *testing.T) {
-	repo := &mockUserRepo{getAllUsersResult: []*domain.User{{ID: 1}, {ID: 2}}}
-	tp := &mockTP{validateUserID: "3"}
+	repo := &mockUserRepo{getAllUsersResult: []*domain.User{{ID: 1, Password: "$2a$10$hash"}, {ID: 2, Password: "$2a$10$hash"}}}
+	tp := &mockTP{validateUserID: "3", validateRole: "admin"}
 	app := buildApp(repo, tp)
 
 	req := httptest.NewRequest(http.MethodGet, "/v1/auth/users", nil)
@@ -329,11 +496,16 @@ func TestGetAllUsers_Success(t *testing.T) {
 	if len(users) != 2 {
 		t.Fatalf("expected 2 users, got %d", len(users))
 	}
+	for _, u := range users {
+		if u.Password != "" {
+			t.Fatalf("expected no password hash, got %q", u.Password)
+		}
+	}
 }
 
 func TestGetAllUsers_RepoError(t *testing.T) {
 	repo := &mockUserRepo{getAllUsersErr: fiber.ErrInternalServerError}
-	tp := &mockTP{validateUserID: "3"}
+	tp := &mockTP{validateUserID: "3", validateRole: "admin"}
 	app := buildApp(repo, tp)
 
 	req := httptest.NewRequest(http.MethodGet, "/v1/auth/users", nil)
@@ -360,6 +532,53 @@ func TestUpdateUser_Success(t *testing.T) {
 	if resp.StatusCode != http.StatusOK {
 		t.Fatalf("expected 200, got %d", resp.StatusCode)
 	}
+	var got domain.User
+	_ = json.NewDecoder(resp.Body).Decode(&got)
+	if got.ID != 3 || got.FirstName != "Updated" || got.LastName != "User" {
+		t.Fatalf("unexpected user: %#v", got)
+	}
+}
+
+func TestPatchMe(t *testing.T) {
+	repo := &mockUserRepo{
+		getUserByIDResult: &domain.User{ID: 3, Email: "user@example.com", FirstName: "Current", LastName: "User"},
+	}
+	tp := &mockTP{validateUserID: "3"}
+	app := buildApp(repo, tp)
+
+	cases := []struct {
+		contentType, body string
+		want              int
+	}{
+		{"application/merge-patch+json", `{"first_name":"Patched"}`, http.StatusOK},
+		{"application/json-patch+json; charset=utf-8", `[{"op":"replace","path":"/first_name","value":"Patched"}]`, http.StatusOK},
+		{"application/json-patch+json", `[{"op":"test","path":"/first_name","value":"Other"}]`, http.StatusUnprocessableEntity},
+		{"application/merge-patch+json", `{"first_name":"Patched"`, http.StatusBadRequest},
+		{"application/merge-patch+json", `{"role":"admin"}`, http.StatusBadRequest},
+		{"application/json", `{"first_name":"Patched"}`, http.StatusUnsupportedMediaType},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodPatch, "/v1/auth/me", bytes.NewBufferString(tc.body))
+		req.Header.Set("Content-Type", tc.contentType)
+		req.Header.Set("Authorization", "Bearer good")
+		resp, _ := app.Test(req)
+		if resp.StatusCode != tc.want {
+			t.Errorf("%s %s: expected %d, got %d", tc.contentType, tc.body, tc.want, resp.StatusCode)
+			continue
+		}
+		switch tc.want {
+		case http.StatusOK:
+			var got domain.User
+			_ = json.NewDecoder(resp.Body).Decode(&got)
+			if got.FirstName != "Patched" || got.LastName != "User" || got.Email != "user@example.com" {
+				t.Errorf("%s: unexpected user: %#v", tc.contentType, got)
+			}
+		case http.StatusUnsupportedMediaType:
+			if accept := resp.Header.Get("Accept-Patch"); accept != "application/merge-patch+json, application/json-patch+json" {
+				t.Errorf("unexpected Accept-Patch %q", accept)
+			}
+		}
+	}
 }
 
 func TestUpdateUser_BadParam(t *testing.T) {
@@ -428,7 +647,7 @@ func TestUpdateUser_RepoError(t *testing.T) {
 // --- Protected: DeleteUser ---
 func TestDeleteUser_Success(t *testing.T) {
 	repo := &mockUserRepo{}
-	tp := &mockTP{validateUserID: "3"}
+	tp := &mockTP{validateUserID: "3", validateRole: "admin"}
 	app := buildApp(repo, tp)
 
 	req := httptest.NewRequest(http.MethodDelete, "/v1/auth/user/3", nil)
@@ -441,7 +660,7 @@ func TestDeleteUser_Success(t *testing.T) {
 
 func TestDeleteUser_BadParam(t *testing.T) {
 	repo := &mockUserRepo{}
-	tp := &mockTP{validateUserID: "3"}
+	tp := &mockTP{validateUserID: "3", validateRole: "admin"}
 	app := buildApp(repo, tp)
 
 	req := httptest.NewRequest(http.MethodDelete, "/v1/auth/user/abc", nil)
@@ -454,7 +673,7 @@ func TestDeleteUser_BadParam(t *testing.T) {
 
 func TestDeleteUser_RepoError(t *testing.T) {
 	repo := &mockUserRepo{deleteUserErr: fiber.ErrInternalServerError}
-	tp := &mockTP{validateUserID: "3"}
+	tp := &mockTP{validateUserID: "3", validateRole: "admin"}
 	app := buildApp(repo, tp)
 
 	req := httptest.NewRequest(http.MethodDelete, "/v1/auth/user/3", nil)
@@ -467,13 +686,14 @@ func TestDeleteUser_RepoError(t *testing.T) {
 
 // --- Protected: UpdatePassword ---
 func TestUpdatePassword_Success(t *testing.T) {
+	hashed, _ := pkg.HashPassword("password123")
 	repo := &mockUserRepo{
-		getUserByIDResult: &domain.User{ID: 3, Email: "user@example.com"},
+		getUserByIDResult: &domain.User{ID: 3, Email: "user@example.com", Password: hashed, IsActive: true},
 	}
 	tp := &mockTP{validateUserID: "3"}
 	app := buildApp(repo, tp)
 
-	body := `{"new_password":"newpassword123"}`
+	body := `{"current_password":"password123","new_password":"newpassword123"}`
 	req := httptest.NewRequest(http.MethodPut, "/v1/auth/user/3/password", bytes.NewBufferString(body))
 	req.Header.Set("Authorization", "Bearer good")
 	req.Header.Set("Content-Type", "application/json")
@@ -514,11 +734,12 @@ func TestUpdatePassword_InvalidJSON(t *testing.T) {
 }
 
 func TestUpdatePassword_ValidationError(t *testing.T) {
-	repo := &mockUserRepo{}
+	hashed, _ := pkg.HashPassword("password123")
+	repo := &mockUserRepo{getUserByIDResult: &domain.User{ID: 3, Email: "user@example.com", Password: hashed, IsActive: true}}
 	tp := &mockTP{validateUserID: "3"}
 	app := buildApp(repo, tp)
 
-	body := `{"new_password":"short"}`
+	body := `{"current_password":"password123","new_password":"short"}`
 	req := httptest.NewRequest(http.MethodPut, "/v1/auth/user/3/password", bytes.NewBufferString(body))
 	req.Header.Set("Authorization", "Bearer good")
 	req.Header.Set("Content-Type", "application/json")
@@ -529,11 +750,12 @@ func TestUpdatePassword_ValidationError(t *testing.T) {
 }
 
 func TestUpdatePassword_RepoError(t *testing.T) {
-	repo := &mockUserRepo{updatePasswordErr: fiber.ErrInternalServerError, getUserByIDResult: &domain.User{ID: 3, Email: "user@example.com"}}
+	hashed, _ := pkg.HashPassword("password123")
+	repo := &mockUserRepo{updatePasswordErr: fiber.ErrInternalServerError, getUserByIDResult: &domain.User{ID: 3, Email: "user@example.com", Password: hashed, IsActive: true}}
 	tp := &mockTP{validateUserID: "3"}
 	app := buildApp(repo, tp)
 
-	body := `{"new_password":"newpassword123"}`
+	body := `{"current_password":"password123","new_password":"newpassword123"}`
 	req := httptest.NewRequest(http.MethodPut, "/v1/auth/user/3/password", bytes.NewBufferString(body))
 	req.Header.Set("Authorization", "Bearer good")
 	req.Header.Set("Content-Type", "application/json")
@@ -542,3 +764,544 @@ func TestUpdatePassword_RepoError(t *testing.T) {
 		t.Fatalf("expected 500, got %d", resp.StatusCode)
 	}
 }
+
+// --- RefreshToken ---
+func TestRefreshToken_Invalid(t *testing.T) {
+	repo := &mockUserRepo{}
+	tp := &mockTP{}
+	app := buildApp(repo, tp)
+
+	body := `{"refresh_token":"unknown"}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/token/refresh", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, _ := app.Test(req)
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", resp.StatusCode)
+	}
+}
+
+func TestRefreshToken_InvalidJSON(t *testing.T) {
+	repo := &mockUserRepo{}
+	tp := &mockTP{}
+	app := buildApp(repo, tp)
+
+	body := `{"refresh_token":` // malformed
+	req := httptest.NewRequest(http.MethodPost, "/v1/token/refresh", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, _ := app.Test(req)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", resp.StatusCode)
+	}
+}
+
+func TestUpdatePassword_WrongCurrentPassword(t *testing.T) {
+	hashed, _ := pkg.HashPassword("password123")
+	repo := &mockUserRepo{getUserByIDResult: &domain.User{ID: 3, Email: "user@example.com", Password: hashed, IsActive: true}}
+	tp := &mockTP{validateUserID: "3"}
+	app := buildApp(repo, tp)
+
+	body := `{"current_password":"wrongpassword","new_password":"newpassword123"}`
+	req := httptest.NewRequest(http.MethodPut, "/v1/auth/me/password", bytes.NewBufferString(body))
+	req.Header.Set("Authorization", "Bearer good")
+	req.Header.Set("Content-Type", "application/json")
+	resp, _ := app.Test(req)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", resp.StatusCode)
+	}
+}
+
+func TestUpdatePassword_OtherUserForbidden(t *testing.T) {
+	repo := &mockUserRepo{getUserByIDResult: &domain.User{ID: 4, Email: "other@example.com"}}
+	tp := &mockTP{validateUserID: "3"}
+	app := buildApp(repo, tp)
+
+	body := `{"new_password":"newpassword123"}`
+	req := httptest.NewRequest(http.MethodPut, "/v1/auth/user/4/password", bytes.NewBufferString(body))
+	req.Header.Set("Authorization", "Bearer good")
+	req.Header.Set("Content-Type", "application/json")
+	resp, _ := app.Test(req)
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d", resp.StatusCode)
+	}
+}
+
+// --- Protected: /me ---
+func TestGetMe_Success(t *testing.T) {
+	repo := &mockUserRepo{getUserByIDResult: &domain.User{ID: 3, Email: "user@example.com"}}
+	tp := &mockTP{validateUserID: "3"}
+	app := buildApp(repo, tp)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/auth/me", nil)
+	req.Header.Set("Authorization", "Bearer good")
+	resp, _ := app.Test(req)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	var u domain.User
+	_ = json.NewDecoder(resp.Body).Decode(&u)
+	if u.ID != 3 {
+		t.Fatalf("expected user ID 3, got %d", u.ID)
+	}
+}
+
+func TestUpdateUser_OtherUserForbidden(t *testing.T) {
+	repo := &mockUserRepo{getUserByIDResult: &domain.User{ID: 4, Email: "other@example.com"}}
+	tp := &mockTP{validateUserID: "3"}
+	app := buildApp(repo, tp)
+
+	body := `{"first_name":"Updated","last_name":"User"}`
+	req := httptest.NewRequest(http.MethodPut, "/v1/auth/user/4", bytes.NewBufferString(body))
+	req.Header.Set("Authorization", "Bearer good")
+	req.Header.Set("Content-Type", "application/json")
+	resp, _ := app.Test(req)
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d", resp.StatusCode)
+	}
+}
+
+func TestDeleteMe_Success(t *testing.T) {
+	repo := &mockUserRepo{}
+	tp := &mockTP{validateUserID: "3"}
+	app := buildApp(repo, tp)
+
+	req := httptest.NewRequest(http.MethodDelete, "/v1/auth/me", nil)
+	req.Header.Set("Authorization", "Bearer good")
+	resp, _ := app.Test(req)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+}
+
+// --- Protected: Logout ---
+func TestLogout_RevokesToken(t *testing.T) {
+	repo := &mockUserRepo{getUserByIDResult: &domain.User{ID: 3, Email: "user@example.com"}}
+	tp := &mockTP{validateUserID: "3", validateJTI: "jti-1"}
+	store := memory.NewTokenRevocationStore()
+
+	app := fiber.New()
+	svc := usercase.NewUserService(repo, tp, usercase.WithTokenRevocation(store, time.Minute))
+	auth := middleware.NewAuthMiddleware(tp)
+	auth.Revocations = store
+	NewUserHandler(svc).RegisterProtected(app.Group("/v1/auth", auth.Protect), auth)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/auth/logout", nil)
+	req.Header.Set("Authorization", "Bearer good")
+	resp, _ := app.Test(req)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/v1/auth/me", nil)
+	req.Header.Set("Authorization", "Bearer good")
+	resp, _ = app.Test(req)
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401 after logout, got %d", resp.StatusCode)
+	}
+}
+
+func TestRevokeUserTokens_ForbiddenForUser(t *testing.T) {
+	repo := &mockUserRepo{}
+	tp := &mockTP{validateUserID: "3"}
+	app := buildApp(repo, tp)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/auth/admin/users/4/revoke-tokens", nil)
+	req.Header.Set("Authorization", "Bearer good")
+	resp, _ := app.Test(req)
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d", resp.StatusCode)
+	}
+}
+
+func TestRevokeUserTokens_Admin(t *testing.T) {
+	repo := &mockUserRepo{}
+	tp := &mockTP{validateUserID: "3", validateRole: "admin"}
+	app := buildApp(repo, tp)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/auth/admin/users/4/revoke-tokens", nil)
+	req.Header.Set("Authorization", "Bearer good")
+	resp, _ := app.Test(req)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+}
+
+// --- Password reset ---
+func TestForgotPassword_NotEnabled(t *testing.T) {
+	repo := &mockUserRepo{}
+	tp := &mockTP{}
+	app := buildApp(repo, tp)
+
+	body := `{"email":"user@example.com"}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/password/forgot", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, _ := app.Test(req)
+	if resp.StatusCode != http.StatusNotImplemented {
+		t.Fatalf("expected 501, got %d", resp.StatusCode)
+	}
+}
+
+func TestResetPassword_ValidationError(t *testing.T) {
+	repo := &mockUserRepo{}
+	tp := &mockTP{}
+	app := buildApp(repo, tp)
+
+	body := `{"token":"","new_password":"short"}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/password/reset", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, _ := app.Test(req)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", resp.StatusCode)
+	}
+}
+
+// --- Email verification ---
+func TestVerifyEmail_NotEnabled(t *testing.T) {
+	repo := &mockUserRepo{}
+	tp := &mockTP{}
+	app := buildApp(repo, tp)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/verify-email?token=abc", nil)
+	resp, _ := app.Test(req)
+	if resp.StatusCode != http.StatusNotImplemented {
+		t.Fatalf("expected 501, got %d", resp.StatusCode)
+	}
+}
+
+// --- Two-factor authentication ---
+func TestProtect_RejectsMFAPendingToken(t *testing.T) {
+	repo := &mockUserRepo{getUserByIDResult: &domain.User{ID: 5}}
+	tp := &mockTP{validateUserID: "5", validatePurpose: domain.PurposeMFAPending}
+	app := buildApp(repo, tp)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/auth/me", nil)
+	req.Header.Set("Authorization", "Bearer pending")
+	resp, _ := app.Test(req)
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", resp.StatusCode)
+	}
+}
+
+func TestLoginMFA_NotEnabled(t *testing.T) {
+	repo := &mockUserRepo{}
+	tp := &mockTP{}
+	app := buildApp(repo, tp)
+
+	body := `{"mfa_token":"pending","code":"123456"}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/login/mfa", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, _ := app.Test(req)
+	if resp.StatusCode != http.StatusNotImplemented {
+		t.Fatalf("expected 501, got %d", resp.StatusCode)
+	}
+}
+
+func TestSetupMFA_Success(t *testing.T) {
+	hash, _ := pkg.HashPassword("password123")
+	repo := &mockUserRepo{getUserByIDResult: &domain.User{ID: 5, Email: "user@example.com", Password: hash}}
+	tp := &mockTP{validateUserID: "5"}
+	app := fiber.New()
+	svc := usercase.NewUserService(repo, tp, usercase.WithMFA(&mockMFARepo{}, "CMAS", nil))
+	auth := middleware.NewAuthMiddleware(tp)
+	NewUserHandler(svc).RegisterProtected(app.Group("/v1/auth", auth.Protect), auth)
+
+	body := `{"current_password":"password123"}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/auth/me/2fa/setup", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer good")
+	resp, _ := app.Test(req)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	var got domain.MFASetupResponse
+	_ = json.NewDecoder(resp.Body).Decode(&got)
+	if got.Secret == "" || got.URI == "" {
+		t.Fatalf("expected secret and otpauth url, got %+v", got)
+	}
+}
+
+func TestSetupMFA_RequiresCurrentPassword(t *testing.T) {
+	hash, _ := pkg.HashPassword("password123")
+	repo := &mockUserRepo{getUserByIDResult: &domain.User{ID: 5, Email: "user@example.com", Password: hash}}
+	tp := &mockTP{validateUserID: "5"}
+	app := fiber.New()
+	mfaRepo := &mockMFARepo{}
+	svc := usercase.NewUserService(repo, tp, usercase.WithMFA(mfaRepo, "CMAS", nil))
+	auth := middleware.NewAuthMiddleware(tp)
+	NewUserHandler(svc).RegisterProtected(app.Group("/v1/auth", auth.Protect), auth)
+
+	for _, body := range []string{`{}`, `{"current_password":"wrong"}`} {
+		req := httptest.NewRequest(http.MethodPost, "/v1/auth/me/2fa/setup", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer good")
+		resp, _ := app.Test(req)
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d", body, resp.StatusCode)
+		}
+	}
+	if mfaRepo.mfa != nil {
+		t.Fatalf("expected no secret to be stored, got %+v", mfaRepo.mfa)
+	}
+}
+
+func TestConfirmMFA_InvalidCode(t *testing.T) {
+	tp := &mockTP{validateUserID: "5"}
+	app := fiber.New()
+	mfaRepo := &mockMFARepo{mfa: &domain.MFA{UserID: 5, Secret: "JBSWY3DPEHPK3PXP"}}
+	svc := usercase.NewUserService(&mockUserRepo{}, tp, usercase.WithMFA(mfaRepo, "CMAS", nil))
+	auth := middleware.NewAuthMiddleware(tp)
+	NewUserHandler(svc).RegisterProtected(app.Group("/v1/auth", auth.Protect), auth)
+
+	body := `{"code":"abcdef"}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/auth/me/2fa/confirm", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer good")
+	resp, _ := app.Test(req)
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", resp.StatusCode)
+	}
+}
+
+type mockMFARepo struct {
+	mfa *domain.MFA
+}
+
+func (m *mockMFARepo) GetMFA(ctx context.Context, userID int) (*domain.MFA, error) {
+	return m.mfa, nil
+}
+func (m *mockMFARepo) SaveMFASecret(ctx context.Context, userID int, secret string) (bool, error) {
+	m.mfa = &domain.MFA{UserID: userID, Secret: secret}
+	return true, nil
+}
+func (m *mockMFARepo) EnableMFA(ctx context.Context, userID int, recoveryCodeHashes []string) (bool, error) {
+	return true, nil
+}
+func (m *mockMFARepo) DisableMFA(ctx context.Context, userID int) error { return nil }
+func (m *mockMFARepo) UpdateMFALastUsedStep(ctx context.Context, userID int, step int64) (bool, error) {
+	return true, nil
+}
+func (m *mockMFARepo) UseRecoveryCode(ctx context.Context, userID int, codeHash string) (bool, error) {
+	return false, nil
+}
+
+func TestGetUserByID_Missing(t *testing.T) {
+	repo := &mockUserRepo{}
+	tp := &mockTP{validateUserID: "3", validateRole: "admin"}
+	app := buildApp(repo, tp)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/auth/user/42", nil)
+	req.Header.Set("Authorization", "Bearer good")
+	resp, _ := app.Test(req)
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", resp.StatusCode)
+	}
+}
+
+// --- Suspension ---
+func TestSuspendUser_Admin(t *testing.T) {
+	repo := &mockUserRepo{getUserByIDResult: &domain.User{ID: 4, IsActive: true}}
+	tp := &mockTP{validateUserID: "3", validateRole: "admin"}
+	app := buildApp(repo, tp)
+
+	body := `{"reason":"abuse reports"}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/auth/admin/users/4/suspend", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer good")
+	resp, _ := app.Test(req)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	var got domain.UserStatusChange
+	_ = json.NewDecoder(resp.Body).Decode(&got)
+	if got.UserID != 4 || got.Active || got.Reason != "abuse reports" || got.ChangedBy != 3 {
+		t.Fatalf("unexpected status change: %+v", got)
+	}
+}
+
+func TestSuspendUser_MissingReason(t *testing.T) {
+	repo := &mockUserRepo{getUserByIDResult: &domain.User{ID: 4, IsActive: true}}
+	tp := &mockTP{validateUserID: "3", validateRole: "admin"}
+	app := buildApp(repo, tp)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/auth/admin/users/4/suspend", bytes.NewBufferString(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer good")
+	resp, _ := app.Test(req)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", resp.StatusCode)
+	}
+}
+
+func TestGetDeletedUsers(t *testing.T) {
+	deletedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	repo := &mockUserRepo{deletedUsers: []*domain.User{{ID: 4, Email: "gone@example.com", Password: "hash", DeletedAt: &deletedAt}}}
+
+	for role, want := range map[string]int{"admin": http.StatusOK, "user": http.StatusForbidden} {
+		app := buildApp(repo, &mockTP{validateUserID: "3", validateRole: role})
+		req := httptest.NewRequest(http.MethodGet, "/v1/auth/admin/users/trash", nil)
+		req.Header.Set("Authorization", "Bearer good")
+		resp, _ := app.Test(req)
+		if resp.StatusCode != want {
+			t.Fatalf("%s: expected %d, got %d", role, want, resp.StatusCode)
+		}
+		if want != http.StatusOK {
+			continue
+		}
+		var got []domain.User
+		_ = json.NewDecoder(resp.Body).Decode(&got)
+		if len(got) != 1 || got[0].ID != 4 || got[0].DeletedAt == nil || got[0].Password != "" {
+			t.Fatalf("unexpected trash: %+v", got)
+		}
+	}
+}
+
+func TestRestoreUser(t *testing.T) {
+	deletedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	repo := &mockUserRepo{deletedUsers: []*domain.User{{ID: 4, Email: "gone@example.com", Password: "hash", DeletedAt: &deletedAt}}}
+	app := buildApp(repo, &mockTP{validateUserID: "3", validateRole: "admin"})
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/auth/admin/users/4/restore", nil)
+	req.Header.Set("Authorization", "Bearer good")
+	resp, _ := app.Test(req)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	var got domain.User
+	_ = json.NewDecoder(resp.Body).Decode(&got)
+	if got.ID != 4 || got.DeletedAt != nil || got.Password != "" {
+		t.Fatalf("unexpected user: %+v", got)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/v1/auth/admin/users/5/restore", nil)
+	req.Header.Set("Authorization", "Bearer good")
+	resp, _ = app.Test(req)
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", resp.StatusCode)
+	}
+
+	repo.restoreUserErr = pkg.ErrUserAlreadyExists
+	req = httptest.NewRequest(http.MethodPost, "/v1/auth/admin/users/4/restore", nil)
+	req.Header.Set("Authorization", "Bearer good")
+	resp, _ = app.Test(req)
+	if resp.StatusCode != http.StatusConflict {
+		t.Fatalf("expected 409 when the email is taken, got %d", resp.StatusCode)
+	}
+}
+
+func TestLoginUser_Suspended(t *testing.T) {
+	hashed, _ := pkg.HashPassword("password123")
+	repo := &mockUserRepo{getUserByEmailResult: &domain.User{ID: 3, Email: "user@example.com", Password: hashed, Username: "user123"}}
+	tp := &mockTP{token: "token-123"}
+	app := buildApp(repo, tp)
+
+	b, _ := json.Marshal(map[string]string{"email": "user@example.com", "password": "password123"})
+	req := httptest.NewRequest(http.MethodPost, "/v1/login", bytes.NewBuffer(b))
+	req.Header.Set("Content-Type", "application/json")
+	resp, _ := app.Test(req)
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d", resp.StatusCode)
+	}
+}
+
+// --- Sessions ---
+type mockSessionChecker struct {
+	active map[string]bool
+}
+
+func (m *mockSessionChecker) CheckSession(ctx context.Context, userID int, sessionID string) (bool, error) {
+	return m.active[sessionID], nil
+}
+
+func TestProtect_RejectsEndedSession(t *testing.T) {
+	repo := &mockUserRepo{getUserByIDResult: &domain.User{ID: 5, IsActive: true}}
+	app := fiber.New()
+	h := NewUserHandler(usercase.NewUserService(repo, &mockTP{}))
+	checker := &mockSessionChecker{active: map[string]bool{"laptop": true}}
+	for _, session := range []string{"laptop", "lost-phone"} {
+		auth := middleware.NewAuthMiddleware(&mockTP{validateUserID: "5", validateSession: session})
+		auth.Sessions = checker
+		h.RegisterProtected(app.Group("/"+session, auth.Protect), auth)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/laptop/me", nil)
+	req.Header.Set("Authorization", "Bearer token")
+	resp, _ := app.Test(req)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	req = httptest.NewRequest(http.MethodGet, "/lost-phone/me", nil)
+	req.Header.Set("Authorization", "Bearer token")
+	resp, _ = app.Test(req)
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", resp.StatusCode)
+	}
+}
+
+func TestListSessions_NotEnabled(t *testing.T) {
+	repo := &mockUserRepo{}
+	tp := &mockTP{validateUserID: "5"}
+	app := buildApp(repo, tp)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/auth/me/sessions", nil)
+	req.Header.Set("Authorization", "Bearer token")
+	resp, _ := app.Test(req)
+	if resp.StatusCode != http.StatusNotImplemented {
+		t.Fatalf("expected 501, got %d", resp.StatusCode)
+	}
+}
+
+type mockAuditor struct {
+	entries []*domain.ImpersonationAuditEntry
+}
+
+func (m *mockAuditor) AuditImpersonatedRequest(ctx context.Context, entry *domain.ImpersonationAuditEntry) error {
+	m.entries = append(m.entries, entry)
+	return nil
+}
+
+func TestProtect_ImpersonationIsAuditedAndRestricted(t *testing.T) {
+	hashed, _ := pkg.HashPassword("password123")
+	repo := &mockUserRepo{getUserByIDResult: &domain.User{ID: 5, Email: "user@example.com", Password: hashed, IsActive: true}}
+	tp := &mockTP{validateUserID: "5", validateActor: "1"}
+	app := fiber.New()
+	h := NewUserHandler(usercase.NewUserService(repo, tp))
+	auth := middleware.NewAuthMiddleware(tp)
+	auditor := &mockAuditor{}
+	auth.Audit = auditor
+	h.RegisterProtected(app.Group("/v1/auth", auth.Protect), auth)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/auth/me", nil)
+	req.Header.Set("Authorization", "Bearer token")
+	resp, _ := app.Test(req)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+
+	body := `{"current_password":"password123","new_password":"another-password"}`
+	req = httptest.NewRequest(http.MethodPut, "/v1/auth/me/password", bytes.NewBufferString(body))
+	req.Header.Set("Authorization", "Bearer token")
+	req.Header.Set("Content-Type", "application/json")
+	resp, _ = app.Test(req)
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected 403 for a password change while impersonating, got %d", resp.StatusCode)
+	}
+
+	if len(auditor.entries) != 2 {
+		t.Fatalf("expected every request to be audited, got %d entries", len(auditor.entries))
+	}
+	entry := auditor.entries[1]
+	if entry.ActorID != 1 || entry.UserID != 5 || entry.Method != http.MethodPut || entry.Path != "/v1/auth/me/password" {
+		t.Errorf("unexpected audit entry: %+v", entry)
+	}
+}
+
+func TestProtect_ImpersonationWithoutAuditRejected(t *testing.T) {
+	repo := &mockUserRepo{getUserByIDResult: &domain.User{ID: 5, IsActive: true}}
+	app := buildApp(repo, &mockTP{validateUserID: "5", validateActor: "1"})
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/auth/me", nil)
+	req.Header.Set("Authorization", "Bearer token")
+	resp, _ := app.Test(req)
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", resp.StatusCode)
+	}
+}
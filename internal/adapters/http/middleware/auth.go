@@ -1,24 +1,40 @@
 package middleware
 
 import (
+	"strconv"
 	"strings"
 
+	"example.com/practice/fiber/internal/domain"
 	"example.com/practice/fiber/internal/ports"
+	"example.com/practice/fiber/pkg"
 	"github.com/gofiber/fiber/v2"
 )
 
 type AuthMiddleware struct {
 	TokenProvider ports.TokenProvider
-	Repo ports.UserRepository
+	Repo          ports.UserRepository
+	Policy        domain.RolePolicy
+	Revocations   ports.TokenRevocationStore
+	// APIKeys enables the X-API-Key header as an alternative to a token.
+	APIKeys ports.APIKeyAuthenticator
+	// Sessions rejects tokens whose login session has been ended.
+	Sessions ports.SessionChecker
+	// Audit records every request made with an impersonation token. Such
+	// tokens are rejected without it.
+	Audit ports.ImpersonationAuditor
 }
 
 func NewAuthMiddleware(tokenProvider ports.TokenProvider) *AuthMiddleware {
 	return &AuthMiddleware{
 		TokenProvider: tokenProvider,
+		Policy:        domain.DefaultRolePolicy(),
 	}
 }
 
 func (m *AuthMiddleware) Protect(c *fiber.Ctx) error {
+	if apiKey := c.Get("X-API-Key"); apiKey != "" && m.APIKeys != nil {
+		return m.protectAPIKey(c, apiKey)
+	}
 	token := c.Get("Authorization")
 	if token == "" {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
@@ -26,13 +42,163 @@ func (m *AuthMiddleware) Protect(c *fiber.Ctx) error {
 		})
 	}
 	tokenTrim := strings.TrimPrefix(token, "Bearer ")
-	userId, err := m.TokenProvider.ValidateToken(tokenTrim)
-	if err != nil || userId == "" {
+	claims, err := m.TokenProvider.ValidateToken(tokenTrim)
+	// Purpose tokens (e.g. a pending MFA login) are not access tokens.
+	if err != nil || claims == nil || claims.Subject == "" || claims.Purpose != "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"message": "invalid token",
+		})
+	}
+	userId, err := strconv.Atoi(claims.Subject)
+	if err != nil {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 			"message": "invalid token",
 		})
 	}
-	c.Locals("userId", userId)
+	actorId := 0
+	if claims.Actor != "" {
+		actorId, err = strconv.Atoi(claims.Actor)
+		if err != nil || m.Audit == nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"message": "invalid token",
+			})
+		}
+	}
+	if m.Revocations != nil {
+		// Revoking the admin's tokens ends their impersonations too
+		for _, id := range []int{userId, actorId} {
+			if id == 0 {
+				continue
+			}
+			revoked, err := m.Revocations.IsRevoked(c.UserContext(), claims.ID, id, claims.IssuedAt)
+			if err != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"message": "failed to check token",
+				})
+			}
+			if revoked {
+				return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+					"message": "token revoked",
+				})
+			}
+		}
+	}
+	if m.Sessions != nil && claims.SessionID != "" {
+		active, err := m.Sessions.CheckSession(c.UserContext(), userId, claims.SessionID)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"message": "failed to check session",
+			})
+		}
+		if !active {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"message": "session ended",
+			})
+		}
+	}
+	principal := &domain.Principal{
+		UserID:         userId,
+		Role:           claims.Role,
+		ActorID:        actorId,
+		TokenID:        claims.ID,
+		TokenExpiresAt: claims.ExpiresAt,
+		SessionID:      claims.SessionID,
+	}
+	// Requests made while impersonating run only once they are audited
+	if principal.Impersonated() {
+		err := m.Audit.AuditImpersonatedRequest(c.UserContext(), &domain.ImpersonationAuditEntry{
+			ActorID: actorId,
+			UserID:  userId,
+			TokenID: claims.ID,
+			Method:  c.Method(),
+			Path:    c.Path(),
+			IP:      c.IP(),
+		})
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"message": "failed to write audit log",
+			})
+		}
+	}
+	setPrincipal(c, principal)
 	return c.Next()
 }
 
+func (m *AuthMiddleware) protectAPIKey(c *fiber.Ctx, apiKey string) error {
+	principal, err := m.APIKeys.AuthenticateAPIKey(c.UserContext(), apiKey)
+	if err == pkg.ErrInvalidAPIKey {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"message": "invalid api key",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "failed to check api key",
+		})
+	}
+	setPrincipal(c, principal)
+	return c.Next()
+}
+
+func setPrincipal(c *fiber.Ctx, principal *domain.Principal) {
+	c.Locals("userId", strconv.Itoa(principal.UserID))
+	c.Locals("role", principal.Role)
+	c.Locals("principal", principal)
+}
+
+// RequireRole allows the request through only when the caller has one of roles.
+// It must run after Protect.
+func (m *AuthMiddleware) RequireRole(roles ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		principal := CurrentPrincipal(c)
+		if principal != nil {
+			for _, role := range roles {
+				if principal.Role == role {
+					return c.Next()
+				}
+			}
+		}
+		return forbidden(c)
+	}
+}
+
+// RequirePermission allows the request through only when the caller's role is
+// granted every one of permissions by the policy and, for API keys, the key
+// has those scopes. It must run after Protect.
+func (m *AuthMiddleware) RequirePermission(permissions ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		principal := CurrentPrincipal(c)
+		if principal == nil {
+			return forbidden(c)
+		}
+		for _, permission := range permissions {
+			if !m.Policy.Allows(principal.Role, permission) || !principal.HasScope(permission) {
+				return forbidden(c)
+			}
+		}
+		return c.Next()
+	}
+}
+
+// DenyImpersonation refuses the request when an admin is impersonating the
+// caller, e.g. for password and 2FA changes. It must run after Protect.
+func (m *AuthMiddleware) DenyImpersonation(c *fiber.Ctx) error {
+	if principal := CurrentPrincipal(c); principal != nil && principal.Impersonated() {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"message": "not allowed while impersonating",
+		})
+	}
+	return c.Next()
+}
+
+// CurrentPrincipal returns the caller stored by Protect, or nil.
+func CurrentPrincipal(c *fiber.Ctx) *domain.Principal {
+	principal, _ := c.Locals("principal").(*domain.Principal)
+	return principal
+}
+
+func forbidden(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+		"message": "forbidden",
+	})
+}
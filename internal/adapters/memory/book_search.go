@@ -0,0 +1,201 @@
+package memory
+
+import (
+	"context"
+	"html"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"example.com/practice/fiber/internal/domain"
+)
+
+const (
+	// Weights of title and author matches, as ts_rank uses for A and B.
+	titleWeight  = 1.0
+	authorWeight = 0.4
+	// suggestionThreshold is the share of the query's trigrams a suggestion
+	// must contain, like pg_trgm's word_similarity_threshold.
+	suggestionThreshold = 0.6
+)
+
+// BookSearch is an in-process search index for tests and development. It
+// matches whole words without stemming, so it finds less than the Postgres
+// search does.
+type BookSearch struct {
+	mu    sync.RWMutex
+	books map[int]*domain.Book
+}
+
+func NewBookSearch(books ...*domain.Book) *BookSearch {
+	s := &BookSearch{books: map[int]*domain.Book{}}
+	for _, book := range books {
+		s.Index(book)
+	}
+	return s
+}
+
+// Index adds book to the index or replaces the indexed copy.
+func (s *BookSearch) Index(book *domain.Book) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	indexed := *book
+	s.books[book.ID] = &indexed
+}
+
+func (s *BookSearch) Remove(id int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.books, id)
+}
+
+// SearchBooks returns the books whose title or author contains every word of
+// query.Q.
+func (s *BookSearch) SearchBooks(ctx context.Context, query domain.BookSearchQuery) ([]*domain.BookSearchHit, error) {
+	terms := searchWords(query.Q)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	hits := []*domain.BookSearchHit{}
+	if len(terms) == 0 {
+		return hits, nil
+	}
+	for _, book := range s.books {
+		title, author := wordSet(book.Title), wordSet(book.Author)
+		var rank float64
+		matched := true
+		for _, term := range terms {
+			if !title[term] && !author[term] {
+				matched = false
+				break
+			}
+			if title[term] {
+				rank += titleWeight
+			}
+			if author[term] {
+				rank += authorWeight
+			}
+		}
+		if !matched {
+			continue
+		}
+		found := *book
+		hits = append(hits, &domain.BookSearchHit{
+			Book: &found,
+			Rank: rank,
+			Highlights: domain.BookHighlights{
+				Title:  highlight(book.Title, terms),
+				Author: highlight(book.Author, terms),
+			},
+		})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Rank != hits[j].Rank {
+			return hits[i].Rank > hits[j].Rank
+		}
+		return hits[i].Book.ID < hits[j].Book.ID
+	})
+	if len(hits) > query.Limit {
+		hits = hits[:query.Limit]
+	}
+	return hits, nil
+}
+
+// SuggestBooks returns titles and authors containing most of the trigrams of
+// q, best first.
+func (s *BookSearch) SuggestBooks(ctx context.Context, q string, limit int) ([]string, error) {
+	want := trigrams(q)
+	suggestions := []string{}
+	if len(want) == 0 {
+		return suggestions, nil
+	}
+	s.mu.RLock()
+	scores := map[string]float64{}
+	for _, book := range s.books {
+		for _, term := range []string{book.Title, book.Author} {
+			have := trigrams(term)
+			common := 0
+			for trigram := range want {
+				if have[trigram] {
+					common++
+				}
+			}
+			score := float64(common) / float64(len(want))
+			if score >= suggestionThreshold && score > scores[term] {
+				scores[term] = score
+			}
+		}
+	}
+	s.mu.RUnlock()
+	for term := range scores {
+		suggestions = append(suggestions, term)
+	}
+	sort.Slice(suggestions, func(i, j int) bool {
+		a, b := suggestions[i], suggestions[j]
+		if scores[a] != scores[b] {
+			return scores[a] > scores[b]
+		}
+		return a < b
+	})
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions, nil
+}
+
+// searchWords splits s into lowercase words of letters and digits.
+func searchWords(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func wordSet(s string) map[string]bool {
+	set := map[string]bool{}
+	for _, word := range searchWords(s) {
+		set[word] = true
+	}
+	return set
+}
+
+// highlight HTML-escapes text and marks the words in terms.
+func highlight(text string, terms []string) string {
+	match := map[string]bool{}
+	for _, term := range terms {
+		match[term] = true
+	}
+	var b strings.Builder
+	runes := []rune(text)
+	for i := 0; i < len(runes); {
+		j := i
+		for j < len(runes) && (unicode.IsLetter(runes[j]) || unicode.IsDigit(runes[j])) {
+			j++
+		}
+		if j == i {
+			b.WriteString(html.EscapeString(string(runes[i])))
+			i++
+			continue
+		}
+		word := string(runes[i:j])
+		if match[strings.ToLower(word)] {
+			b.WriteString(domain.HighlightStart + html.EscapeString(word) + domain.HighlightStop)
+		} else {
+			b.WriteString(html.EscapeString(word))
+		}
+		i = j
+	}
+	return b.String()
+}
+
+// trigrams returns the trigrams of the words of s, each padded the way
+// pg_trgm pads them.
+func trigrams(s string) map[string]bool {
+	set := map[string]bool{}
+	for _, word := range searchWords(s) {
+		padded := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(padded); i++ {
+			set[string(padded[i:i+3])] = true
+		}
+	}
+	return set
+}
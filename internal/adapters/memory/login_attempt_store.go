@@ -0,0 +1,82 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"example.com/practice/fiber/internal/domain"
+)
+
+// LoginAttemptStore keeps failed login counters in process. Like
+// TokenRevocationStore it only suits a single API instance.
+type LoginAttemptStore struct {
+	mu       sync.Mutex
+	attempts map[string]domain.LoginAttempt
+}
+
+func NewLoginAttemptStore() *LoginAttemptStore {
+	return &LoginAttemptStore{attempts: map[string]domain.LoginAttempt{}}
+}
+
+func (s *LoginAttemptStore) GetLoginAttempt(ctx context.Context, key string) (*domain.LoginAttempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	attempt, ok := s.attempts[key]
+	if !ok {
+		return nil, nil
+	}
+	return &attempt, nil
+}
+
+func (s *LoginAttemptStore) RecordLoginFailure(ctx context.Context, key string, now, resetBefore time.Time) (*domain.LoginAttempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	attempt, ok := s.attempts[key]
+	if !ok || lastActivity(attempt).Before(resetBefore) {
+		attempt = domain.LoginAttempt{Key: key}
+	}
+	attempt.Failures++
+	attempt.LastFailureAt = now
+	s.attempts[key] = attempt
+	return &attempt, nil
+}
+
+func (s *LoginAttemptStore) LockLogin(ctx context.Context, key string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	attempt, ok := s.attempts[key]
+	if !ok {
+		attempt = domain.LoginAttempt{Key: key}
+	}
+	attempt.LockedUntil = &until
+	s.attempts[key] = attempt
+	return nil
+}
+
+func (s *LoginAttemptStore) ResetLoginAttempts(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.attempts, key)
+	return nil
+}
+
+func (s *LoginAttemptStore) DeleteStaleLoginAttempts(ctx context.Context, before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var deleted int64
+	for key, attempt := range s.attempts {
+		if lastActivity(attempt).Before(before) {
+			delete(s.attempts, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func lastActivity(attempt domain.LoginAttempt) time.Time {
+	if attempt.LockedUntil != nil && attempt.LockedUntil.After(attempt.LastFailureAt) {
+		return *attempt.LockedUntil
+	}
+	return attempt.LastFailureAt
+}
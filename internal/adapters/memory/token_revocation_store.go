@@ -0,0 +1,72 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type userRevocation struct {
+	before    time.Time
+	expiresAt time.Time
+}
+
+// TokenRevocationStore is an in-process revocation list. It is suitable for a
+// single API instance; use the Postgres store when running several.
+type TokenRevocationStore struct {
+	mu     sync.RWMutex
+	tokens map[string]time.Time
+	users  map[int]userRevocation
+}
+
+func NewTokenRevocationStore() *TokenRevocationStore {
+	return &TokenRevocationStore{
+		tokens: map[string]time.Time{},
+		users:  map[int]userRevocation{},
+	}
+}
+
+func (s *TokenRevocationStore) RevokeToken(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens[tokenID] = expiresAt
+	return nil
+}
+
+func (s *TokenRevocationStore) RevokeUserTokens(ctx context.Context, userID int, before time.Time, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users[userID] = userRevocation{before: before, expiresAt: expiresAt}
+	return nil
+}
+
+func (s *TokenRevocationStore) IsRevoked(ctx context.Context, tokenID string, userID int, issuedAt time.Time) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if _, ok := s.tokens[tokenID]; ok {
+		return true, nil
+	}
+	if revocation, ok := s.users[userID]; ok && !issuedAt.After(revocation.before) {
+		return true, nil
+	}
+	return false, nil
+}
+
+func (s *TokenRevocationStore) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var deleted int64
+	for tokenID, expiresAt := range s.tokens {
+		if now.After(expiresAt) {
+			delete(s.tokens, tokenID)
+			deleted++
+		}
+	}
+	for userID, revocation := range s.users {
+		if now.After(revocation.expiresAt) {
+			delete(s.users, userID)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
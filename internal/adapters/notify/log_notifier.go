@@ -0,0 +1,36 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"example.com/practice/fiber/internal/domain"
+)
+
+// LogNotifier writes notifications as JSON lines to w instead of sending
+// them, so flows such as password reset work without an SMTP server.
+type LogNotifier struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewLogNotifier(w io.Writer) *LogNotifier {
+	return &LogNotifier{w: w}
+}
+
+func (n *LogNotifier) Notify(ctx context.Context, notification domain.Notification) error {
+	line, err := json.Marshal(struct {
+		Time time.Time `json:"time"`
+		domain.Notification
+	}{Time: time.Now(), Notification: notification})
+	if err != nil {
+		return err
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	_, err = n.w.Write(append(line, '\n'))
+	return err
+}
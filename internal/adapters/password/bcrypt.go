@@ -0,0 +1,33 @@
+package password
+
+import "golang.org/x/crypto/bcrypt"
+
+// BcryptHasher hashes with bcrypt at Cost.
+type BcryptHasher struct {
+	Cost int
+}
+
+// NewBcryptHasher uses bcrypt.DefaultCost when cost is zero.
+func NewBcryptHasher(cost int) *BcryptHasher {
+	if cost == 0 {
+		cost = bcrypt.DefaultCost
+	}
+	return &BcryptHasher{Cost: cost}
+}
+
+func (h *BcryptHasher) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), h.Cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+func (h *BcryptHasher) Verify(hash, password string) (bool, error) {
+	return verify(hash, password)
+}
+
+func (h *BcryptHasher) NeedsRehash(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost != h.Cost
+}
@@ -0,0 +1,170 @@
+package password
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// rangePrefixLength is the length of the hash prefix that names a file
+	// of the k-anonymity range layout; its lines hold the rest of the hash.
+	rangePrefixLength = 5
+	// breachScanSize is the range below which the search switches from
+	// bisecting to reading lines in order.
+	breachScanSize = 4096
+)
+
+// BreachedPasswordFile checks passwords against a local copy of the Pwned
+// Passwords list, so passwords never leave the server. Two layouts are
+// supported, both as written by the haveibeenpwned downloader:
+//
+//   - a file with one "SHA1:COUNT" line per password, sorted by hash;
+//   - a directory of k-anonymity ranges: one file per 5 character hash
+//     prefix, named "ABCDE" or "ABCDE.txt", with "SUFFIX:COUNT" lines as
+//     returned by the range API.
+//
+// Lookups bisect the sorted file to the range of the hash, so the list is
+// never loaded into memory.
+type BreachedPasswordFile struct {
+	path   string
+	ranges bool
+}
+
+// NewBreachedPasswordFile checks that path can be opened. A directory is
+// read as a set of hash-prefix range files.
+func NewBreachedPasswordFile(path string) (*BreachedPasswordFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("breached passwords: %w", err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("breached passwords: %w", err)
+	}
+	return &BreachedPasswordFile{path: path, ranges: info.IsDir()}, nil
+}
+
+func (b *BreachedPasswordFile) IsBreached(ctx context.Context, password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	if !b.ranges {
+		return searchFile(ctx, b.path, hash)
+	}
+	prefix, suffix := hash[:rangePrefixLength], hash[rangePrefixLength:]
+	for _, name := range []string{prefix + ".txt", prefix} {
+		found, err := searchFile(ctx, filepath.Join(b.path, name), suffix)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		return found, err
+	}
+	return false, nil
+}
+
+// searchFile reports whether the file at path, sorted by hash, has a line
+// for target.
+func searchFile(ctx context.Context, path, target string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return false, err
+	}
+	keyLength := len(target)
+	// Invariant: lines starting before lo sort before target, and the first
+	// line starting at or after hi does not.
+	lo, hi := int64(0), info.Size()
+	for hi-lo > breachScanSize {
+		if err := ctx.Err(); err != nil {
+			return false, err
+		}
+		mid := lo + (hi-lo)/2
+		hash, err := hashAt(f, mid, info.Size(), keyLength)
+		if err != nil {
+			return false, err
+		}
+		if hash == "" || hash >= target {
+			hi = mid
+		} else {
+			lo = mid
+		}
+	}
+	r := bufio.NewReader(io.NewSectionReader(f, lo, info.Size()-lo))
+	if lo > 0 {
+		if err := skipPartialLine(f, lo, r); err != nil {
+			return false, err
+		}
+	}
+	for {
+		line, err := r.ReadString('\n')
+		if hash := lineHash(line, keyLength); hash != "" {
+			if hash == target {
+				return true, nil
+			}
+			if hash > target {
+				return false, nil
+			}
+		}
+		if err == io.EOF {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+}
+
+// hashAt returns the hash of the first line that starts at or after offset,
+// or "" at the end of the file.
+func hashAt(f *os.File, offset, size int64, keyLength int) (string, error) {
+	r := bufio.NewReader(io.NewSectionReader(f, offset, size-offset))
+	if offset > 0 {
+		if err := skipPartialLine(f, offset, r); err != nil {
+			return "", err
+		}
+	}
+	line, err := r.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	return lineHash(line, keyLength), nil
+}
+
+// skipPartialLine advances r, which reads from offset, to the next line start
+// unless offset already is one.
+func skipPartialLine(f *os.File, offset int64, r *bufio.Reader) error {
+	var prev [1]byte
+	if _, err := f.ReadAt(prev[:], offset-1); err != nil {
+		return err
+	}
+	if prev[0] == '\n' {
+		return nil
+	}
+	_, err := r.ReadString('\n')
+	if err == io.EOF {
+		return nil
+	}
+	return err
+}
+
+// lineHash returns the hash, or hash suffix, of keyLength characters that
+// line starts with.
+func lineHash(line string, keyLength int) string {
+	if len(line) < keyLength {
+		return ""
+	}
+	return strings.ToUpper(line[:keyLength])
+}
@@ -0,0 +1,114 @@
+package password
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func sha1Hex(password string) string {
+	sum := sha1.Sum([]byte(password))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+// breachedList returns n breached passwords and their hashes sorted by hash.
+func breachedList(n int) ([]string, map[string]string) {
+	byHash := make(map[string]string, n)
+	hashes := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		password := fmt.Sprintf("password-%d", i)
+		hash := sha1Hex(password)
+		byHash[hash] = password
+		hashes = append(hashes, hash)
+	}
+	sort.Strings(hashes)
+	return hashes, byHash
+}
+
+func TestBreachedPasswordFile(t *testing.T) {
+	hashes, byHash := breachedList(500)
+	var b strings.Builder
+	for i, hash := range hashes {
+		// Mix line endings and letter case like files from different tools.
+		if i%2 == 0 {
+			hash = strings.ToLower(hash)
+		}
+		newline := "\n"
+		if i%3 == 0 {
+			newline = "\r\n"
+		}
+		fmt.Fprintf(&b, "%s:%d%s", hash, i+1, newline)
+	}
+	path := filepath.Join(t.TempDir(), "pwned.txt")
+	if err := os.WriteFile(path, []byte(b.String()), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if b.Len() <= 4*breachScanSize {
+		t.Fatalf("test file of %d bytes is too small to be bisected", b.Len())
+	}
+	checker, err := NewBreachedPasswordFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]bool{
+		byHash[hashes[0]]:             true,
+		byHash[hashes[len(hashes)-1]]: true,
+		byHash[hashes[len(hashes)/2]]: true,
+		byHash[hashes[3]]:             true, // CRLF line
+		byHash[hashes[301]]:           true,
+		"correct horse battery":       false,
+		"password-500":                false,
+	}
+	for password, want := range cases {
+		got, err := checker.IsBreached(context.Background(), password)
+		if err != nil || got != want {
+			t.Errorf("IsBreached(%q) = %v, %v; want %v", password, got, err, want)
+		}
+	}
+}
+
+func TestBreachedPasswordFile_Ranges(t *testing.T) {
+	hashes, byHash := breachedList(500)
+	ranges := map[string]*strings.Builder{}
+	for i, hash := range hashes {
+		prefix := hash[:rangePrefixLength]
+		if ranges[prefix] == nil {
+			ranges[prefix] = &strings.Builder{}
+		}
+		fmt.Fprintf(ranges[prefix], "%s:%d\r\n", hash[rangePrefixLength:], i+1)
+	}
+	dir := t.TempDir()
+	for prefix, b := range ranges {
+		name := prefix + ".txt"
+		if prefix == hashes[0][:rangePrefixLength] {
+			name = prefix
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(b.String()), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	checker, err := NewBreachedPasswordFile(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]bool{
+		byHash[hashes[0]]:             true, // file without extension
+		byHash[hashes[len(hashes)-1]]: true,
+		byHash[hashes[len(hashes)/2]]: true,
+		"correct horse battery":       false,
+	}
+	for password, want := range cases {
+		got, err := checker.IsBreached(context.Background(), password)
+		if err != nil || got != want {
+			t.Errorf("IsBreached(%q) = %v, %v; want %v", password, got, err, want)
+		}
+	}
+}
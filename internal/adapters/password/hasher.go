@@ -0,0 +1,31 @@
+package password
+
+import (
+	"errors"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var errUnknownHash = errors.New("password: unknown hash format")
+
+// verify checks password against a bcrypt or Argon2id hash. Both hashers use
+// it, so stored hashes keep working while users move to the configured
+// algorithm.
+func verify(hash, password string) (bool, error) {
+	switch {
+	case strings.HasPrefix(hash, argon2idPrefix):
+		params, salt, key, err := decodeArgon2id(hash)
+		if err != nil {
+			return false, err
+		}
+		return argon2idMatches(params, salt, key, password), nil
+	case strings.HasPrefix(hash, "$2"):
+		err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+	return false, errUnknownHash
+}
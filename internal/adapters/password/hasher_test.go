@@ -0,0 +1,87 @@
+package password
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// fastArgon2id keeps the tests quick; the encoding does not depend on cost.
+var fastArgon2id = Argon2idParams{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func TestHashers(t *testing.T) {
+	hashers := map[string]interface {
+		Hash(string) (string, error)
+		Verify(string, string) (bool, error)
+		NeedsRehash(string) bool
+	}{
+		"bcrypt":   NewBcryptHasher(bcrypt.MinCost),
+		"argon2id": NewArgon2idHasher(fastArgon2id),
+	}
+	hashes := map[string]string{}
+	for name, h := range hashers {
+		hash, err := h.Hash("correct horse")
+		if err != nil {
+			t.Fatalf("%s: Hash() error = %v", name, err)
+		}
+		hashes[name] = hash
+		if ok, err := h.Verify(hash, "correct horse"); !ok || err != nil {
+			t.Errorf("%s: Verify() of the right password = %v, %v", name, ok, err)
+		}
+		if ok, err := h.Verify(hash, "wrong horse"); ok || err != nil {
+			t.Errorf("%s: Verify() of a wrong password = %v, %v", name, ok, err)
+		}
+		if h.NeedsRehash(hash) {
+			t.Errorf("%s: NeedsRehash() of a fresh hash = true", name)
+		}
+		if other, _ := h.Hash("correct horse"); other == hash {
+			t.Errorf("%s: Hash() should use a new salt every time", name)
+		}
+	}
+	if !strings.HasPrefix(hashes["argon2id"], "$argon2id$v=19$m=64,t=1,p=1$") {
+		t.Errorf("argon2id hash is not in PHC format: %s", hashes["argon2id"])
+	}
+
+	// Either hasher verifies the other's hashes but wants them rehashed
+	for name, h := range hashers {
+		for other, hash := range hashes {
+			if ok, err := h.Verify(hash, "correct horse"); !ok || err != nil {
+				t.Errorf("%s: Verify() of a %s hash = %v, %v", name, other, ok, err)
+			}
+			if want := name != other; h.NeedsRehash(hash) != want {
+				t.Errorf("%s: NeedsRehash() of a %s hash = %v, want %v", name, other, !want, want)
+			}
+		}
+	}
+
+	// Changed costs call for a rehash
+	if NewBcryptHasher(bcrypt.MinCost+1).NeedsRehash(hashes["bcrypt"]) != true {
+		t.Errorf("bcrypt: NeedsRehash() should be true for another cost")
+	}
+	stronger := fastArgon2id
+	stronger.Iterations++
+	if NewArgon2idHasher(stronger).NeedsRehash(hashes["argon2id"]) != true {
+		t.Errorf("argon2id: NeedsRehash() should be true for other parameters")
+	}
+}
+
+func TestVerify_InvalidHash(t *testing.T) {
+	const salt, key = "c2FsdHNhbHRzYWx0c2FsdA", "a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2U"
+	for _, hash := range []string{
+		"",
+		"plaintext",
+		"$argon2i$v=19$m=64,t=1,p=1$" + salt + "$" + key,
+		"$argon2id$v=16$m=64,t=1,p=1$" + salt + "$" + key,
+		"$argon2id$v=19$m=64,t=0,p=1$" + salt + "$" + key,
+		"$argon2id$v=19$m=64,t=1,p=0$" + salt + "$" + key,
+		"$argon2id$v=19$m=64,t=1,p=1$$" + key,
+		"$argon2id$v=19$m=64,t=1,p=1$" + salt + "$",
+		"$argon2id$v=19$m=64,t=1,p=1$" + salt,
+		"$2a$04$short",
+	} {
+		if ok, err := verify(hash, "password"); ok || err == nil {
+			t.Errorf("verify(%q) = %v, %v; want an error", hash, ok, err)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	domain "example.com/practice/fiber/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type apiKeyRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewAPIKeyRepo(db *pgxpool.Pool) *apiKeyRepo {
+	return &apiKeyRepo{db: db}
+}
+
+func (r *apiKeyRepo) CreateAPIKey(ctx context.Context, key *domain.APIKey) error {
+	return r.db.QueryRow(ctx, "INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes, expires_at) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at", key.UserID, key.Name, key.Prefix, key.KeyHash, key.Scopes, key.ExpiresAt).Scan(&key.ID, &key.CreatedAt)
+}
+
+func (r *apiKeyRepo) GetAPIKeyByPrefix(ctx context.Context, prefix string) (*domain.APIKey, error) {
+	var key domain.APIKey
+	err := r.db.QueryRow(ctx, "SELECT id, user_id, name, prefix, key_hash, scopes, expires_at, last_used_at, revoked_at, created_at FROM api_keys WHERE prefix = $1", prefix).Scan(&key.ID, &key.UserID, &key.Name, &key.Prefix, &key.KeyHash, &key.Scopes, &key.ExpiresAt, &key.LastUsedAt, &key.RevokedAt, &key.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *apiKeyRepo) ListUserAPIKeys(ctx context.Context, userID int) ([]*domain.APIKey, error) {
+	keys := []*domain.APIKey{}
+	rows, err := r.db.Query(ctx, "SELECT id, user_id, name, prefix, scopes, expires_at, last_used_at, revoked_at, created_at FROM api_keys WHERE user_id = $1 ORDER BY created_at DESC", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var key domain.APIKey
+		if err := rows.Scan(&key.ID, &key.UserID, &key.Name, &key.Prefix, &key.Scopes, &key.ExpiresAt, &key.LastUsedAt, &key.RevokedAt, &key.CreatedAt); err != nil {
+			return nil, err
+		}
+		keys = append(keys, &key)
+	}
+	return keys, rows.Err()
+}
+
+func (r *apiKeyRepo) RevokeAPIKey(ctx context.Context, userID int, id int64) (bool, error) {
+	tag, err := r.db.Exec(ctx, "UPDATE api_keys SET revoked_at = NOW() WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL", id, userID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func (r *apiKeyRepo) TouchAPIKey(ctx context.Context, id int64, usedAt time.Time) error {
+	_, err := r.db.Exec(ctx, "UPDATE api_keys SET last_used_at = $2 WHERE id = $1", id, usedAt)
+	return err
+}
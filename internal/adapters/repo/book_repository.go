@@ -2,9 +2,15 @@ package repo
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	domain "example.com/practice/fiber/internal/domain"
+	"example.com/practice/fiber/pkg"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -17,32 +23,97 @@ func NewBookRepo(db *pgxpool.Pool) *BookRepo {
 	return &BookRepo{db: db}
 }
 
-func (r *BookRepo) CreateBook(ctx context.Context, book *domain.Book) error {
-	_, err := r.db.Exec(ctx, "INSERT INTO books (title, author, price, stock) VALUES ($1, $2, $3, $4)", book.Title, book.Author, book.Price, book.Stock)
-	return err
+// bookColumns are the columns bookFields scans into, in order. The currency
+// comes before the price, which needs it.
+const bookColumns = "id, title, author, currency, price, stock, published, COALESCE(isbn13, ''), publisher, language, page_count, created_at, updated_at, version, deleted_at"
+
+func bookFields(book *domain.Book) []interface{} {
+	return []interface{}{&book.ID, &book.Title, &book.Author, &book.Price.Currency, &book.Price, &book.Stock,
+		&book.Published, &book.ISBN13, &book.Publisher, &book.Language, &book.PageCount, &book.CreatedAt, &book.UpdatedAt, &book.Version, &book.DeletedAt}
+}
+
+func scanBook(row pgx.Row, book *domain.Book) error {
+	return row.Scan(bookFields(book)...)
+}
+
+// uniqueViolation reports whether err is a Postgres unique_violation.
+func uniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+func (r *BookRepo) CreateBook(ctx context.Context, book *domain.Book) (*domain.Book, error) {
+	var stored domain.Book
+	err := scanBook(r.db.QueryRow(ctx, "INSERT INTO books (title, author, currency, price, stock, published, isbn13, publisher, language, page_count) VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8, $9, $10) RETURNING "+bookColumns,
+		book.Title, book.Author, book.Price.Currency, book.Price, book.Stock, book.Published, book.ISBN13, book.Publisher, book.Language, book.PageCount), &stored)
+	if uniqueViolation(err) {
+		return nil, pkg.ErrBookAlreadyExists
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &stored, nil
 }
 
 func (r *BookRepo) GetBookByID(ctx context.Context, id int) (*domain.Book, error) {
 	var book domain.Book
-	err := r.db.QueryRow(ctx, "SELECT id, title, author, price, stock, created_at, updated_at FROM books WHERE id = $1", id).Scan(&book.ID, &book.Title, &book.Author, &book.Price, &book.Stock, &book.CreatedAt, &book.UpdatedAt)
+	err := scanBook(r.db.QueryRow(ctx, "SELECT "+bookColumns+" FROM books WHERE id = $1 AND deleted_at IS NULL", id), &book)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
 	if err != nil {
 		return nil, err
 	}
 	return &book, nil
 }
 
-func (r *BookRepo) GetAllBooks(ctx context.Context) ([]*domain.Book, error) {
-	rows, err := r.db.Query(ctx, "SELECT id, title, author, price, stock, created_at, updated_at FROM books")
+func (r *BookRepo) GetBookByISBN(ctx context.Context, isbn13 string) (*domain.Book, error) {
+	var book domain.Book
+	err := scanBook(r.db.QueryRow(ctx, "SELECT "+bookColumns+" FROM books WHERE isbn13 = $1 AND deleted_at IS NULL", isbn13), &book)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &book, nil
+}
+
+// bookSortColumns maps the sort fields of domain.BookQuery to SQL. NULL
+// prices sort as zero so that keyset comparisons stay total.
+var bookSortColumns = map[string]struct{ expr, cast string }{
+	domain.BookSortCreatedAt: {"created_at", "timestamp"},
+	domain.BookSortPrice:     {"COALESCE(price, 0)", "numeric"},
+	domain.BookSortTitle:     {"title", "text"},
+}
+
+func (r *BookRepo) GetAllBooks(ctx context.Context, query domain.BookQuery) ([]*domain.Book, error) {
+	sort, ok := bookSortColumns[query.Sort]
+	if !ok {
+		return nil, fmt.Errorf("unsupported sort field %q", query.Sort)
+	}
+	where, args := bookFilterSQL(query.Filter)
+	op, order := ">", "ASC"
+	if query.Desc {
+		op, order = "<", "DESC"
+	}
+	if query.After != nil {
+		args = append(args, query.After.Value, query.After.ID)
+		where = append(where, fmt.Sprintf("(%s, id) %s ($%d::%s, $%d)", sort.expr, op, len(args)-1, sort.cast, len(args)))
+	}
+	args = append(args, query.Limit)
+	sql := "SELECT " + bookColumns + " FROM books" + whereSQL(where) +
+		fmt.Sprintf(" ORDER BY %s %s, id %s LIMIT $%d", sort.expr, order, order, len(args))
+	rows, err := r.db.Query(ctx, sql, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var books []*domain.Book		
+	books := []*domain.Book{}
 	for rows.Next() {
 		var book domain.Book
-		err := rows.Scan(&book.ID, &book.Title, &book.Author, &book.Price, &book.Stock, &book.CreatedAt, &book.UpdatedAt)
-		if err != nil {
+		if err := scanBook(rows, &book); err != nil {
 			return nil, err
 		}
 		books = append(books, &book)
@@ -55,12 +126,185 @@ func (r *BookRepo) GetAllBooks(ctx context.Context) ([]*domain.Book, error) {
 	return books, nil
 }
 
-func (r *BookRepo) UpdateBook(ctx context.Context, book *domain.Book) error {
-	_, err := r.db.Exec(ctx, "UPDATE books SET title = $1, author = $2, price = $3, stock = $4, updated_at = $5 WHERE id = $6", book.Title, book.Author, book.Price, book.Stock, time.Now(), book.ID)
-	return err
+func (r *BookRepo) CountBooks(ctx context.Context, filter domain.BookFilter) (int, error) {
+	where, args := bookFilterSQL(filter)
+	var total int
+	err := r.db.QueryRow(ctx, "SELECT COUNT(*) FROM books"+whereSQL(where), args...).Scan(&total)
+	return total, err
+}
+
+// bookFilterSQL returns the WHERE conditions for filter and their arguments,
+// numbered from $1. Books in the trash never match.
+func bookFilterSQL(filter domain.BookFilter) ([]string, []interface{}) {
+	where := []string{"deleted_at IS NULL"}
+	var args []interface{}
+	add := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		where = append(where, fmt.Sprintf(cond, len(args)))
+	}
+	if filter.Author != "" {
+		add("LOWER(author) = LOWER($%d)", filter.Author)
+	}
+	if filter.Title != "" {
+		add(`title ILIKE '%%' || $%d || '%%'`, likeEscaper.Replace(filter.Title))
+	}
+	if filter.Currency != "" {
+		add("currency = $%d", filter.Currency)
+	}
+	if filter.MinPrice != nil {
+		add("price >= $%d", *filter.MinPrice)
+	}
+	if filter.MaxPrice != nil {
+		add("price <= $%d", *filter.MaxPrice)
+	}
+	if filter.InStock != nil {
+		if *filter.InStock {
+			where = append(where, "stock > 0")
+		} else {
+			where = append(where, "COALESCE(stock, 0) <= 0")
+		}
+	}
+	return where, args
+}
+
+// likeEscaper escapes LIKE wildcards so that user input matches literally.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+func whereSQL(conditions []string) string {
+	if len(conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(conditions, " AND ")
+}
+
+func (r *BookRepo) UpdateBook(ctx context.Context, book *domain.Book) (*domain.Book, error) {
+	var stored domain.Book
+	err := scanBook(r.db.QueryRow(ctx, "UPDATE books SET title = $1, author = $2, currency = $3, price = $4, stock = $5, published = $6, isbn13 = NULLIF($7, ''), publisher = $8, language = $9, page_count = $10, updated_at = $11, version = version + 1 WHERE id = $12 AND ($13 = 0 OR version = $13) AND deleted_at IS NULL RETURNING "+bookColumns,
+		book.Title, book.Author, book.Price.Currency, book.Price, book.Stock, book.Published, book.ISBN13, book.Publisher, book.Language, book.PageCount, time.Now(), book.ID, book.Version), &stored)
+	if uniqueViolation(err) {
+		return nil, pkg.ErrBookAlreadyExists
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, r.missedBook(ctx, book.ID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &stored, nil
+}
+
+func (r *BookRepo) UpdateBookFields(ctx context.Context, book *domain.Book, fields []string) (*domain.Book, error) {
+	var set []string
+	var args []interface{}
+	add := func(assignment string, arg interface{}) {
+		args = append(args, arg)
+		set = append(set, fmt.Sprintf(assignment, len(args)))
+	}
+	for _, field := range fields {
+		switch field {
+		case "title":
+			add("title = $%d", book.Title)
+		case "author":
+			add("author = $%d", book.Author)
+		case "price":
+			add("currency = $%d", book.Price.Currency)
+			add("price = $%d", book.Price)
+		case "stock":
+			add("stock = $%d", book.Stock)
+		case "published":
+			add("published = $%d", book.Published)
+		case "isbn13":
+			add("isbn13 = NULLIF($%d, '')", book.ISBN13)
+		case "publisher":
+			add("publisher = $%d", book.Publisher)
+		case "language":
+			add("language = $%d", book.Language)
+		case "page_count":
+			add("page_count = $%d", book.PageCount)
+		default:
+			return nil, fmt.Errorf("unsupported book field %q", field)
+		}
+	}
+	add("updated_at = $%d", time.Now())
+	args = append(args, book.ID, book.Version)
+	sql := "UPDATE books SET " + strings.Join(set, ", ") + ", version = version + 1" +
+		fmt.Sprintf(" WHERE id = $%d AND ($%d = 0 OR version = $%d) AND deleted_at IS NULL RETURNING ", len(args)-1, len(args), len(args)) + bookColumns
+
+	var stored domain.Book
+	err := scanBook(r.db.QueryRow(ctx, sql, args...), &stored)
+	if uniqueViolation(err) {
+		return nil, pkg.ErrBookAlreadyExists
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, r.missedBook(ctx, book.ID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &stored, nil
+}
+
+func (r *BookRepo) DeleteBook(ctx context.Context, id, version int) error {
+	tag, err := r.db.Exec(ctx, "UPDATE books SET deleted_at = NOW(), version = version + 1 WHERE id = $1 AND ($2 = 0 OR version = $2) AND deleted_at IS NULL", id, version)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return r.missedBook(ctx, id)
+	}
+	return nil
+}
+
+// missedBook explains why a conditional write on book id touched no rows:
+// either the book is gone or its version moved on.
+func (r *BookRepo) missedBook(ctx context.Context, id int) error {
+	var exists bool
+	if err := r.db.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM books WHERE id = $1 AND deleted_at IS NULL)", id).Scan(&exists); err != nil {
+		return err
+	}
+	if exists {
+		return pkg.ErrConflict
+	}
+	return pkg.ErrBookNotFound
+}
+
+func (r *BookRepo) GetDeletedBooks(ctx context.Context) ([]*domain.Book, error) {
+	rows, err := r.db.Query(ctx, "SELECT "+bookColumns+" FROM books WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC, id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	books := []*domain.Book{}
+	for rows.Next() {
+		var book domain.Book
+		if err := scanBook(rows, &book); err != nil {
+			return nil, err
+		}
+		books = append(books, &book)
+	}
+	return books, rows.Err()
+}
+
+func (r *BookRepo) RestoreBook(ctx context.Context, id int) (*domain.Book, error) {
+	var stored domain.Book
+	err := scanBook(r.db.QueryRow(ctx, "UPDATE books SET deleted_at = NULL, updated_at = NOW(), version = version + 1 WHERE id = $1 AND deleted_at IS NOT NULL RETURNING "+bookColumns, id), &stored)
+	if uniqueViolation(err) {
+		return nil, pkg.ErrBookAlreadyExists
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, pkg.ErrBookNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &stored, nil
 }
 
-func (r *BookRepo) DeleteBook(ctx context.Context, id int) error {
-	_, err := r.db.Exec(ctx, "DELETE FROM books WHERE id = $1", id)
-	return err
+func (r *BookRepo) PurgeDeletedBooks(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := r.db.Exec(ctx, "DELETE FROM books WHERE deleted_at < $1", before)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
 }
\ No newline at end of file
@@ -0,0 +1,82 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+
+	"example.com/practice/fiber/internal/domain"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// bookSearchRepo searches the search_vector column of books, a generated
+// tsvector over title (weight A) and author (weight B). Suggestions use
+// pg_trgm word similarity.
+type bookSearchRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewBookSearchRepo(db *pgxpool.Pool) *bookSearchRepo {
+	return &bookSearchRepo{db: db}
+}
+
+// headlineOptions highlights every match and returns the whole field; titles
+// and authors are short.
+var headlineOptions = fmt.Sprintf("StartSel=%s, StopSel=%s, HighlightAll=true", domain.HighlightStart, domain.HighlightStop)
+
+// escapeHTMLSQL escapes column before ts_headline adds its markers. The
+// default parser reads the entities as single tokens, so matching is not
+// affected.
+func escapeHTMLSQL(column string) string {
+	return fmt.Sprintf("replace(replace(replace(%s, '&', '&amp;'), '<', '&lt;'), '>', '&gt;')", column)
+}
+
+func (r *bookSearchRepo) SearchBooks(ctx context.Context, query domain.BookSearchQuery) ([]*domain.BookSearchHit, error) {
+	sql := fmt.Sprintf(`SELECT %s,
+		ts_rank_cd(search_vector, q) AS rank,
+		ts_headline('english', %s, q, $3),
+		ts_headline('english', %s, q, $3)
+		FROM books, websearch_to_tsquery('english', $1) q
+		WHERE search_vector @@ q AND deleted_at IS NULL
+		ORDER BY rank DESC, id
+		LIMIT $2`, bookColumns, escapeHTMLSQL("title"), escapeHTMLSQL("author"))
+	rows, err := r.db.Query(ctx, sql, query.Q, query.Limit, headlineOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	hits := []*domain.BookSearchHit{}
+	for rows.Next() {
+		var book domain.Book
+		hit := domain.BookSearchHit{Book: &book}
+		fields := append(bookFields(&book), &hit.Rank, &hit.Highlights.Title, &hit.Highlights.Author)
+		if err := rows.Scan(fields...); err != nil {
+			return nil, err
+		}
+		hits = append(hits, &hit)
+	}
+	return hits, rows.Err()
+}
+
+func (r *bookSearchRepo) SuggestBooks(ctx context.Context, q string, limit int) ([]string, error) {
+	rows, err := r.db.Query(ctx, `SELECT term FROM (
+		SELECT title AS term, word_similarity($1, title) AS score FROM books WHERE $1 <% title AND deleted_at IS NULL
+		UNION ALL
+		SELECT author, word_similarity($1, author) FROM books WHERE $1 <% author AND deleted_at IS NULL
+	) candidates
+	GROUP BY term
+	ORDER BY MAX(score) DESC, term
+	LIMIT $2`, q, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	suggestions := []string{}
+	for rows.Next() {
+		var term string
+		if err := rows.Scan(&term); err != nil {
+			return nil, err
+		}
+		suggestions = append(suggestions, term)
+	}
+	return suggestions, rows.Err()
+}
@@ -0,0 +1,42 @@
+package repo
+
+import (
+	"context"
+	"errors"
+
+	domain "example.com/practice/fiber/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type emailVerificationRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewEmailVerificationRepo(db *pgxpool.Pool) *emailVerificationRepo {
+	return &emailVerificationRepo{db: db}
+}
+
+func (r *emailVerificationRepo) CreateEmailVerification(ctx context.Context, verification *domain.EmailVerification) error {
+	return r.db.QueryRow(ctx, "INSERT INTO email_verifications (user_id, token_hash, expires_at) VALUES ($1, $2, $3) RETURNING id, created_at", verification.UserID, verification.TokenHash, verification.ExpiresAt).Scan(&verification.ID, &verification.CreatedAt)
+}
+
+func (r *emailVerificationRepo) GetEmailVerificationByHash(ctx context.Context, tokenHash string) (*domain.EmailVerification, error) {
+	var verification domain.EmailVerification
+	err := r.db.QueryRow(ctx, "SELECT id, user_id, token_hash, expires_at, used_at, created_at FROM email_verifications WHERE token_hash = $1", tokenHash).Scan(&verification.ID, &verification.UserID, &verification.TokenHash, &verification.ExpiresAt, &verification.UsedAt, &verification.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &verification, nil
+}
+
+func (r *emailVerificationRepo) MarkEmailVerificationUsed(ctx context.Context, id int64) (bool, error) {
+	tag, err := r.db.Exec(ctx, "UPDATE email_verifications SET used_at = NOW() WHERE id = $1 AND used_at IS NULL", id)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
@@ -0,0 +1,37 @@
+package repo
+
+import (
+	"context"
+
+	domain "example.com/practice/fiber/internal/domain"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type impersonationAuditRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewImpersonationAuditRepo(db *pgxpool.Pool) *impersonationAuditRepo {
+	return &impersonationAuditRepo{db: db}
+}
+
+func (r *impersonationAuditRepo) CreateImpersonationAuditEntry(ctx context.Context, entry *domain.ImpersonationAuditEntry) error {
+	return r.db.QueryRow(ctx, "INSERT INTO impersonation_audit (actor_id, user_id, token_id, action, reason, method, path, ip) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id, created_at", entry.ActorID, entry.UserID, entry.TokenID, entry.Action, entry.Reason, entry.Method, entry.Path, entry.IP).Scan(&entry.ID, &entry.CreatedAt)
+}
+
+func (r *impersonationAuditRepo) ListImpersonationAuditEntries(ctx context.Context, userID int, limit int) ([]*domain.ImpersonationAuditEntry, error) {
+	rows, err := r.db.Query(ctx, "SELECT id, actor_id, user_id, token_id, action, reason, method, path, ip, created_at FROM impersonation_audit WHERE user_id = $1 ORDER BY id DESC LIMIT $2", userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	entries := []*domain.ImpersonationAuditEntry{}
+	for rows.Next() {
+		var entry domain.ImpersonationAuditEntry
+		if err := rows.Scan(&entry.ID, &entry.ActorID, &entry.UserID, &entry.TokenID, &entry.Action, &entry.Reason, &entry.Method, &entry.Path, &entry.IP, &entry.CreatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, &entry)
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,65 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	domain "example.com/practice/fiber/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type loginAttemptRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewLoginAttemptRepo(db *pgxpool.Pool) *loginAttemptRepo {
+	return &loginAttemptRepo{db: db}
+}
+
+func (r *loginAttemptRepo) GetLoginAttempt(ctx context.Context, key string) (*domain.LoginAttempt, error) {
+	var attempt domain.LoginAttempt
+	err := r.db.QueryRow(ctx, "SELECT attempt_key, failures, last_failure_at, locked_until FROM login_attempts WHERE attempt_key = $1", key).Scan(&attempt.Key, &attempt.Failures, &attempt.LastFailureAt, &attempt.LockedUntil)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &attempt, nil
+}
+
+func (r *loginAttemptRepo) RecordLoginFailure(ctx context.Context, key string, now, resetBefore time.Time) (*domain.LoginAttempt, error) {
+	var attempt domain.LoginAttempt
+	err := r.db.QueryRow(ctx, `INSERT INTO login_attempts (attempt_key, failures, last_failure_at) VALUES ($1, 1, $2)
+		ON CONFLICT (attempt_key) DO UPDATE SET
+			failures = CASE
+				WHEN GREATEST(login_attempts.last_failure_at, COALESCE(login_attempts.locked_until, login_attempts.last_failure_at)) < $3 THEN 1
+				ELSE login_attempts.failures + 1
+			END,
+			last_failure_at = EXCLUDED.last_failure_at
+		RETURNING attempt_key, failures, last_failure_at, locked_until`, key, now, resetBefore).Scan(&attempt.Key, &attempt.Failures, &attempt.LastFailureAt, &attempt.LockedUntil)
+	if err != nil {
+		return nil, err
+	}
+	return &attempt, nil
+}
+
+func (r *loginAttemptRepo) LockLogin(ctx context.Context, key string, until time.Time) error {
+	_, err := r.db.Exec(ctx, "UPDATE login_attempts SET locked_until = $2 WHERE attempt_key = $1", key, until)
+	return err
+}
+
+func (r *loginAttemptRepo) ResetLoginAttempts(ctx context.Context, key string) error {
+	_, err := r.db.Exec(ctx, "DELETE FROM login_attempts WHERE attempt_key = $1", key)
+	return err
+}
+
+func (r *loginAttemptRepo) DeleteStaleLoginAttempts(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := r.db.Exec(ctx, "DELETE FROM login_attempts WHERE GREATEST(last_failure_at, COALESCE(locked_until, last_failure_at)) < $1", before)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
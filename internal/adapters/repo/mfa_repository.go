@@ -0,0 +1,93 @@
+package repo
+
+import (
+	"context"
+	"errors"
+
+	domain "example.com/practice/fiber/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type mfaRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewMFARepo(db *pgxpool.Pool) *mfaRepo {
+	return &mfaRepo{db: db}
+}
+
+func (r *mfaRepo) GetMFA(ctx context.Context, userID int) (*domain.MFA, error) {
+	var mfa domain.MFA
+	err := r.db.QueryRow(ctx, "SELECT user_id, secret, enabled_at, last_used_step, created_at FROM user_mfa WHERE user_id = $1", userID).Scan(&mfa.UserID, &mfa.Secret, &mfa.EnabledAt, &mfa.LastUsedStep, &mfa.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &mfa, nil
+}
+
+func (r *mfaRepo) SaveMFASecret(ctx context.Context, userID int, secret string) (bool, error) {
+	tag, err := r.db.Exec(ctx, "INSERT INTO user_mfa (user_id, secret) VALUES ($1, $2) ON CONFLICT (user_id) DO UPDATE SET secret = EXCLUDED.secret, enabled_at = NULL, last_used_step = 0, created_at = NOW() WHERE user_mfa.enabled_at IS NULL", userID, secret)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func (r *mfaRepo) EnableMFA(ctx context.Context, userID int, recoveryCodeHashes []string) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+	tag, err := tx.Exec(ctx, "UPDATE user_mfa SET enabled_at = NOW() WHERE user_id = $1 AND enabled_at IS NULL", userID)
+	if err != nil {
+		return false, err
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+	if _, err := tx.Exec(ctx, "DELETE FROM mfa_recovery_codes WHERE user_id = $1", userID); err != nil {
+		return false, err
+	}
+	for _, codeHash := range recoveryCodeHashes {
+		if _, err := tx.Exec(ctx, "INSERT INTO mfa_recovery_codes (user_id, code_hash) VALUES ($1, $2)", userID, codeHash); err != nil {
+			return false, err
+		}
+	}
+	return true, tx.Commit(ctx)
+}
+
+func (r *mfaRepo) DisableMFA(ctx context.Context, userID int) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	if _, err := tx.Exec(ctx, "DELETE FROM mfa_recovery_codes WHERE user_id = $1", userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, "DELETE FROM user_mfa WHERE user_id = $1", userID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *mfaRepo) UpdateMFALastUsedStep(ctx context.Context, userID int, step int64) (bool, error) {
+	tag, err := r.db.Exec(ctx, "UPDATE user_mfa SET last_used_step = $2 WHERE user_id = $1 AND last_used_step < $2", userID, step)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func (r *mfaRepo) UseRecoveryCode(ctx context.Context, userID int, codeHash string) (bool, error) {
+	tag, err := r.db.Exec(ctx, "UPDATE mfa_recovery_codes SET used_at = NOW() WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL", userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
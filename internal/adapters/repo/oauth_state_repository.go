@@ -0,0 +1,43 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	domain "example.com/practice/fiber/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type oauthStateRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewOAuthStateRepo(db *pgxpool.Pool) *oauthStateRepo {
+	return &oauthStateRepo{db: db}
+}
+
+func (r *oauthStateRepo) CreateOAuthState(ctx context.Context, state *domain.OAuthState) error {
+	return r.db.QueryRow(ctx, "INSERT INTO oauth_states (state_hash, provider, code_verifier, nonce, expires_at) VALUES ($1, $2, $3, $4, $5) RETURNING created_at", state.StateHash, state.Provider, state.CodeVerifier, state.Nonce, state.ExpiresAt).Scan(&state.CreatedAt)
+}
+
+func (r *oauthStateRepo) ConsumeOAuthState(ctx context.Context, stateHash string) (*domain.OAuthState, error) {
+	var state domain.OAuthState
+	err := r.db.QueryRow(ctx, "DELETE FROM oauth_states WHERE state_hash = $1 RETURNING state_hash, provider, code_verifier, nonce, expires_at, created_at", stateHash).Scan(&state.StateHash, &state.Provider, &state.CodeVerifier, &state.Nonce, &state.ExpiresAt, &state.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+func (r *oauthStateRepo) DeleteExpiredOAuthStates(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := r.db.Exec(ctx, "DELETE FROM oauth_states WHERE expires_at < $1", before)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
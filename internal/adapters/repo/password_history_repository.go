@@ -0,0 +1,48 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type passwordHistoryRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewPasswordHistoryRepo(db *pgxpool.Pool) *passwordHistoryRepo {
+	return &passwordHistoryRepo{db: db}
+}
+
+func (r *passwordHistoryRepo) AddPasswordHistory(ctx context.Context, userID int, hash string, keep int) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	if _, err := tx.Exec(ctx, "INSERT INTO password_history (user_id, password_hash) VALUES ($1, $2)", userID, hash); err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, "DELETE FROM password_history WHERE user_id = $1 AND id NOT IN (SELECT id FROM password_history WHERE user_id = $1 ORDER BY id DESC LIMIT $2)", userID, keep)
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *passwordHistoryRepo) ListPasswordHistory(ctx context.Context, userID int, limit int) ([]string, error) {
+	rows, err := r.db.Query(ctx, "SELECT password_hash FROM password_history WHERE user_id = $1 ORDER BY id DESC LIMIT $2", userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	hashes := []string{}
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			return nil, err
+		}
+		hashes = append(hashes, hash)
+	}
+	return hashes, rows.Err()
+}
@@ -0,0 +1,47 @@
+package repo
+
+import (
+	"context"
+	"errors"
+
+	domain "example.com/practice/fiber/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type passwordResetRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewPasswordResetRepo(db *pgxpool.Pool) *passwordResetRepo {
+	return &passwordResetRepo{db: db}
+}
+
+func (r *passwordResetRepo) CreatePasswordReset(ctx context.Context, reset *domain.PasswordReset) error {
+	return r.db.QueryRow(ctx, "INSERT INTO password_resets (user_id, token_hash, expires_at) VALUES ($1, $2, $3) RETURNING id, created_at", reset.UserID, reset.TokenHash, reset.ExpiresAt).Scan(&reset.ID, &reset.CreatedAt)
+}
+
+func (r *passwordResetRepo) GetPasswordResetByHash(ctx context.Context, tokenHash string) (*domain.PasswordReset, error) {
+	var reset domain.PasswordReset
+	err := r.db.QueryRow(ctx, "SELECT id, user_id, token_hash, expires_at, used_at, created_at FROM password_resets WHERE token_hash = $1", tokenHash).Scan(&reset.ID, &reset.UserID, &reset.TokenHash, &reset.ExpiresAt, &reset.UsedAt, &reset.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &reset, nil
+}
+
+func (r *passwordResetRepo) MarkPasswordResetUsed(ctx context.Context, id int64) (bool, error) {
+	tag, err := r.db.Exec(ctx, "UPDATE password_resets SET used_at = NOW() WHERE id = $1 AND used_at IS NULL", id)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func (r *passwordResetRepo) InvalidateUserPasswordResets(ctx context.Context, userID int) error {
+	_, err := r.db.Exec(ctx, "UPDATE password_resets SET used_at = NOW() WHERE user_id = $1 AND used_at IS NULL", userID)
+	return err
+}
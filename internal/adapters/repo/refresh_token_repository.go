@@ -0,0 +1,52 @@
+package repo
+
+import (
+	"context"
+	"errors"
+
+	domain "example.com/practice/fiber/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type refreshTokenRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewRefreshTokenRepo(db *pgxpool.Pool) *refreshTokenRepo {
+	return &refreshTokenRepo{db: db}
+}
+
+func (r *refreshTokenRepo) CreateRefreshToken(ctx context.Context, token *domain.RefreshToken) error {
+	return r.db.QueryRow(ctx, "INSERT INTO refresh_tokens (user_id, family_id, token_hash, expires_at) VALUES ($1, $2, $3, $4) RETURNING id, created_at", token.UserID, token.FamilyID, token.TokenHash, token.ExpiresAt).Scan(&token.ID, &token.CreatedAt)
+}
+
+func (r *refreshTokenRepo) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (*domain.RefreshToken, error) {
+	var token domain.RefreshToken
+	err := r.db.QueryRow(ctx, "SELECT id, user_id, family_id, token_hash, expires_at, used_at, revoked_at, created_at FROM refresh_tokens WHERE token_hash = $1", tokenHash).Scan(&token.ID, &token.UserID, &token.FamilyID, &token.TokenHash, &token.ExpiresAt, &token.UsedAt, &token.RevokedAt, &token.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *refreshTokenRepo) MarkRefreshTokenUsed(ctx context.Context, id int64) (bool, error) {
+	tag, err := r.db.Exec(ctx, "UPDATE refresh_tokens SET used_at = NOW() WHERE id = $1 AND used_at IS NULL AND revoked_at IS NULL", id)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func (r *refreshTokenRepo) RevokeRefreshTokenFamily(ctx context.Context, familyID string) error {
+	_, err := r.db.Exec(ctx, "UPDATE refresh_tokens SET revoked_at = NOW() WHERE family_id = $1 AND revoked_at IS NULL", familyID)
+	return err
+}
+
+func (r *refreshTokenRepo) RevokeUserRefreshTokens(ctx context.Context, userID int) error {
+	_, err := r.db.Exec(ctx, "UPDATE refresh_tokens SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL", userID)
+	return err
+}
@@ -0,0 +1,78 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	domain "example.com/practice/fiber/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type sessionRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewSessionRepo(db *pgxpool.Pool) *sessionRepo {
+	return &sessionRepo{db: db}
+}
+
+func (r *sessionRepo) CreateSession(ctx context.Context, session *domain.Session) error {
+	return r.db.QueryRow(ctx, "INSERT INTO user_sessions (id, user_id, user_agent, ip) VALUES ($1, $2, $3, $4) RETURNING created_at, last_seen_at", session.ID, session.UserID, session.UserAgent, session.IP).Scan(&session.CreatedAt, &session.LastSeenAt)
+}
+
+func (r *sessionRepo) GetSession(ctx context.Context, id string) (*domain.Session, error) {
+	var session domain.Session
+	err := r.db.QueryRow(ctx, "SELECT id, user_id, user_agent, ip, created_at, last_seen_at, revoked_at FROM user_sessions WHERE id = $1", id).Scan(&session.ID, &session.UserID, &session.UserAgent, &session.IP, &session.CreatedAt, &session.LastSeenAt, &session.RevokedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *sessionRepo) ListUserSessions(ctx context.Context, userID int) ([]*domain.Session, error) {
+	rows, err := r.db.Query(ctx, "SELECT id, user_id, user_agent, ip, created_at, last_seen_at, revoked_at FROM user_sessions WHERE user_id = $1 AND revoked_at IS NULL ORDER BY last_seen_at DESC", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	sessions := []*domain.Session{}
+	for rows.Next() {
+		var session domain.Session
+		if err := rows.Scan(&session.ID, &session.UserID, &session.UserAgent, &session.IP, &session.CreatedAt, &session.LastSeenAt, &session.RevokedAt); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, &session)
+	}
+	return sessions, rows.Err()
+}
+
+func (r *sessionRepo) TouchSession(ctx context.Context, id string, seenAt time.Time) error {
+	_, err := r.db.Exec(ctx, "UPDATE user_sessions SET last_seen_at = $2 WHERE id = $1", id, seenAt)
+	return err
+}
+
+func (r *sessionRepo) RevokeSession(ctx context.Context, userID int, id string) (bool, error) {
+	tag, err := r.db.Exec(ctx, "UPDATE user_sessions SET revoked_at = NOW() WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL", id, userID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func (r *sessionRepo) RevokeUserSessions(ctx context.Context, userID int) error {
+	_, err := r.db.Exec(ctx, "UPDATE user_sessions SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL", userID)
+	return err
+}
+
+func (r *sessionRepo) DeleteStaleSessions(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := r.db.Exec(ctx, "DELETE FROM user_sessions WHERE last_seen_at < $1", before)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,47 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type tokenRevocationRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewTokenRevocationRepo(db *pgxpool.Pool) *tokenRevocationRepo {
+	return &tokenRevocationRepo{db: db}
+}
+
+func (r *tokenRevocationRepo) RevokeToken(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	_, err := r.db.Exec(ctx, "INSERT INTO revoked_tokens (token_id, expires_at) VALUES ($1, $2) ON CONFLICT (token_id) DO NOTHING", tokenID, expiresAt)
+	return err
+}
+
+func (r *tokenRevocationRepo) RevokeUserTokens(ctx context.Context, userID int, before time.Time, expiresAt time.Time) error {
+	_, err := r.db.Exec(ctx, "INSERT INTO user_token_revocations (user_id, revoked_before, expires_at) VALUES ($1, $2, $3) ON CONFLICT (user_id) DO UPDATE SET revoked_before = EXCLUDED.revoked_before, expires_at = EXCLUDED.expires_at", userID, before, expiresAt)
+	return err
+}
+
+func (r *tokenRevocationRepo) IsRevoked(ctx context.Context, tokenID string, userID int, issuedAt time.Time) (bool, error) {
+	var revoked bool
+	err := r.db.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM revoked_tokens WHERE token_id = $1) OR EXISTS (SELECT 1 FROM user_token_revocations WHERE user_id = $2 AND revoked_before >= $3)", tokenID, userID, issuedAt).Scan(&revoked)
+	if err != nil {
+		return false, err
+	}
+	return revoked, nil
+}
+
+func (r *tokenRevocationRepo) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	tokens, err := r.db.Exec(ctx, "DELETE FROM revoked_tokens WHERE expires_at < $1", now)
+	if err != nil {
+		return 0, err
+	}
+	users, err := r.db.Exec(ctx, "DELETE FROM user_token_revocations WHERE expires_at < $1", now)
+	if err != nil {
+		return 0, err
+	}
+	return tokens.RowsAffected() + users.RowsAffected(), nil
+}
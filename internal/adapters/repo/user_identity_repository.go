@@ -0,0 +1,58 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	domain "example.com/practice/fiber/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type userIdentityRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewUserIdentityRepo(db *pgxpool.Pool) *userIdentityRepo {
+	return &userIdentityRepo{db: db}
+}
+
+func (r *userIdentityRepo) GetUserIdentity(ctx context.Context, provider, subject string) (*domain.UserIdentity, error) {
+	var identity domain.UserIdentity
+	err := r.db.QueryRow(ctx, "SELECT id, user_id, provider, subject, COALESCE(email, ''), created_at, last_login_at FROM user_identities WHERE provider = $1 AND subject = $2", provider, subject).Scan(&identity.ID, &identity.UserID, &identity.Provider, &identity.Subject, &identity.Email, &identity.CreatedAt, &identity.LastLoginAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+func (r *userIdentityRepo) CreateUserIdentity(ctx context.Context, identity *domain.UserIdentity) error {
+	return r.db.QueryRow(ctx, "INSERT INTO user_identities (user_id, provider, subject, email) VALUES ($1, $2, $3, NULLIF($4, '')) RETURNING id, created_at", identity.UserID, identity.Provider, identity.Subject, identity.Email).Scan(&identity.ID, &identity.CreatedAt)
+}
+
+func (r *userIdentityRepo) CreateUserWithIdentity(ctx context.Context, user *domain.User, identity *domain.UserIdentity) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	err = tx.QueryRow(ctx, "INSERT INTO users (email, password, username, first_name, last_name, role, email_verified_at) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, is_active, created_at, updated_at", user.Email, user.Password, user.Username, user.FirstName, user.LastName, user.Role, user.EmailVerifiedAt).Scan(&user.ID, &user.IsActive, &user.CreatedAt, &user.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	identity.UserID = user.ID
+	err = tx.QueryRow(ctx, "INSERT INTO user_identities (user_id, provider, subject, email) VALUES ($1, $2, $3, NULLIF($4, '')) RETURNING id, created_at", identity.UserID, identity.Provider, identity.Subject, identity.Email).Scan(&identity.ID, &identity.CreatedAt)
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *userIdentityRepo) TouchUserIdentity(ctx context.Context, id int64, loginAt time.Time) error {
+	_, err := r.db.Exec(ctx, "UPDATE user_identities SET last_login_at = $2 WHERE id = $1", id, loginAt)
+	return err
+}
@@ -2,8 +2,14 @@ package repo
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
 
 	domain "example.com/practice/fiber/internal/domain"
+	"example.com/practice/fiber/pkg"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -15,9 +21,19 @@ func NewUserRepo(db *pgxpool.Pool) *userRepo {
 	return &userRepo{db: db}
 }
 
+const userColumns = "id, email, password, username, first_name, last_name, role, is_active, email_verified_at, suspended_at, COALESCE(suspension_reason, ''), created_at, updated_at, deleted_at"
+
+func scanUser(row pgx.Row, user *domain.User) error {
+	return row.Scan(&user.ID, &user.Email, &user.Password, &user.Username, &user.FirstName, &user.LastName, &user.Role, &user.IsActive,
+		&user.EmailVerifiedAt, &user.SuspendedAt, &user.SuspensionReason, &user.CreatedAt, &user.UpdatedAt, &user.DeletedAt)
+}
+
 func (r *userRepo) GetUserByID(ctx context.Context, userId string) (*domain.User, error) {
 	var user domain.User
-	err := r.db.QueryRow(ctx, "SELECT id, email, password, username, first_name, last_name, role FROM users WHERE id = $1", userId).Scan(&user.ID, &user.Email, &user.Password, &user.Username, &user.FirstName, &user.LastName, &user.Role)
+	err := scanUser(r.db.QueryRow(ctx, "SELECT "+userColumns+" FROM users WHERE id = $1 AND deleted_at IS NULL", userId), &user)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -26,7 +42,10 @@ func (r *userRepo) GetUserByID(ctx context.Context, userId string) (*domain.User
 
 func (r *userRepo) GetUserByEmail(ctx context.Context, email string) (*domain.User, error) {	
 	var user domain.User
-	err := r.db.QueryRow(ctx, "SELECT id, email, password, username, first_name, last_name, role FROM users WHERE email = $1", email).Scan(&user.ID, &user.Email, &user.Password, &user.Username, &user.FirstName, &user.LastName, &user.Role)
+	err := scanUser(r.db.QueryRow(ctx, "SELECT "+userColumns+" FROM users WHERE email = $1 AND deleted_at IS NULL", email), &user)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -35,14 +54,14 @@ func (r *userRepo) GetUserByEmail(ctx context.Context, email string) (*domain.Us
 
 func (r *userRepo) GetAllUsers(ctx context.Context) ([]*domain.User, error) {
 	var users []*domain.User
-	rows, err := r.db.Query(ctx, "SELECT id, email, password, username, first_name, last_name, role FROM users")
+	rows, err := r.db.Query(ctx, "SELECT "+userColumns+" FROM users WHERE deleted_at IS NULL")
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 	for rows.Next() {
 		var user domain.User
-		err := rows.Scan(&user.ID, &user.Email, &user.Password, &user.Username, &user.FirstName, &user.LastName, &user.Role)
+		err := scanUser(rows, &user)
 		if err != nil {
 			return nil, err
 		}
@@ -51,27 +70,66 @@ func (r *userRepo) GetAllUsers(ctx context.Context) ([]*domain.User, error) {
 	return users, nil
 }
 
-func (r *userRepo) CreateUser(ctx context.Context, user *domain.User) error {		
-	_, err := r.db.Exec(ctx, "INSERT INTO users (email, password, username, first_name, last_name, role) VALUES ($1, $2, $3, $4, $5, $6)", user.Email, user.Password, user.Username, user.FirstName, user.LastName, user.Role)
+func (r *userRepo) CreateUser(ctx context.Context, user *domain.User) (*domain.User, error) {
+	var stored domain.User
+	err := scanUser(r.db.QueryRow(ctx, "INSERT INTO users (email, password, username, first_name, last_name, role) VALUES ($1, $2, $3, $4, $5, $6) RETURNING "+userColumns, user.Email, user.Password, user.Username, user.FirstName, user.LastName, user.Role), &stored)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	return nil
+	return &stored, nil
 }
 
-func (r *userRepo) UpdateUser(ctx context.Context, user *domain.User) error {
-	_, err := r.db.Exec(ctx, "UPDATE users SET first_name = $1, last_name = $2 WHERE id = $3", user.FirstName, user.LastName, user.ID)
+func (r *userRepo) UpdateUser(ctx context.Context, user *domain.User) (*domain.User, error) {
+	var stored domain.User
+	err := scanUser(r.db.QueryRow(ctx, "UPDATE users SET first_name = $1, last_name = $2, updated_at = NOW() WHERE id = $3 AND deleted_at IS NULL RETURNING "+userColumns, user.FirstName, user.LastName, user.ID), &stored)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, pkg.ErrUserNotFound
+	}
 	if err != nil {
-		return err
+		return nil, err
 	}
-	return nil
+	return &stored, nil
+}
+
+func (r *userRepo) UpdateUserFields(ctx context.Context, user *domain.User, fields []string) (*domain.User, error) {
+	var set []string
+	var args []interface{}
+	add := func(assignment string, arg interface{}) {
+		args = append(args, arg)
+		set = append(set, fmt.Sprintf(assignment, len(args)))
+	}
+	for _, field := range fields {
+		switch field {
+		case "first_name":
+			add("first_name = $%d", user.FirstName)
+		case "last_name":
+			add("last_name = $%d", user.LastName)
+		default:
+			return nil, fmt.Errorf("unsupported user field %q", field)
+		}
+	}
+	args = append(args, user.ID)
+	sql := "UPDATE users SET " + strings.Join(set, ", ") + fmt.Sprintf(", updated_at = NOW() WHERE id = $%d AND deleted_at IS NULL RETURNING ", len(args)) + userColumns
+
+	var stored domain.User
+	err := scanUser(r.db.QueryRow(ctx, sql, args...), &stored)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, pkg.ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &stored, nil
 }
 
 func (r *userRepo) DeleteUser(ctx context.Context, id int) error {						
-	_, err := r.db.Exec(ctx, "DELETE FROM users WHERE id = $1", id)
+	tag, err := r.db.Exec(ctx, "UPDATE users SET deleted_at = NOW(), updated_at = NOW() WHERE id = $1 AND deleted_at IS NULL", id)
 	if err != nil {
 		return err
 	}
+	if tag.RowsAffected() == 0 {
+		return pkg.ErrUserNotFound
+	}
 	return nil
 }
 
@@ -82,3 +140,77 @@ func (r *userRepo) UpdatePassword(ctx context.Context, id int, password string)
 	}
 	return nil
 }
+
+func (r *userRepo) MarkEmailVerified(ctx context.Context, id int) error {
+	_, err := r.db.Exec(ctx, "UPDATE users SET email_verified_at = NOW() WHERE id = $1 AND email_verified_at IS NULL", id)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *userRepo) SetUserStatus(ctx context.Context, change *domain.UserStatusChange) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	if change.Active {
+		_, err = tx.Exec(ctx, "UPDATE users SET is_active = TRUE, suspended_at = NULL, suspension_reason = NULL, updated_at = NOW() WHERE id = $1", change.UserID)
+	} else {
+		_, err = tx.Exec(ctx, "UPDATE users SET is_active = FALSE, suspended_at = NOW(), suspension_reason = $2, updated_at = NOW() WHERE id = $1", change.UserID, change.Reason)
+	}
+	if err != nil {
+		return err
+	}
+	err = tx.QueryRow(ctx, "INSERT INTO user_status_changes (user_id, active, reason, changed_by) VALUES ($1, $2, $3, $4) RETURNING id, changed_at", change.UserID, change.Active, change.Reason, change.ChangedBy).Scan(&change.ID, &change.ChangedAt)
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *userRepo) RehashPassword(ctx context.Context, id int, oldHash, newHash string) error {
+	_, err := r.db.Exec(ctx, "UPDATE users SET password = $1 WHERE id = $2 AND password = $3", newHash, id, oldHash)
+	return err
+}
+
+func (r *userRepo) GetDeletedUsers(ctx context.Context) ([]*domain.User, error) {
+	rows, err := r.db.Query(ctx, "SELECT "+userColumns+" FROM users WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC, id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	users := []*domain.User{}
+	for rows.Next() {
+		var user domain.User
+		if err := scanUser(rows, &user); err != nil {
+			return nil, err
+		}
+		users = append(users, &user)
+	}
+	return users, rows.Err()
+}
+
+func (r *userRepo) RestoreUser(ctx context.Context, id int) (*domain.User, error) {
+	var stored domain.User
+	err := scanUser(r.db.QueryRow(ctx, "UPDATE users SET deleted_at = NULL, updated_at = NOW() WHERE id = $1 AND deleted_at IS NOT NULL RETURNING "+userColumns, id), &stored)
+	if uniqueViolation(err) {
+		return nil, pkg.ErrUserAlreadyExists
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, pkg.ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &stored, nil
+}
+
+func (r *userRepo) PurgeDeletedUsers(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := r.db.Exec(ctx, "DELETE FROM users WHERE deleted_at < $1", before)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,33 @@
+package domain
+
+import "time"
+
+// APIKeyScopes are the permissions an API key can be limited to.
+var APIKeyScopes = []string{PermBooksRead, PermBooksWrite}
+
+// APIKey is a long-lived credential for machine-to-machine access. Only the
+// hash of the key is stored; Prefix is the non-secret part used for lookup.
+type APIKey struct {
+	ID         int64      `json:"id"`
+	UserID     int        `json:"user_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	KeyHash    string     `json:"-"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name" validate:"required,max=100"`
+	Scopes    []string   `json:"scopes" validate:"required,min=1"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// CreateAPIKeyResponse includes the plain key, which is only shown once.
+type CreateAPIKeyResponse struct {
+	APIKey
+	Key string `json:"key"`
+}
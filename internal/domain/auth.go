@@ -1,10 +1,98 @@
 package domain
 
+import "time"
+
 type AuthResponse struct {
-	Token string `json:"token"`
+	Token        string `json:"token,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+
+	// Set instead of the tokens above when a second factor is needed.
+	MFARequired           bool     `json:"mfa_required,omitempty"`
+	MFAEnrollmentRequired bool     `json:"mfa_enrollment_required,omitempty"`
+	MFAToken              string   `json:"mfa_token,omitempty"`
+	RecoveryCodes         []string `json:"recovery_codes,omitempty"`
 }
 
 type AuthRequest struct {
 	Email    string `json:"email" validate:"required,email"`
 	Password string `json:"password" validate:"required"`
+	// ClientIP and UserAgent are set by the handler for login throttling
+	// and the session record.
+	ClientIP  string `json:"-"`
+	UserAgent string `json:"-"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+	// ClientIP and UserAgent are set by the handler for the session record.
+	ClientIP  string `json:"-"`
+	UserAgent string `json:"-"`
+}
+
+// Claims are the identity attributes carried by an access token. IssuedAt is
+// filled in by the token provider, which also sets ID and ExpiresAt unless
+// the caller chose them. A non-empty Purpose marks a restricted token such as
+// PurposeMFAPending. SessionID names the login session the token belongs to.
+// Actor is the subject of an admin acting as Subject (RFC 8693 "act").
+type Claims struct {
+	ID        string
+	Subject   string
+	Role      string
+	Purpose   string
+	SessionID string
+	Actor     string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
+// Principal is the authenticated caller of a request. Callers using an API
+// key carry its ID and are limited to its Scopes on top of their role. An
+// admin impersonating UserID is named by ActorID.
+type Principal struct {
+	UserID         int       `json:"user_id"`
+	Role           string    `json:"role"`
+	ActorID        int       `json:"actor_id,omitempty"`
+	TokenID        string    `json:"-"`
+	TokenExpiresAt time.Time `json:"-"`
+	SessionID      string    `json:"-"`
+	APIKeyID       int64     `json:"-"`
+	Scopes         []string  `json:"-"`
+}
+
+// HasScope reports whether the caller's credential covers permission. Tokens
+// are not scoped; API keys only cover the scopes they were created with.
+func (p *Principal) HasScope(permission string) bool {
+	if p.APIKeyID == 0 {
+		return true
+	}
+	for _, scope := range p.Scopes {
+		if scope == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// Impersonated reports whether an admin is acting as the user.
+func (p *Principal) Impersonated() bool {
+	return p.ActorID != 0
+}
+
+type LogoutRequest struct {
+	// RefreshToken is optional; when present its family is revoked too.
+	RefreshToken string `json:"refresh_token"`
+}
+
+// RefreshToken is the server-side record of an issued refresh token. Only the
+// hash of the token is stored. Tokens rotated from the same login share a
+// FamilyID so that a replayed token can revoke the whole chain.
+type RefreshToken struct {
+	ID        int64      `json:"id"`
+	UserID    int        `json:"user_id"`
+	FamilyID  string     `json:"family_id"`
+	TokenHash string     `json:"-"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
 }
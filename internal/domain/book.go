@@ -1,15 +1,38 @@
 package domain
 
 import (
+	"encoding/json"
 	"time"
 )
 
+// Book is a catalog entry. ISBN13 is kept without hyphens; clients may send
+// an ISBN-10 instead. ISBN10 is derived from ISBN13 when the book is written
+// out and ignored on input. Language is a BCP 47 tag such as "en" or "pt-BR".
+// Version starts at 1 and goes up with every update; clients echo it in
+// If-Match so that concurrent edits do not overwrite each other. DeletedAt is
+// set while the book is in the trash.
 type Book struct {
-	ID        int       `json:"id"`
-	Title     string    `json:"title"`
-	Author    string    `json:"author"`
-	Price     int       `json:"price"`
-	Stock     int       `json:"stock"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID        int        `json:"id"`
+	Title     string     `json:"title" validate:"required,max=255"`
+	Author    string     `json:"author" validate:"required,max=255"`
+	Price     Money      `json:"price"`
+	Stock     int        `json:"stock" validate:"gte=0"`
+	Published *Date      `json:"published,omitempty"`
+	ISBN13    string     `json:"isbn13,omitempty"`
+	ISBN10    string     `json:"isbn10,omitempty"`
+	Publisher string     `json:"publisher,omitempty" validate:"max=255"`
+	Language  string     `json:"language,omitempty" validate:"omitempty,max=35,bcp47_language_tag"`
+	PageCount int        `json:"page_count,omitempty" validate:"gte=0"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	Version   int        `json:"version" validate:"gte=0"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// MarshalJSON fills in ISBN10 for ISBN-13s that have one.
+func (b Book) MarshalJSON() ([]byte, error) {
+	type book Book
+	out := book(b)
+	out.ISBN10, _ = ISBN13To10(b.ISBN13)
+	return json.Marshal(out)
 }
@@ -0,0 +1,48 @@
+package domain
+
+// Fields a book listing can be sorted by.
+const (
+	BookSortCreatedAt = "created_at"
+	BookSortPrice     = "price"
+	BookSortTitle     = "title"
+)
+
+// BookFilter narrows a book listing. Zero fields are not applied. Title
+// matches a case-insensitive substring, Author the whole name in any case.
+// Price bounds are in Currency, which they require.
+type BookFilter struct {
+	Author   string
+	Title    string
+	Currency string
+	MinPrice *Money
+	MaxPrice *Money
+	InStock  *bool
+}
+
+// BookQuery asks for one page of books. Pages are keyset based: After is the
+// position of the last book of the previous page, in the same sort order.
+type BookQuery struct {
+	Filter BookFilter
+	Sort   string
+	Desc   bool
+	Limit  int
+	Cursor string
+	After  *BookCursor
+}
+
+// BookCursor is the position of a book in a sorted listing. Value is the
+// book's sort field in text form; ID breaks ties.
+type BookCursor struct {
+	Sort  string `json:"s"`
+	Desc  bool   `json:"d,omitempty"`
+	Value string `json:"v"`
+	ID    int    `json:"id"`
+}
+
+// BookPage is one page of a book listing. Total counts every book matching
+// the filter.
+type BookPage struct {
+	Books      []*Book `json:"books"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+	Total      int     `json:"total"`
+}
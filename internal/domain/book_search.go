@@ -0,0 +1,34 @@
+package domain
+
+// HighlightStart and HighlightStop surround matched terms in
+// BookHighlights. The rest of the highlighted text is HTML-escaped, so the
+// fields can be rendered as HTML.
+const (
+	HighlightStart = "<mark>"
+	HighlightStop  = "</mark>"
+)
+
+type BookSearchQuery struct {
+	Q     string
+	Limit int
+}
+
+type BookHighlights struct {
+	Title  string `json:"title"`
+	Author string `json:"author"`
+}
+
+// BookSearchHit is a book matching a search, best matches first. Rank is only
+// comparable within one search.
+type BookSearchHit struct {
+	Book       *Book          `json:"book"`
+	Rank       float64        `json:"rank"`
+	Highlights BookHighlights `json:"highlights"`
+}
+
+type BookSearchResult struct {
+	Hits []*BookSearchHit `json:"hits"`
+	// Suggestions are titles and authors close to the query, offered when
+	// nothing matched it.
+	Suggestions []string `json:"suggestions,omitempty"`
+}
@@ -0,0 +1,64 @@
+package domain
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestDateJSON(t *testing.T) {
+	d := NewDate(2001, time.February, 3)
+	data, err := json.Marshal(d)
+	if err != nil || string(data) != `"2001-02-03"` {
+		t.Fatalf("Marshal() = %s, %v", data, err)
+	}
+	var parsed Date
+	if err := json.Unmarshal(data, &parsed); err != nil || !parsed.Equal(d.Time) {
+		t.Fatalf("Unmarshal() = %v, %v", parsed, err)
+	}
+	for _, bad := range []string{`"2001-02-30"`, `"2001-2-3"`, `"2001-02-03T00:00:00Z"`, `20010203`} {
+		if err := json.Unmarshal([]byte(bad), &parsed); err == nil {
+			t.Errorf("Unmarshal(%s) should fail", bad)
+		}
+	}
+}
+
+func TestDateScanDate(t *testing.T) {
+	cases := []struct {
+		name  string
+		value pgtype.Date
+		want  Date
+		fails bool
+	}{
+		{"date", pgtype.Date{Time: time.Date(2001, time.February, 3, 0, 0, 0, 0, time.UTC), Valid: true}, NewDate(2001, time.February, 3), false},
+		{"other zone", pgtype.Date{Time: time.Date(2001, time.February, 3, 0, 0, 0, 0, time.FixedZone("", 9*3600)), Valid: true}, NewDate(2001, time.February, 3), false},
+		{"null", pgtype.Date{}, Date{}, false},
+		{"infinity", pgtype.Date{InfinityModifier: pgtype.Infinity, Valid: true}, Date{}, true},
+		{"-infinity", pgtype.Date{InfinityModifier: pgtype.NegativeInfinity, Valid: true}, Date{}, true},
+	}
+	for _, tc := range cases {
+		d := NewDate(1999, time.December, 31)
+		err := d.ScanDate(tc.value)
+		if (err != nil) != tc.fails {
+			t.Errorf("%s: ScanDate() error = %v, want failure %v", tc.name, err, tc.fails)
+			continue
+		}
+		if !tc.fails && d != tc.want {
+			t.Errorf("%s: ScanDate() = %v, want %v", tc.name, d, tc.want)
+		}
+	}
+}
+
+func TestDateValue(t *testing.T) {
+	d := NewDate(2001, time.February, 3)
+	v, err := d.DateValue()
+	if err != nil || !v.Valid || v.InfinityModifier != pgtype.Finite {
+		t.Fatalf("DateValue() = %+v, %v", v, err)
+	}
+	var scanned Date
+	if err := scanned.ScanDate(v); err != nil || scanned != d {
+		t.Errorf("round trip = %v, %v; want %v", scanned, err, d)
+	}
+}
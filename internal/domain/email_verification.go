@@ -0,0 +1,18 @@
+package domain
+
+import "time"
+
+// EmailVerification is a single-use token proving ownership of the address a
+// user registered with. Only its hash is stored.
+type EmailVerification struct {
+	ID        int64      `json:"id"`
+	UserID    int        `json:"user_id"`
+	TokenHash string     `json:"-"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type ResendVerificationRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
@@ -0,0 +1,39 @@
+package domain
+
+import "time"
+
+// Actions in the impersonation audit log.
+const (
+	ImpersonationActionStart   = "start"
+	ImpersonationActionRequest = "request"
+)
+
+// ImpersonationAuditEntry records an admin starting to impersonate a user,
+// with the reason given, or a request made with the impersonation token.
+type ImpersonationAuditEntry struct {
+	ID        int64     `json:"id"`
+	ActorID   int       `json:"actor_id"`
+	UserID    int       `json:"user_id"`
+	TokenID   string    `json:"token_id"`
+	Action    string    `json:"action"`
+	Reason    string    `json:"reason,omitempty"`
+	Method    string    `json:"method,omitempty"`
+	Path      string    `json:"path,omitempty"`
+	IP        string    `json:"ip"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ImpersonationRequest struct {
+	Reason string `json:"reason" validate:"required,max=500"`
+	// ClientIP is set by the handler for the audit log.
+	ClientIP string `json:"-"`
+}
+
+// ImpersonationResponse carries a short-lived access token for the
+// impersonated user. It cannot be refreshed.
+type ImpersonationResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+	UserID    int       `json:"user_id"`
+	ActorID   int       `json:"actor_id"`
+}
@@ -0,0 +1,73 @@
+package domain
+
+import (
+	"strings"
+
+	"example.com/practice/fiber/pkg"
+)
+
+// isbnBookland is the prefix that turns an ISBN-10 into an ISBN-13.
+const isbnBookland = "978"
+
+// NormalizeISBN returns the ISBN-13 form of isbn, which may be an ISBN-10 or
+// ISBN-13 with hyphens or spaces. It fails unless the check digit is right.
+func NormalizeISBN(isbn string) (string, error) {
+	compact := strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(isbn))
+	switch len(compact) {
+	case 13:
+		if !digitsOnly(compact) || isbn13CheckDigit(compact[:12]) != compact[12] {
+			return "", pkg.ErrInvalidISBN
+		}
+		return compact, nil
+	case 10:
+		if !digitsOnly(compact[:9]) || isbn10CheckDigit(compact[:9]) != compact[9] {
+			return "", pkg.ErrInvalidISBN
+		}
+		return isbn10To13(compact), nil
+	}
+	return "", pkg.ErrInvalidISBN
+}
+
+// isbn10To13 converts a compact ISBN-10 with a verified check digit to
+// ISBN-13.
+func isbn10To13(isbn10 string) string {
+	body := isbnBookland + isbn10[:9]
+	return body + string(isbn13CheckDigit(body))
+}
+
+// ISBN13To10 converts a compact ISBN-13 to ISBN-10. Only 978 ISBNs have an
+// ISBN-10 form.
+func ISBN13To10(isbn13 string) (string, error) {
+	if len(isbn13) != 13 || !digitsOnly(isbn13) || !strings.HasPrefix(isbn13, isbnBookland) {
+		return "", pkg.ErrInvalidISBN
+	}
+	body := isbn13[3:12]
+	return body + string(isbn10CheckDigit(body)), nil
+}
+
+// isbn13CheckDigit weighs the twelve digits of body 1, 3, 1, 3, ...
+func isbn13CheckDigit(body string) byte {
+	sum := 0
+	for i, r := range body {
+		weight := 1
+		if i%2 == 1 {
+			weight = 3
+		}
+		sum += int(r-'0') * weight
+	}
+	return byte('0' + (10-sum%10)%10)
+}
+
+// isbn10CheckDigit weighs the nine digits of body 10 down to 2; a check
+// value of ten is written X.
+func isbn10CheckDigit(body string) byte {
+	sum := 0
+	for i, r := range body {
+		sum += int(r-'0') * (10 - i)
+	}
+	check := (11 - sum%11) % 11
+	if check == 10 {
+		return 'X'
+	}
+	return byte('0' + check)
+}
@@ -0,0 +1,89 @@
+package domain
+
+import (
+	"encoding/json"
+	"testing"
+
+	"example.com/practice/fiber/pkg"
+)
+
+func TestNormalizeISBN(t *testing.T) {
+	cases := []struct {
+		isbn string
+		want string
+		err  error
+	}{
+		{"9780306406157", "9780306406157", nil},
+		{"978-0-306-40615-7", "9780306406157", nil},
+		{"978 0 306 40615 7", "9780306406157", nil},
+		{"0306406152", "9780306406157", nil},
+		{"0-306-40615-2", "9780306406157", nil},
+		// Check digit X, in either case
+		{"080442957X", "9780804429573", nil},
+		{"0-8044-2957-x", "9780804429573", nil},
+		// 979 ISBNs have no ISBN-10 form and are kept as they are
+		{"979-10-90636-07-1", "9791090636071", nil},
+		{"9780306406158", "", pkg.ErrInvalidISBN},
+		{"0306406153", "", pkg.ErrInvalidISBN},
+		{"0804429579", "", pkg.ErrInvalidISBN},
+		{"X804429573", "", pkg.ErrInvalidISBN},
+		{"978030640615X", "", pkg.ErrInvalidISBN},
+		{"97803064061", "", pkg.ErrInvalidISBN},
+		{"", "", pkg.ErrInvalidISBN},
+	}
+	for _, tc := range cases {
+		got, err := NormalizeISBN(tc.isbn)
+		if got != tc.want || err != tc.err {
+			t.Errorf("NormalizeISBN(%q) = %q, %v; want %q, %v", tc.isbn, got, err, tc.want, tc.err)
+		}
+	}
+}
+
+func TestISBN13To10(t *testing.T) {
+	cases := []struct {
+		isbn string
+		want string
+		err  error
+	}{
+		{"9780306406157", "0306406152", nil},
+		{"9780804429573", "080442957X", nil},
+		// The ISBN-13 check digit is not carried over
+		{"9780306406150", "0306406152", nil},
+		{"9791090636071", "", pkg.ErrInvalidISBN},
+		{"978-0-306-40615-7", "", pkg.ErrInvalidISBN},
+		{"0306406152", "", pkg.ErrInvalidISBN},
+		{"", "", pkg.ErrInvalidISBN},
+	}
+	for _, tc := range cases {
+		got, err := ISBN13To10(tc.isbn)
+		if got != tc.want || err != tc.err {
+			t.Errorf("ISBN13To10(%q) = %q, %v; want %q, %v", tc.isbn, got, err, tc.want, tc.err)
+		}
+	}
+}
+
+func TestBook_ISBN10(t *testing.T) {
+	cases := map[string]any{
+		"9780306406157": "0306406152",
+		"9791090636071": nil,
+		"":              nil,
+	}
+	for isbn13, want := range cases {
+		data, err := json.Marshal(&Book{ISBN13: isbn13})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var fields map[string]any
+		if err := json.Unmarshal(data, &fields); err != nil {
+			t.Fatal(err)
+		}
+		if fields["isbn10"] != want {
+			t.Errorf("json.Marshal(ISBN13 %q) = %s, want isbn10 %v", isbn13, data, want)
+		}
+	}
+	// Written out books can be read back in
+	var book Book
+	if err := pkg.DecodeJSON([]byte(`{"isbn13":"9780306406157","isbn10":"0306406152"}`), &book); err != nil {
+		t.Errorf("DecodeJSON() error = %v", err)
+	}
+}
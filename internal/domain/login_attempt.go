@@ -0,0 +1,55 @@
+package domain
+
+import "time"
+
+// LoginAttempt tracks failed logins for one key, e.g. an account or a client
+// IP.
+type LoginAttempt struct {
+	Key           string
+	Failures      int
+	LastFailureAt time.Time
+	LockedUntil   *time.Time
+}
+
+// Locked reports whether logins for the key are blocked at now.
+func (a *LoginAttempt) Locked(now time.Time) bool {
+	return a != nil && a.LockedUntil != nil && now.Before(*a.LockedUntil)
+}
+
+// LockoutPolicy decides when repeated login failures lock an account or a
+// client IP. Once the threshold is reached every further failure doubles
+// the lock, starting at BaseLockout and capped at MaxLockout. Failures are
+// forgotten after Window without new ones.
+type LockoutPolicy struct {
+	MaxAttempts   int
+	IPMaxAttempts int
+	Window        time.Duration
+	BaseLockout   time.Duration
+	MaxLockout    time.Duration
+}
+
+func DefaultLockoutPolicy() LockoutPolicy {
+	return LockoutPolicy{
+		MaxAttempts:   5,
+		IPMaxAttempts: 20,
+		Window:        15 * time.Minute,
+		BaseLockout:   time.Minute,
+		MaxLockout:    time.Hour,
+	}
+}
+
+// LockDuration returns how long to lock after failures, or 0 while failures
+// is below threshold.
+func (p LockoutPolicy) LockDuration(failures, threshold int) time.Duration {
+	if threshold <= 0 || failures < threshold {
+		return 0
+	}
+	lock := p.BaseLockout
+	for i := threshold; i < failures && lock < p.MaxLockout; i++ {
+		lock *= 2
+	}
+	if lock > p.MaxLockout {
+		lock = p.MaxLockout
+	}
+	return lock
+}
@@ -0,0 +1,49 @@
+package domain
+
+import "time"
+
+// Token purposes. Tokens with a purpose are only accepted by the login step
+// they were issued for, never as access tokens.
+const (
+	PurposeMFAPending = "mfa_pending"
+	PurposeMFAEnroll  = "mfa_enroll"
+)
+
+// MFA is a user's TOTP enrollment. It is pending until EnabledAt is set.
+type MFA struct {
+	UserID       int        `json:"user_id"`
+	Secret       string     `json:"-"`
+	EnabledAt    *time.Time `json:"enabled_at,omitempty"`
+	LastUsedStep int64      `json:"-"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+func (m *MFA) Enabled() bool {
+	return m != nil && m.EnabledAt != nil
+}
+
+type MFASetupResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_url"`
+}
+
+type MFASetupRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+}
+
+type MFACodeRequest struct {
+	// Code is a TOTP code or, where accepted, a recovery code.
+	Code string `json:"code" validate:"required"`
+}
+
+type MFALoginRequest struct {
+	MFAToken string `json:"mfa_token" validate:"required"`
+	Code     string `json:"code"`
+	// ClientIP and UserAgent are set by the handler for the session record.
+	ClientIP  string `json:"-"`
+	UserAgent string `json:"-"`
+}
+
+type MFARecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
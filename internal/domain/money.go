@@ -0,0 +1,246 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"example.com/practice/fiber/pkg"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// currencyExponents holds the number of decimal places of the minor unit of
+// each supported ISO 4217 currency.
+var currencyExponents = map[string]int{
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0, "KRW": 0,
+	"PYG": 0, "RWF": 0, "UGX": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+}
+
+// twoDecimalCurrencies lists the supported currencies with cents.
+var twoDecimalCurrencies = strings.Fields(`
+	AED AFN ALL AMD ANG AOA ARS AUD AWG AZN BAM BBD BDT BGN BMD BND BOB BRL BSD
+	BTN BWP BYN BZD CAD CDF CHF CNY COP CRC CUP CVE CZK DKK DOP DZD EGP ERN ETB
+	EUR FJD FKP GBP GEL GHS GIP GMD GTQ GYD HKD HNL HTG HUF IDR ILS INR IRR JMD
+	KES KGS KHR KPW KYD KZT LAK LBP LKR LRD LSL MAD MDL MGA MKD MMK MNT MOP MRU
+	MUR MVR MWK MXN MYR MZN NAD NGN NIO NOK NPR NZD PAB PEN PGK PHP PKR PLN QAR
+	RON RSD RUB SAR SBD SCR SDG SEK SGD SHP SLE SOS SRD SSP STN SVC SYP SZL THB
+	TJS TMT TOP TRY TTD TWD TZS UAH USD UYU UZS VES WST XCD YER ZAR ZMW ZWL`)
+
+func init() {
+	for _, code := range twoDecimalCurrencies {
+		currencyExponents[code] = 2
+	}
+}
+
+// Money is an exact amount of a currency, counted in its minor unit (cents
+// for USD, yen for JPY). Arithmetic never rounds; it fails instead of
+// overflowing or mixing currencies.
+//
+// In JSON, Money is {"amount": "19.99", "currency": "USD"}. In Postgres, it
+// reads and writes the NUMERIC amount; the currency is a column of its own
+// and must be scanned before the amount.
+type Money struct {
+	Amount   int64
+	Currency string
+}
+
+// ParseMoney parses a decimal amount such as "19.99" in currency. The amount
+// may not have more decimal places than the currency's minor unit.
+func ParseMoney(amount, currency string) (Money, error) {
+	exp, ok := currencyExponents[currency]
+	if !ok {
+		return Money{}, pkg.ErrInvalidMoney
+	}
+	negative := strings.HasPrefix(amount, "-")
+	whole, frac, hasPoint := strings.Cut(strings.TrimPrefix(amount, "-"), ".")
+	if whole == "" || (hasPoint && frac == "") || len(frac) > exp || !digitsOnly(whole) || !digitsOnly(frac) {
+		return Money{}, pkg.ErrInvalidMoney
+	}
+	frac += strings.Repeat("0", exp-len(frac))
+	units, err := strconv.ParseInt(whole+frac, 10, 64)
+	if err != nil {
+		return Money{}, pkg.ErrMoneyOverflow
+	}
+	if negative {
+		units = -units
+	}
+	return Money{Amount: units, Currency: currency}, nil
+}
+
+func digitsOnly(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Valid reports whether m is in a supported currency.
+func (m Money) Valid() bool {
+	_, ok := currencyExponents[m.Currency]
+	return ok
+}
+
+func (m Money) IsNegative() bool {
+	return m.Amount < 0
+}
+
+// Decimal formats the amount in major units, e.g. "19.99".
+func (m Money) Decimal() string {
+	exp := currencyExponents[m.Currency]
+	digits := strconv.FormatUint(absAmount(m.Amount), 10)
+	if len(digits) <= exp {
+		digits = strings.Repeat("0", exp-len(digits)+1) + digits
+	}
+	sign := ""
+	if m.Amount < 0 {
+		sign = "-"
+	}
+	if exp == 0 {
+		return sign + digits
+	}
+	return sign + digits[:len(digits)-exp] + "." + digits[len(digits)-exp:]
+}
+
+func absAmount(n int64) uint64 {
+	if n < 0 {
+		return uint64(-(n + 1)) + 1
+	}
+	return uint64(n)
+}
+
+func (m Money) String() string {
+	return m.Decimal() + " " + m.Currency
+}
+
+func (m Money) Add(other Money) (Money, error) {
+	if m.Currency != other.Currency {
+		return Money{}, pkg.ErrCurrencyMismatch
+	}
+	sum := m.Amount + other.Amount
+	if (other.Amount > 0 && sum < m.Amount) || (other.Amount < 0 && sum > m.Amount) {
+		return Money{}, pkg.ErrMoneyOverflow
+	}
+	return Money{Amount: sum, Currency: m.Currency}, nil
+}
+
+func (m Money) Sub(other Money) (Money, error) {
+	if other.Amount == math.MinInt64 {
+		return Money{}, pkg.ErrMoneyOverflow
+	}
+	return m.Add(Money{Amount: -other.Amount, Currency: other.Currency})
+}
+
+// Mul multiplies m by a whole quantity.
+func (m Money) Mul(quantity int64) (Money, error) {
+	product := new(big.Int).Mul(big.NewInt(m.Amount), big.NewInt(quantity))
+	if !product.IsInt64() {
+		return Money{}, pkg.ErrMoneyOverflow
+	}
+	return Money{Amount: product.Int64(), Currency: m.Currency}, nil
+}
+
+// Cmp compares m with other, which must be in the same currency, and returns
+// -1, 0 or +1.
+func (m Money) Cmp(other Money) (int, error) {
+	if m.Currency != other.Currency {
+		return 0, pkg.ErrCurrencyMismatch
+	}
+	switch {
+	case m.Amount < other.Amount:
+		return -1, nil
+	case m.Amount > other.Amount:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+type moneyJSON struct {
+	Amount   string `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+// MarshalJSON writes the amount as a string so that clients do not read it
+// into a float. Money without a currency is null.
+func (m Money) MarshalJSON() ([]byte, error) {
+	if m.Currency == "" {
+		return []byte("null"), nil
+	}
+	return json.Marshal(moneyJSON{Amount: m.Decimal(), Currency: m.Currency})
+}
+
+// UnmarshalJSON accepts the amount as a string or a number. Numbers are read
+// from their digits, never through a float.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*m = Money{}
+		return nil
+	}
+	var raw struct {
+		Amount   json.Number `json:"amount"`
+		Currency string      `json:"currency"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return pkg.ErrInvalidMoney
+	}
+	parsed, err := ParseMoney(raw.Amount.String(), strings.ToUpper(raw.Currency))
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// ScanNumeric implements pgtype.NumericScanner. It needs the currency, so
+// the currency column must come first in the scan. SQL NULL scans as zero.
+func (m *Money) ScanNumeric(n pgtype.Numeric) error {
+	exp, ok := currencyExponents[m.Currency]
+	if !ok {
+		return fmt.Errorf("scan money: unknown currency %q", m.Currency)
+	}
+	if !n.Valid {
+		m.Amount = 0
+		return nil
+	}
+	if n.NaN || n.InfinityModifier != pgtype.Finite {
+		return pkg.ErrInvalidMoney
+	}
+	units := new(big.Int).Set(n.Int)
+	shift := int64(n.Exp) + int64(exp)
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(absShift(shift)), nil)
+	if shift >= 0 {
+		units.Mul(units, scale)
+	} else {
+		var rem big.Int
+		units.QuoRem(units, scale, &rem)
+		if rem.Sign() != 0 {
+			return fmt.Errorf("scan money: %s has more decimal places than %s allows", n.Int, m.Currency)
+		}
+	}
+	if !units.IsInt64() {
+		return pkg.ErrMoneyOverflow
+	}
+	m.Amount = units.Int64()
+	return nil
+}
+
+func absShift(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// NumericValue implements pgtype.NumericValuer.
+func (m Money) NumericValue() (pgtype.Numeric, error) {
+	exp, ok := currencyExponents[m.Currency]
+	if !ok {
+		return pgtype.Numeric{}, pkg.ErrInvalidMoney
+	}
+	return pgtype.Numeric{Int: big.NewInt(m.Amount), Exp: int32(-exp), Valid: true}, nil
+}
@@ -0,0 +1,135 @@
+package domain
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"testing"
+
+	"example.com/practice/fiber/pkg"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestParseMoney(t *testing.T) {
+	cases := []struct {
+		amount, currency string
+		units            int64
+		decimal          string
+		err              error
+	}{
+		{"19.99", "USD", 1999, "19.99", nil},
+		{"19.9", "USD", 1990, "19.90", nil},
+		{"0.05", "USD", 5, "0.05", nil},
+		{"-3", "USD", -300, "-3.00", nil},
+		{"1500", "JPY", 1500, "1500", nil},
+		{"1.234", "KWD", 1234, "1.234", nil},
+		{"0.001", "KWD", 1, "0.001", nil},
+		{"19.999", "USD", 0, "", pkg.ErrInvalidMoney},
+		{"1.5", "JPY", 0, "", pkg.ErrInvalidMoney},
+		{"1.2345", "KWD", 0, "", pkg.ErrInvalidMoney},
+		{"1.", "USD", 0, "", pkg.ErrInvalidMoney},
+		{".5", "USD", 0, "", pkg.ErrInvalidMoney},
+		{"1e3", "USD", 0, "", pkg.ErrInvalidMoney},
+		{"1", "XYZ", 0, "", pkg.ErrInvalidMoney},
+		{"92233720368547758.08", "USD", 0, "", pkg.ErrMoneyOverflow},
+	}
+	for _, tc := range cases {
+		m, err := ParseMoney(tc.amount, tc.currency)
+		if !errors.Is(err, tc.err) {
+			t.Errorf("ParseMoney(%q, %s) error = %v, want %v", tc.amount, tc.currency, err, tc.err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if m.Amount != tc.units || m.Decimal() != tc.decimal {
+			t.Errorf("ParseMoney(%q, %s) = %d (%s), want %d (%s)", tc.amount, tc.currency, m.Amount, m.Decimal(), tc.units, tc.decimal)
+		}
+	}
+}
+
+func TestMoneyArithmetic(t *testing.T) {
+	usd := func(units int64) Money { return Money{Amount: units, Currency: "USD"} }
+	cases := []struct {
+		name string
+		op   func() (Money, error)
+		want Money
+		err  error
+	}{
+		{"add", func() (Money, error) { return usd(150).Add(usd(250)) }, usd(400), nil},
+		{"sub", func() (Money, error) { return usd(150).Sub(usd(250)) }, usd(-100), nil},
+		{"mul", func() (Money, error) { return usd(150).Mul(3) }, usd(450), nil},
+		{"add overflow", func() (Money, error) { return usd(math.MaxInt64).Add(usd(1)) }, Money{}, pkg.ErrMoneyOverflow},
+		{"add underflow", func() (Money, error) { return usd(math.MinInt64).Add(usd(-1)) }, Money{}, pkg.ErrMoneyOverflow},
+		{"sub min", func() (Money, error) { return usd(0).Sub(usd(math.MinInt64)) }, Money{}, pkg.ErrMoneyOverflow},
+		{"mul overflow", func() (Money, error) { return usd(math.MaxInt64 / 2).Mul(3) }, Money{}, pkg.ErrMoneyOverflow},
+		{"add currency mismatch", func() (Money, error) { return usd(1).Add(Money{Amount: 1, Currency: "EUR"}) }, Money{}, pkg.ErrCurrencyMismatch},
+		{"sub currency mismatch", func() (Money, error) { return usd(1).Sub(Money{Amount: 1, Currency: "JPY"}) }, Money{}, pkg.ErrCurrencyMismatch},
+	}
+	for _, tc := range cases {
+		got, err := tc.op()
+		if err != tc.err || got != tc.want {
+			t.Errorf("%s = %v, %v; want %v, %v", tc.name, got, err, tc.want, tc.err)
+		}
+	}
+
+	if c, err := usd(1).Cmp(usd(2)); c != -1 || err != nil {
+		t.Errorf("Cmp() = %d, %v; want -1", c, err)
+	}
+	if c, err := usd(2).Cmp(usd(2)); c != 0 || err != nil {
+		t.Errorf("Cmp() = %d, %v; want 0", c, err)
+	}
+	if _, err := usd(1).Cmp(Money{Amount: 1, Currency: "EUR"}); err != pkg.ErrCurrencyMismatch {
+		t.Errorf("Cmp() error = %v, want %v", err, pkg.ErrCurrencyMismatch)
+	}
+}
+
+func TestMoneyScanNumeric(t *testing.T) {
+	price := pgtype.Numeric{Int: big.NewInt(1999), Exp: -2, Valid: true}
+	cases := []struct {
+		name     string
+		currency string
+		numeric  pgtype.Numeric
+		want     int64
+		fails    bool
+	}{
+		{"USD", "USD", price, 1999, false},
+		{"KWD", "KWD", price, 19990, false},
+		{"JPY remainder", "JPY", price, 0, true},
+		{"JPY whole", "JPY", pgtype.Numeric{Int: big.NewInt(1500), Exp: 0, Valid: true}, 1500, false},
+		{"positive exponent", "USD", pgtype.Numeric{Int: big.NewInt(2), Exp: 3, Valid: true}, 200000, false},
+		{"trailing zeros", "JPY", pgtype.Numeric{Int: big.NewInt(150000), Exp: -2, Valid: true}, 1500, false},
+		{"null", "USD", pgtype.Numeric{}, 0, false},
+		{"NaN", "USD", pgtype.Numeric{NaN: true, Valid: true}, 0, true},
+		{"infinity", "USD", pgtype.Numeric{InfinityModifier: pgtype.Infinity, Valid: true}, 0, true},
+		{"overflow", "USD", pgtype.Numeric{Int: big.NewInt(math.MaxInt64), Exp: 0, Valid: true}, 0, true},
+		{"unknown currency", "XYZ", price, 0, true},
+	}
+	for _, tc := range cases {
+		m := Money{Amount: 42, Currency: tc.currency}
+		err := m.ScanNumeric(tc.numeric)
+		if (err != nil) != tc.fails {
+			t.Errorf("%s: ScanNumeric() error = %v, want failure %v", tc.name, err, tc.fails)
+			continue
+		}
+		if !tc.fails && m.Amount != tc.want {
+			t.Errorf("%s: ScanNumeric() = %d, want %d", tc.name, m.Amount, tc.want)
+		}
+	}
+}
+
+func TestMoneyNumericValue(t *testing.T) {
+	for _, m := range []Money{{Amount: 1999, Currency: "USD"}, {Amount: -1500, Currency: "JPY"}, {Amount: 1234, Currency: "KWD"}} {
+		n, err := m.NumericValue()
+		if err != nil {
+			t.Fatalf("NumericValue(%v) error = %v", m, err)
+		}
+		scanned := Money{Currency: m.Currency}
+		if err := scanned.ScanNumeric(n); err != nil || scanned != m {
+			t.Errorf("round trip of %v = %v, %v", m, scanned, err)
+		}
+	}
+	if _, err := (Money{Amount: 1, Currency: "XYZ"}).NumericValue(); err != pkg.ErrInvalidMoney {
+		t.Errorf("NumericValue() error = %v, want %v", err, pkg.ErrInvalidMoney)
+	}
+}
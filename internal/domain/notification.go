@@ -0,0 +1,9 @@
+package domain
+
+// Notification is a message delivered to a user through a ports.Notifier,
+// e.g. a password reset link.
+type Notification struct {
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
@@ -0,0 +1,53 @@
+package domain
+
+import "time"
+
+// ExternalIdentity is what an identity provider asserts about a user after a
+// successful sign-in.
+type ExternalIdentity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	GivenName     string
+	FamilyName    string
+}
+
+// UserIdentity links a user to their account at an external identity
+// provider.
+type UserIdentity struct {
+	ID          int64      `json:"id"`
+	UserID      int        `json:"user_id"`
+	Provider    string     `json:"provider"`
+	Subject     string     `json:"subject"`
+	Email       string     `json:"email"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+}
+
+// OAuthState is kept between redirecting to a provider and its callback.
+// Only the hash of the state parameter is stored.
+type OAuthState struct {
+	StateHash    string
+	Provider     string
+	CodeVerifier string
+	Nonce        string
+	ExpiresAt    time.Time
+	CreatedAt    time.Time
+}
+
+// OAuthStart is where to send the browser to sign in at a provider.
+type OAuthStart struct {
+	AuthorizationURL string `json:"authorization_url"`
+	State            string `json:"-"`
+}
+
+// OAuthCallbackRequest carries the query parameters of a provider callback.
+type OAuthCallbackRequest struct {
+	Provider string
+	State    string `validate:"required"`
+	Code     string
+	Error    string
+	// ClientIP and UserAgent are recorded on the session.
+	ClientIP  string
+	UserAgent string
+}
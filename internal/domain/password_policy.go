@@ -0,0 +1,23 @@
+package domain
+
+// PasswordPolicy lists the rules new passwords must follow. MinLength counts
+// characters; MaxLength counts bytes because bcrypt rejects passwords longer
+// than 72 bytes. History is how many of the user's latest passwords,
+// including the current one, may not be reused.
+type PasswordPolicy struct {
+	MinLength          int
+	MaxLength          int
+	RequireUpper       bool
+	RequireLower       bool
+	RequireDigit       bool
+	RequireSymbol      bool
+	ForbidPersonalInfo bool
+	History            int
+}
+
+func DefaultPasswordPolicy() PasswordPolicy {
+	return PasswordPolicy{
+		MinLength: 8,
+		MaxLength: 72,
+	}
+}
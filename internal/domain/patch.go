@@ -0,0 +1,385 @@
+package domain
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"example.com/practice/fiber/pkg"
+)
+
+// Media types of the partial updates PATCH endpoints accept.
+const (
+	// MergePatchType is a JSON merge patch (RFC 7396): an object whose
+	// members replace those of the resource, with null removing them.
+	MergePatchType = "application/merge-patch+json"
+	// JSONPatchType is a JSON patch (RFC 6902): a list of add, remove,
+	// replace, move, copy and test operations on JSON pointers.
+	JSONPatchType = "application/json-patch+json"
+)
+
+// Patch is a partial update of a resource in one of the patch media types.
+type Patch struct {
+	Type string
+	Body []byte
+}
+
+// Supported reports whether p is in a media type Apply understands.
+func (p Patch) Supported() bool {
+	return p.Type == MergePatchType || p.Type == JSONPatchType
+}
+
+// Apply returns the JSON document doc with p applied. It returns
+// pkg.ErrUnsupportedPatch for other media types, pkg.ErrInvalidPatch when
+// the patch is malformed and pkg.ErrPatchFailed when one of its operations
+// does not fit doc, in which case nothing is applied.
+func (p Patch) Apply(doc []byte) ([]byte, error) {
+	var target interface{}
+	if err := decodeJSON(doc, &target); err != nil {
+		return nil, err
+	}
+	switch p.Type {
+	case MergePatchType:
+		var patch interface{}
+		if err := decodeJSON(p.Body, &patch); err != nil {
+			return nil, pkg.ErrInvalidPatch
+		}
+		target = mergePatch(target, patch)
+	case JSONPatchType:
+		var ops []patchOperation
+		if err := decodeJSON(p.Body, &ops); err != nil {
+			return nil, pkg.ErrInvalidPatch
+		}
+		for _, op := range ops {
+			var err error
+			if target, err = op.apply(target); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, pkg.ErrUnsupportedPatch
+	}
+	return json.Marshal(target)
+}
+
+// decodeJSON decodes data into v, keeping numbers as json.Number so that
+// they are written back exactly as they were read.
+func decodeJSON(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(v); err != nil {
+		return err
+	}
+	if decoder.More() {
+		return pkg.ErrInvalidPatch
+	}
+	return nil
+}
+
+func mergePatch(target, patch interface{}) interface{} {
+	members, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	object, ok := target.(map[string]interface{})
+	if !ok {
+		object = map[string]interface{}{}
+	}
+	for name, value := range members {
+		if value == nil {
+			delete(object, name)
+			continue
+		}
+		object[name] = mergePatch(object[name], value)
+	}
+	return object
+}
+
+// patchOperation is one operation of a JSON patch. Value stays raw so that a
+// missing value can be told apart from null.
+type patchOperation struct {
+	Op    string          `json:"op"`
+	Path  *string         `json:"path"`
+	From  *string         `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+func (op patchOperation) apply(doc interface{}) (interface{}, error) {
+	if op.Path == nil {
+		return nil, pkg.ErrInvalidPatch
+	}
+	path, err := parsePointer(*op.Path)
+	if err != nil {
+		return nil, err
+	}
+	var value interface{}
+	var from []string
+	switch op.Op {
+	case "add", "replace", "test":
+		if op.Value == nil {
+			return nil, pkg.ErrInvalidPatch
+		}
+		if err := decodeJSON(op.Value, &value); err != nil {
+			return nil, pkg.ErrInvalidPatch
+		}
+	case "move", "copy":
+		if op.From == nil {
+			return nil, pkg.ErrInvalidPatch
+		}
+		if from, err = parsePointer(*op.From); err != nil {
+			return nil, err
+		}
+	case "remove":
+	default:
+		return nil, pkg.ErrInvalidPatch
+	}
+	switch op.Op {
+	case "add":
+		return addValue(doc, path, value)
+	case "remove":
+		return removeValue(doc, path)
+	case "replace":
+		if _, err := getValue(doc, path); err != nil {
+			return nil, err
+		}
+		return replaceValue(doc, path, value)
+	case "move":
+		// A value cannot move into one of its own children
+		if len(from) < len(path) && isPrefix(from, path) {
+			return nil, pkg.ErrPatchFailed
+		}
+		moved, err := getValue(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		if doc, err = removeValue(doc, from); err != nil {
+			return nil, err
+		}
+		return addValue(doc, path, moved)
+	case "copy":
+		copied, err := getValue(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		return addValue(doc, path, deepCopy(copied))
+	default: // test
+		current, err := getValue(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !jsonEqual(current, value) {
+			return nil, pkg.ErrPatchFailed
+		}
+		return doc, nil
+	}
+}
+
+// pointerEscaper decodes the ~1 and ~0 escapes of a JSON pointer token in a
+// single pass, so that "~01" stays "~1".
+var pointerEscaper = strings.NewReplacer("~1", "/", "~0", "~")
+
+// parsePointer splits a JSON pointer (RFC 6901) into its tokens. The empty
+// pointer is the whole document.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	}
+	if pointer[0] != '/' {
+		return nil, pkg.ErrInvalidPatch
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = pointerEscaper.Replace(token)
+	}
+	return tokens, nil
+}
+
+func isPrefix(prefix, path []string) bool {
+	for i := range prefix {
+		if prefix[i] != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// arrayIndex parses token as an index into an array of length n. "-", the
+// end of the array, is only allowed when end is true.
+func arrayIndex(token string, n int, end bool) (int, error) {
+	if token == "-" && end {
+		return n, nil
+	}
+	if token == "" || (len(token) > 1 && token[0] == '0') || strings.Trim(token, "0123456789") != "" {
+		return 0, pkg.ErrPatchFailed
+	}
+	i, err := strconv.Atoi(token)
+	if err != nil || i > n || (i == n && !end) {
+		return 0, pkg.ErrPatchFailed
+	}
+	return i, nil
+}
+
+func getValue(doc interface{}, path []string) (interface{}, error) {
+	for _, token := range path {
+		switch container := doc.(type) {
+		case map[string]interface{}:
+			value, ok := container[token]
+			if !ok {
+				return nil, pkg.ErrPatchFailed
+			}
+			doc = value
+		case []interface{}:
+			i, err := arrayIndex(token, len(container), false)
+			if err != nil {
+				return nil, err
+			}
+			doc = container[i]
+		default:
+			return nil, pkg.ErrPatchFailed
+		}
+	}
+	return doc, nil
+}
+
+// replaceValue sets the existing value at path and returns the new document.
+func replaceValue(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	parent, err := getValue(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	token := path[len(path)-1]
+	switch container := parent.(type) {
+	case map[string]interface{}:
+		container[token] = value
+	case []interface{}:
+		i, err := arrayIndex(token, len(container), false)
+		if err != nil {
+			return nil, err
+		}
+		container[i] = value
+	default:
+		return nil, pkg.ErrPatchFailed
+	}
+	return doc, nil
+}
+
+func addValue(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	parentPath := path[:len(path)-1]
+	parent, err := getValue(doc, parentPath)
+	if err != nil {
+		return nil, err
+	}
+	token := path[len(path)-1]
+	switch container := parent.(type) {
+	case map[string]interface{}:
+		container[token] = value
+		return doc, nil
+	case []interface{}:
+		i, err := arrayIndex(token, len(container), true)
+		if err != nil {
+			return nil, err
+		}
+		grown := make([]interface{}, 0, len(container)+1)
+		grown = append(append(append(grown, container[:i]...), value), container[i:]...)
+		return replaceValue(doc, parentPath, grown)
+	default:
+		return nil, pkg.ErrPatchFailed
+	}
+}
+
+func removeValue(doc interface{}, path []string) (interface{}, error) {
+	if len(path) == 0 {
+		return nil, pkg.ErrPatchFailed
+	}
+	parentPath := path[:len(path)-1]
+	parent, err := getValue(doc, parentPath)
+	if err != nil {
+		return nil, err
+	}
+	token := path[len(path)-1]
+	switch container := parent.(type) {
+	case map[string]interface{}:
+		if _, ok := container[token]; !ok {
+			return nil, pkg.ErrPatchFailed
+		}
+		delete(container, token)
+		return doc, nil
+	case []interface{}:
+		i, err := arrayIndex(token, len(container), false)
+		if err != nil {
+			return nil, err
+		}
+		shrunk := make([]interface{}, 0, len(container)-1)
+		shrunk = append(append(shrunk, container[:i]...), container[i+1:]...)
+		return replaceValue(doc, parentPath, shrunk)
+	default:
+		return nil, pkg.ErrPatchFailed
+	}
+}
+
+func deepCopy(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		object := make(map[string]interface{}, len(v))
+		for name, member := range v {
+			object[name] = deepCopy(member)
+		}
+		return object
+	case []interface{}:
+		array := make([]interface{}, len(v))
+		for i, element := range v {
+			array[i] = deepCopy(element)
+		}
+		return array
+	default:
+		return value
+	}
+}
+
+// jsonEqual compares JSON values the way the test operation does: numbers
+// by value, objects regardless of member order.
+func jsonEqual(a, b interface{}) bool {
+	switch a := a.(type) {
+	case map[string]interface{}:
+		b, ok := b.(map[string]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for name, member := range a {
+			other, ok := b[name]
+			if !ok || !jsonEqual(member, other) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		b, ok := b.([]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !jsonEqual(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	case json.Number:
+		b, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		x, okA := new(big.Rat).SetString(a.String())
+		y, okB := new(big.Rat).SetString(b.String())
+		return okA && okB && x.Cmp(y) == 0
+	default:
+		return a == b
+	}
+}
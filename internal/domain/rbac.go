@@ -0,0 +1,44 @@
+package domain
+
+const (
+	RoleAdmin = "admin"
+	RoleUser  = "user"
+)
+
+// Permissions checked by the HTTP layer. They use the same "resource:action"
+// form throughout so roles can be configured as plain string lists.
+const (
+	PermBooksRead        = "books:read"
+	PermBooksWrite       = "books:write"
+	PermBooksManage      = "books:manage"
+	PermUsersRead        = "users:read"
+	PermUsersManage      = "users:manage"
+	PermUsersImpersonate = "users:impersonate"
+	PermProfileRead      = "profile:read"
+	PermProfileWrite     = "profile:write"
+)
+
+// PermAll grants every permission to a role.
+const PermAll = "*"
+
+// RolePolicy maps a role to the permissions it is granted.
+type RolePolicy map[string][]string
+
+// DefaultRolePolicy is used when no policy is configured: admins can do
+// anything, regular users can read books and manage their own profile.
+func DefaultRolePolicy() RolePolicy {
+	return RolePolicy{
+		RoleAdmin: {PermAll},
+		RoleUser:  {PermBooksRead, PermProfileRead, PermProfileWrite},
+	}
+}
+
+// Allows reports whether role has been granted permission.
+func (p RolePolicy) Allows(role, permission string) bool {
+	for _, granted := range p[role] {
+		if granted == PermAll || granted == permission {
+			return true
+		}
+	}
+	return false
+}
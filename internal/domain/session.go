@@ -0,0 +1,24 @@
+package domain
+
+import "time"
+
+// Client describes the device a sign-in request came from.
+type Client struct {
+	IP        string
+	UserAgent string
+}
+
+// Session is a signed-in device. It starts at login and its ID is carried by
+// every access token and refresh token issued for it, so ending the session
+// signs the device out.
+type Session struct {
+	ID         string     `json:"id"`
+	UserID     int        `json:"user_id"`
+	UserAgent  string     `json:"user_agent"`
+	IP         string     `json:"ip"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	RevokedAt  *time.Time `json:"-"`
+	// Current marks the session of the caller in listings.
+	Current bool `json:"current"`
+}
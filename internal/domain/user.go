@@ -1,15 +1,11 @@
 package domain
 
-import (
-	"time"
-
-	"golang.org/x/crypto/bcrypt"
-)
+import "time"
 
 type User struct {
 	ID        int       `json:"id"`
 	Email     string    `json:"email" validate:"required,email"`
-	Password  string    `json:"password" validate:"required,min=8" hash:"bcrypt"`
+	Password  string    `json:"password" validate:"required" hash:"bcrypt"`
 	Username  string    `json:"username" validate:"required,min=3,max=20"`
 	FirstName string    `json:"first_name" validate:"required,min=3,max=20"`
 	LastName  string    `json:"last_name" validate:"required,min=3,max=20"`
@@ -17,6 +13,11 @@ type User struct {
 	IsActive  bool      `json:"is_active" default:"true"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+
+	EmailVerifiedAt  *time.Time `json:"email_verified_at,omitempty"`
+	SuspendedAt      *time.Time `json:"suspended_at,omitempty"`
+	SuspensionReason string     `json:"suspension_reason,omitempty"`
+	DeletedAt        *time.Time `json:"deleted_at,omitempty"`
 }
 
 type UpdateUserRequest struct {
@@ -25,11 +26,7 @@ type UpdateUserRequest struct {
 }
 
 type UpdatePasswordRequest struct {
-	NewPassword string `json:"new_password" validate:"required,min=8"`
-}
-
-
-
-func (u *User) ComparePassword(password string) error {
-	return bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password))
+	// CurrentPassword is required when users change their own password.
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password" validate:"required"`
 }
@@ -0,0 +1,17 @@
+package domain
+
+import "time"
+
+// UserStatusChange records an admin suspending or reactivating an account.
+type UserStatusChange struct {
+	ID        int64     `json:"id"`
+	UserID    int       `json:"user_id"`
+	Active    bool      `json:"active"`
+	Reason    string    `json:"reason"`
+	ChangedBy int       `json:"changed_by"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+type UserStatusRequest struct {
+	Reason string `json:"reason" validate:"required,max=500"`
+}
@@ -0,0 +1,24 @@
+package ports
+
+import (
+	"context"
+	"time"
+
+	"example.com/practice/fiber/internal/domain"
+)
+
+type APIKeyRepository interface {
+	// CreateAPIKey stores key and fills in its ID and CreatedAt.
+	CreateAPIKey(ctx context.Context, key *domain.APIKey) error
+	GetAPIKeyByPrefix(ctx context.Context, prefix string) (*domain.APIKey, error)
+	ListUserAPIKeys(ctx context.Context, userID int) ([]*domain.APIKey, error)
+	// RevokeAPIKey revokes key id of userID. It returns false when no such
+	// active key exists.
+	RevokeAPIKey(ctx context.Context, userID int, id int64) (bool, error)
+	TouchAPIKey(ctx context.Context, id int64, usedAt time.Time) error
+}
+
+// APIKeyAuthenticator resolves an API key to the caller it belongs to.
+type APIKeyAuthenticator interface {
+	AuthenticateAPIKey(ctx context.Context, key string) (*domain.Principal, error)
+}
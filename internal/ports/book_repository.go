@@ -2,14 +2,41 @@ package ports
 
 import (
 	"context"
+	"time"
 
 	"example.com/practice/fiber/internal/domain"
 )
 
+// BookRepository stores books. Deleted books go to the trash: every method
+// except the trash ones acts as if they did not exist.
 type BookRepository interface {
-	CreateBook(ctx context.Context, book *domain.Book) error
+	// CreateBook and UpdateBook return the book as stored, with its ID and
+	// timestamps.
+	CreateBook(ctx context.Context, book *domain.Book) (*domain.Book, error)
+	// GetBookByID returns nil when there is no book with id.
 	GetBookByID(ctx context.Context, id int) (*domain.Book, error)
-	GetAllBooks(ctx context.Context) ([]*domain.Book, error)
-	UpdateBook(ctx context.Context, book *domain.Book) error
-	DeleteBook(ctx context.Context, id int) error
+	// GetBookByISBN returns nil when no book has isbn13.
+	GetBookByISBN(ctx context.Context, isbn13 string) (*domain.Book, error)
+	// GetAllBooks returns up to query.Limit books matching query.Filter that
+	// come after query.After in the query's sort order.
+	GetAllBooks(ctx context.Context, query domain.BookQuery) ([]*domain.Book, error)
+	CountBooks(ctx context.Context, filter domain.BookFilter) (int, error)
+	// UpdateBook and DeleteBook only touch the book while it is at the given
+	// version; a zero version matches any. They return pkg.ErrBookNotFound
+	// when there is no book with the ID and pkg.ErrConflict when it is at
+	// another version. UpdateBook increments the version.
+	UpdateBook(ctx context.Context, book *domain.Book) (*domain.Book, error)
+	// UpdateBookFields writes only the named fields of book, by their JSON
+	// names, and otherwise behaves like UpdateBook.
+	UpdateBookFields(ctx context.Context, book *domain.Book, fields []string) (*domain.Book, error)
+	DeleteBook(ctx context.Context, id, version int) error
+	// GetDeletedBooks lists the trash, most recently deleted first.
+	GetDeletedBooks(ctx context.Context) ([]*domain.Book, error)
+	// RestoreBook takes book id out of the trash. It returns
+	// pkg.ErrBookNotFound when the book is not in the trash and
+	// pkg.ErrBookAlreadyExists when a live book has its ISBN.
+	RestoreBook(ctx context.Context, id int) (*domain.Book, error)
+	// PurgeDeletedBooks permanently deletes books that went to the trash
+	// before the given time and returns how many there were.
+	PurgeDeletedBooks(ctx context.Context, before time.Time) (int64, error)
 }
\ No newline at end of file
@@ -0,0 +1,17 @@
+package ports
+
+import (
+	"context"
+
+	"example.com/practice/fiber/internal/domain"
+)
+
+// BookSearch is a full-text index over the book catalog.
+type BookSearch interface {
+	// SearchBooks returns up to query.Limit books matching the words of
+	// query.Q in their title or author, best matches first.
+	SearchBooks(ctx context.Context, query domain.BookSearchQuery) ([]*domain.BookSearchHit, error)
+	// SuggestBooks returns up to limit titles and authors that look like q,
+	// tolerating typos.
+	SuggestBooks(ctx context.Context, q string, limit int) ([]string, error)
+}
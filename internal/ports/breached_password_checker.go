@@ -0,0 +1,9 @@
+package ports
+
+import "context"
+
+// BreachedPasswordChecker reports whether a password is known from a data
+// breach.
+type BreachedPasswordChecker interface {
+	IsBreached(ctx context.Context, password string) (bool, error)
+}
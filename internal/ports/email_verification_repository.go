@@ -0,0 +1,15 @@
+package ports
+
+import (
+	"context"
+
+	"example.com/practice/fiber/internal/domain"
+)
+
+type EmailVerificationRepository interface {
+	CreateEmailVerification(ctx context.Context, verification *domain.EmailVerification) error
+	GetEmailVerificationByHash(ctx context.Context, tokenHash string) (*domain.EmailVerification, error)
+	// MarkEmailVerificationUsed consumes the token. It returns false when the
+	// token had already been used.
+	MarkEmailVerificationUsed(ctx context.Context, id int64) (bool, error)
+}
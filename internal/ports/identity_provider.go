@@ -0,0 +1,17 @@
+package ports
+
+import (
+	"context"
+
+	"example.com/practice/fiber/internal/domain"
+)
+
+// IdentityProvider signs users in at an external OpenID Connect provider
+// using the authorization code flow with PKCE.
+type IdentityProvider interface {
+	// AuthCodeURL returns the provider URL the browser is redirected to.
+	AuthCodeURL(ctx context.Context, state, nonce, codeVerifier string) (string, error)
+	// Exchange redeems an authorization code and returns the verified
+	// identity from the ID token, which must carry nonce.
+	Exchange(ctx context.Context, code, codeVerifier, nonce string) (*domain.ExternalIdentity, error)
+}
@@ -0,0 +1,19 @@
+package ports
+
+import (
+	"context"
+
+	"example.com/practice/fiber/internal/domain"
+)
+
+type ImpersonationAuditRepository interface {
+	CreateImpersonationAuditEntry(ctx context.Context, entry *domain.ImpersonationAuditEntry) error
+	// ListImpersonationAuditEntries returns up to limit entries about userID,
+	// newest first.
+	ListImpersonationAuditEntries(ctx context.Context, userID int, limit int) ([]*domain.ImpersonationAuditEntry, error)
+}
+
+// ImpersonationAuditor records requests made with impersonation tokens.
+type ImpersonationAuditor interface {
+	AuditImpersonatedRequest(ctx context.Context, entry *domain.ImpersonationAuditEntry) error
+}
@@ -0,0 +1,8 @@
+package ports
+
+import "example.com/practice/fiber/internal/domain"
+
+// KeySet exposes the public keys that verify our tokens.
+type KeySet interface {
+	JWKS() domain.JSONWebKeySet
+}
@@ -0,0 +1,21 @@
+package ports
+
+import (
+	"context"
+	"time"
+
+	"example.com/practice/fiber/internal/domain"
+)
+
+type LoginAttemptStore interface {
+	GetLoginAttempt(ctx context.Context, key string) (*domain.LoginAttempt, error)
+	// RecordLoginFailure adds a failure for key and returns the updated
+	// record. The count starts over when neither the last failure nor the
+	// lock is later than resetBefore.
+	RecordLoginFailure(ctx context.Context, key string, now, resetBefore time.Time) (*domain.LoginAttempt, error)
+	LockLogin(ctx context.Context, key string, until time.Time) error
+	ResetLoginAttempts(ctx context.Context, key string) error
+	// DeleteStaleLoginAttempts removes records whose last failure and lock
+	// both ended before the given time.
+	DeleteStaleLoginAttempts(ctx context.Context, before time.Time) (int64, error)
+}
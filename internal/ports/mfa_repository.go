@@ -0,0 +1,24 @@
+package ports
+
+import (
+	"context"
+
+	"example.com/practice/fiber/internal/domain"
+)
+
+type MFARepository interface {
+	GetMFA(ctx context.Context, userID int) (*domain.MFA, error)
+	// SaveMFASecret stores a pending (not yet enabled) secret for userID. It
+	// returns false when 2FA is already enabled.
+	SaveMFASecret(ctx context.Context, userID int, secret string) (bool, error)
+	// EnableMFA enables the pending secret and replaces the recovery codes. It
+	// returns false when there is no pending secret.
+	EnableMFA(ctx context.Context, userID int, recoveryCodeHashes []string) (bool, error)
+	DisableMFA(ctx context.Context, userID int) error
+	// UpdateMFALastUsedStep records the TOTP step just used. It returns false
+	// when step is not newer than the last one, i.e. the code was replayed.
+	UpdateMFALastUsedStep(ctx context.Context, userID int, step int64) (bool, error)
+	// UseRecoveryCode consumes a recovery code. It returns false when the code
+	// does not exist or was already used.
+	UseRecoveryCode(ctx context.Context, userID int, codeHash string) (bool, error)
+}
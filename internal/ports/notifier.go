@@ -0,0 +1,11 @@
+package ports
+
+import (
+	"context"
+
+	"example.com/practice/fiber/internal/domain"
+)
+
+type Notifier interface {
+	Notify(ctx context.Context, notification domain.Notification) error
+}
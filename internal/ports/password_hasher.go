@@ -0,0 +1,13 @@
+package ports
+
+// PasswordHasher hashes passwords for storage and checks passwords against
+// stored hashes.
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	// Verify reports whether password matches hash. Hashes made with any
+	// supported algorithm are accepted, not only the configured one.
+	Verify(hash, password string) (bool, error)
+	// NeedsRehash reports whether hash was made with another algorithm or
+	// other parameters than the hasher currently uses.
+	NeedsRehash(hash string) bool
+}
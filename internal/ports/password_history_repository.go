@@ -0,0 +1,12 @@
+package ports
+
+import "context"
+
+// PasswordHistoryRepository keeps the hashes of passwords users have replaced.
+type PasswordHistoryRepository interface {
+	// AddPasswordHistory records hash for userID and keeps only the newest
+	// keep entries.
+	AddPasswordHistory(ctx context.Context, userID int, hash string, keep int) error
+	// ListPasswordHistory returns up to limit hashes, newest first.
+	ListPasswordHistory(ctx context.Context, userID int, limit int) ([]string, error)
+}
@@ -0,0 +1,17 @@
+package ports
+
+import (
+	"context"
+
+	"example.com/practice/fiber/internal/domain"
+)
+
+type PasswordResetRepository interface {
+	CreatePasswordReset(ctx context.Context, reset *domain.PasswordReset) error
+	GetPasswordResetByHash(ctx context.Context, tokenHash string) (*domain.PasswordReset, error)
+	// MarkPasswordResetUsed consumes the token. It returns false when the token
+	// had already been used.
+	MarkPasswordResetUsed(ctx context.Context, id int64) (bool, error)
+	// InvalidateUserPasswordResets consumes every outstanding token of userID.
+	InvalidateUserPasswordResets(ctx context.Context, userID int) error
+}
@@ -0,0 +1,29 @@
+package ports
+
+import (
+	"context"
+	"time"
+
+	"example.com/practice/fiber/internal/domain"
+)
+
+type SessionRepository interface {
+	CreateSession(ctx context.Context, session *domain.Session) error
+	GetSession(ctx context.Context, id string) (*domain.Session, error)
+	// ListUserSessions returns the sessions of userID that were not ended,
+	// most recently seen first.
+	ListUserSessions(ctx context.Context, userID int) ([]*domain.Session, error)
+	TouchSession(ctx context.Context, id string, seenAt time.Time) error
+	// RevokeSession ends a session of userID. It returns false when there is
+	// no such active session.
+	RevokeSession(ctx context.Context, userID int, id string) (bool, error)
+	RevokeUserSessions(ctx context.Context, userID int) error
+	// DeleteStaleSessions removes sessions last seen before before.
+	DeleteStaleSessions(ctx context.Context, before time.Time) (int64, error)
+}
+
+// SessionChecker tells the auth middleware whether the session a token was
+// issued for is still active.
+type SessionChecker interface {
+	CheckSession(ctx context.Context, userID int, sessionID string) (bool, error)
+}
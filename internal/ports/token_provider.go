@@ -1,6 +1,8 @@
 package ports
 
+import "example.com/practice/fiber/internal/domain"
+
 type TokenProvider interface {
-	GenerateToken(userId string) (string, error)
-	ValidateToken(token string) (string, error)
+	GenerateToken(claims domain.Claims) (string, error)
+	ValidateToken(token string) (*domain.Claims, error)
 }
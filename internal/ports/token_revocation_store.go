@@ -0,0 +1,17 @@
+package ports
+
+import (
+	"context"
+	"time"
+)
+
+// TokenRevocationStore keeps track of access tokens that must be rejected
+// before they expire. Entries are only needed until expiresAt and can be
+// removed afterwards with DeleteExpired.
+type TokenRevocationStore interface {
+	RevokeToken(ctx context.Context, tokenID string, expiresAt time.Time) error
+	// RevokeUserTokens revokes every token of userID issued at or before before.
+	RevokeUserTokens(ctx context.Context, userID int, before time.Time, expiresAt time.Time) error
+	IsRevoked(ctx context.Context, tokenID string, userID int, issuedAt time.Time) (bool, error)
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+}
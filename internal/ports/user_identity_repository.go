@@ -0,0 +1,25 @@
+package ports
+
+import (
+	"context"
+	"time"
+
+	"example.com/practice/fiber/internal/domain"
+)
+
+type UserIdentityRepository interface {
+	GetUserIdentity(ctx context.Context, provider, subject string) (*domain.UserIdentity, error)
+	CreateUserIdentity(ctx context.Context, identity *domain.UserIdentity) error
+	// CreateUserWithIdentity creates user and links identity to it in one
+	// transaction.
+	CreateUserWithIdentity(ctx context.Context, user *domain.User, identity *domain.UserIdentity) error
+	TouchUserIdentity(ctx context.Context, id int64, loginAt time.Time) error
+}
+
+type OAuthStateRepository interface {
+	CreateOAuthState(ctx context.Context, state *domain.OAuthState) error
+	// ConsumeOAuthState deletes and returns the state with stateHash, or nil
+	// when there is none.
+	ConsumeOAuthState(ctx context.Context, stateHash string) (*domain.OAuthState, error)
+	DeleteExpiredOAuthStates(ctx context.Context, before time.Time) (int64, error)
+}
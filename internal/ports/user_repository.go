@@ -2,16 +2,44 @@ package ports
 
 import (
 	"context"
+	"time"
 
 	"example.com/practice/fiber/internal/domain"
 )
 
+// UserRepository stores users. Deleted users go to the trash: every method
+// except the trash ones acts as if they did not exist.
 type UserRepository interface {
-	CreateUser(ctx context.Context, user *domain.User) error
+	// CreateUser and UpdateUser return the user as stored, with its ID and
+	// timestamps.
+	CreateUser(ctx context.Context, user *domain.User) (*domain.User, error)
 	GetUserByID(ctx context.Context, id string) (*domain.User, error)
 	GetUserByEmail(ctx context.Context, email string) (*domain.User, error)
 	GetAllUsers(ctx context.Context) ([]*domain.User, error)
-	UpdateUser(ctx context.Context, user *domain.User) error
+	// UpdateUser writes the names of user.ID. It returns pkg.ErrUserNotFound
+	// when there is no such user.
+	UpdateUser(ctx context.Context, user *domain.User) (*domain.User, error)
+	// UpdateUserFields writes only the named fields of user, by their JSON
+	// names; only first_name and last_name can be written this way.
+	UpdateUserFields(ctx context.Context, user *domain.User, fields []string) (*domain.User, error)
+	// DeleteUser moves user id to the trash. It returns pkg.ErrUserNotFound
+	// when there is no such user.
 	DeleteUser(ctx context.Context, id int) error
 	UpdatePassword(ctx context.Context, id int, password string) error
+	// RehashPassword replaces the password hash of id only while it is still
+	// oldHash, so a concurrent password change is not overwritten.
+	RehashPassword(ctx context.Context, id int, oldHash, newHash string) error
+	MarkEmailVerified(ctx context.Context, id int) error
+	// SetUserStatus activates or suspends change.UserID and records change.
+	SetUserStatus(ctx context.Context, change *domain.UserStatusChange) error
+	// GetDeletedUsers lists the trash, most recently deleted first.
+	GetDeletedUsers(ctx context.Context) ([]*domain.User, error)
+	// RestoreUser takes user id out of the trash. It returns
+	// pkg.ErrUserNotFound when the user is not in the trash and
+	// pkg.ErrUserAlreadyExists when a live user has its email or username.
+	RestoreUser(ctx context.Context, id int) (*domain.User, error)
+	// PurgeDeletedUsers permanently deletes users that went to the trash
+	// before the given time, with everything that references them, and
+	// returns how many there were.
+	PurgeDeletedUsers(ctx context.Context, before time.Time) (int64, error)
 }
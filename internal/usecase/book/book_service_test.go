@@ -3,41 +3,119 @@ package usecase
 import (
 	"context"
 	"errors"
-	"fmt"
+	"reflect"
 	"testing"
 	"time"
 
 	"example.com/practice/fiber/internal/domain"
+	"example.com/practice/fiber/pkg"
 )
 
 type mockBookRepository struct {
 	books domain.Book
 	err   error
+	// list, when set, is returned by GetAllBooks instead of books.
+	list      []*domain.Book
+	lastQuery domain.BookQuery
+	byISBN    map[string]*domain.Book
+	// missing makes GetBookByID find nothing.
+	missing bool
+	// fields are the fields UpdateBookFields last wrote.
+	fields []string
+	// concurrentWrites makes that many UpdateBookFields calls find the book
+	// changed since it was read.
+	concurrentWrites int
+	// purgedBefore is the cutoff PurgeDeletedBooks was last called with.
+	purgedBefore time.Time
 }
 
-func (m *mockBookRepository) CreateBook(ctx context.Context, book *domain.Book) error {
-	return m.err
+func (m *mockBookRepository) CreateBook(ctx context.Context, book *domain.Book) (*domain.Book, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	stored := *book
+	stored.ID = 1
+	return &stored, nil
 }
 
 func (m *mockBookRepository) GetBookByID(ctx context.Context, id int) (*domain.Book, error) {
+	if m.missing {
+		return nil, m.err
+	}
 	return &m.books, m.err
 }
 
-func (m *mockBookRepository) GetAllBooks(ctx context.Context) ([]*domain.Book, error) {
+func (m *mockBookRepository) GetBookByISBN(ctx context.Context, isbn13 string) (*domain.Book, error) {
+	return m.byISBN[isbn13], nil
+}
+
+func (m *mockBookRepository) GetAllBooks(ctx context.Context, query domain.BookQuery) ([]*domain.Book, error) {
+	m.lastQuery = query
 	if m.err != nil {
 		return []*domain.Book{}, m.err
 	}
+	if m.list != nil {
+		if len(m.list) > query.Limit {
+			return m.list[:query.Limit], nil
+		}
+		return m.list, nil
+	}
 	return []*domain.Book{&m.books}, nil
 }
 
-func (m *mockBookRepository) UpdateBook(ctx context.Context, book *domain.Book) error {
-	return m.err
+func (m *mockBookRepository) CountBooks(ctx context.Context, filter domain.BookFilter) (int, error) {
+	if m.list != nil {
+		return len(m.list), m.err
+	}
+	return 1, m.err
+}
+
+func (m *mockBookRepository) UpdateBook(ctx context.Context, book *domain.Book) (*domain.Book, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	if book.Version != 0 && book.Version != m.books.Version {
+		return nil, pkg.ErrConflict
+	}
+	stored := *book
+	stored.Version = m.books.Version + 1
+	return &stored, nil
 }
 
-func (m *mockBookRepository) DeleteBook(ctx context.Context, id int) error {
+func (m *mockBookRepository) UpdateBookFields(ctx context.Context, book *domain.Book, fields []string) (*domain.Book, error) {
+	m.fields = fields
+	if m.concurrentWrites > 0 {
+		m.concurrentWrites--
+		m.books.Version++
+	}
+	return m.UpdateBook(ctx, book)
+}
+
+func (m *mockBookRepository) DeleteBook(ctx context.Context, id, version int) error {
+	if m.err == nil && version != 0 && version != m.books.Version {
+		return pkg.ErrConflict
+	}
 	return m.err
 }
 
+func (m *mockBookRepository) GetDeletedBooks(ctx context.Context) ([]*domain.Book, error) {
+	return m.list, m.err
+}
+
+func (m *mockBookRepository) RestoreBook(ctx context.Context, id int) (*domain.Book, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	restored := m.books
+	restored.DeletedAt = nil
+	return &restored, nil
+}
+
+func (m *mockBookRepository) PurgeDeletedBooks(ctx context.Context, before time.Time) (int64, error) {
+	m.purgedBefore = before
+	return 1, m.err
+}
+
 func TestCreateBook(t *testing.T) {
 	repo := &mockBookRepository{}
 	service := NewBookService(repo)
@@ -45,18 +123,100 @@ func TestCreateBook(t *testing.T) {
 	book := &domain.Book{
 		Title:     "Test Book",
 		Author:    "Test Author",
-		Price:     100,
+		Price:     domain.Money{Amount: 100, Currency: "USD"},
 		Stock:     10,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
 
-	err := service.CreateBook(context.Background(), book)
+	_, err := service.CreateBook(context.Background(), book)
 	if err != nil {
 		t.Errorf("CreateBook() error = %v, wantErr %v", err, repo.err)
 	}
 }
 
+// invalidFields returns the rule each invalid field of err broke.
+func invalidFields(t *testing.T, err error) map[string]string {
+	t.Helper()
+	var invalid *pkg.ValidationError
+	if !errors.As(err, &invalid) {
+		t.Fatalf("error = %v, want a *pkg.ValidationError", err)
+	}
+	fields := map[string]string{}
+	for _, field := range invalid.Fields {
+		fields[field.Field] = field.Rule
+	}
+	return fields
+}
+
+func TestCreateBook_Validation(t *testing.T) {
+	repo := &mockBookRepository{}
+	service := NewBookService(repo)
+
+	book := &domain.Book{
+		Author:    "Test Author",
+		Price:     domain.Money{Amount: -1, Currency: "USD"},
+		Stock:     -1,
+		ISBN13:    "978-0-306-40615-8",
+		Language:  "not a language",
+		PageCount: -5,
+	}
+	want := map[string]string{"title": "required", "price": "gte", "stock": "gte", "isbn13": "isbn", "language": "bcp47_language_tag", "page_count": "gte"}
+	_, createErr := service.CreateBook(context.Background(), book)
+	_, updateErr := service.UpdateBook(context.Background(), book)
+	for name, err := range map[string]error{"CreateBook": createErr, "UpdateBook": updateErr} {
+		if got := invalidFields(t, err); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s() invalid fields = %v, want %v", name, got, want)
+		}
+		if !errors.Is(err, pkg.ErrValidationError) {
+			t.Errorf("%s() error should wrap %v", name, pkg.ErrValidationError)
+		}
+	}
+
+	for _, currency := range []string{"", "XYZ"} {
+		book := &domain.Book{Title: "Test Book", Author: "Test Author", Price: domain.Money{Amount: 100, Currency: currency}}
+		_, err := service.CreateBook(context.Background(), book)
+		if got := invalidFields(t, err); got["price"] != "currency" {
+			t.Errorf("CreateBook() with currency %q invalid fields = %v", currency, got)
+		}
+	}
+}
+
+func TestBookService_NotFound(t *testing.T) {
+	service := NewBookService(&mockBookRepository{missing: true})
+	if _, err := service.GetBookByID(context.Background(), 1); err != pkg.ErrBookNotFound {
+		t.Errorf("GetBookByID() error = %v, want %v", err, pkg.ErrBookNotFound)
+	}
+}
+
+func TestCreateBook_ISBN(t *testing.T) {
+	repo := &mockBookRepository{byISBN: map[string]*domain.Book{"9780306406157": {ID: 3}}}
+	service := NewBookService(repo)
+	price := domain.Money{Amount: 100, Currency: "USD"}
+
+	book := &domain.Book{Title: "Test Book", Author: "Test Author", Price: price, ISBN13: "0-8044-2957-X"}
+	if _, err := service.CreateBook(context.Background(), book); err != nil {
+		t.Fatalf("CreateBook() error = %v", err)
+	}
+	if book.ISBN13 != "9780804429573" {
+		t.Errorf("CreateBook() ISBN13 = %q, want the ISBN-13 form", book.ISBN13)
+	}
+
+	book = &domain.Book{Title: "Test Book", Author: "Test Author", Price: price, ISBN13: "0306406152"}
+	if _, err := service.CreateBook(context.Background(), book); err != pkg.ErrBookAlreadyExists {
+		t.Errorf("CreateBook() error = %v, want %v", err, pkg.ErrBookAlreadyExists)
+	}
+	// The book keeps its own ISBN on update
+	book.ID = 3
+	if _, err := service.UpdateBook(context.Background(), book); err != nil {
+		t.Errorf("UpdateBook() error = %v", err)
+	}
+	book.ID = 4
+	if _, err := service.UpdateBook(context.Background(), book); err != pkg.ErrBookAlreadyExists {
+		t.Errorf("UpdateBook() error = %v, want %v", err, pkg.ErrBookAlreadyExists)
+	}
+}
+
 func TestCreateBook_Error(t *testing.T) {
 	repo := &mockBookRepository{err: errors.New("test error")}
 	service := NewBookService(repo)
@@ -64,13 +224,13 @@ func TestCreateBook_Error(t *testing.T) {
 	book := &domain.Book{
 		Title:     "Test Book",
 		Author:    "Test Author",
-		Price:     100,
+		Price:     domain.Money{Amount: 100, Currency: "USD"},
 		Stock:     10,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
 
-	err := service.CreateBook(context.Background(), book)
+	_, err := service.CreateBook(context.Background(), book)
 	if err == nil {
 		t.Errorf("CreateBook() error = %v, wantErr %v", err, repo.err)
 	}
@@ -81,7 +241,7 @@ func TestGetBookByID(t *testing.T) {
 		ID:        1,
 		Title:     "Test Book",
 		Author:    "Test Author",
-		Price:     100,
+		Price:     domain.Money{Amount: 100, Currency: "USD"},
 		Stock:     10,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
@@ -102,11 +262,10 @@ func TestGetBookByID_Error(t *testing.T) {
 	service := NewBookService(repo)
 
 	book, err := service.GetBookByID(context.Background(), 1)
-	fmt.Println(book.ID)
 	if err == nil {
 		t.Errorf("GetBookByID() error = %v, wantErr %v", err, repo.err)
 	}
-	if book.ID != 0 {
+	if book != nil {
 		t.Errorf("GetBookByID() book = %v, want nil", book)
 	}
 }
@@ -116,17 +275,18 @@ func TestGetAllBooks(t *testing.T) {
 		ID:        1,
 		Title:     "Test Book",
 		Author:    "Test Author",
-		Price:     100,
+		Price:     domain.Money{Amount: 100, Currency: "USD"},
 		Stock:     10,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}}
 	service := NewBookService(repo)
 
-	books, err := service.GetAllBooks(context.Background())
+	page, err := service.GetAllBooks(context.Background(), domain.BookQuery{})
 	if err != nil {
-		t.Errorf("GetAllBooks() error = %v, wantErr %v", err, repo.err)
+		t.Fatalf("GetAllBooks() error = %v, wantErr %v", err, repo.err)
 	}
+	books := page.Books
 	if len(books) != 1 {
 		t.Errorf("GetAllBooks() len(books) = %v, want %v", len(books), 1)
 	}
@@ -139,12 +299,12 @@ func TestGetAllBooks_Error(t *testing.T) {
 	repo := &mockBookRepository{books: domain.Book{}, err: errors.New("test error")}
 	service := NewBookService(repo)
 
-	books, err := service.GetAllBooks(context.Background())
+	page, err := service.GetAllBooks(context.Background(), domain.BookQuery{})
 	if err == nil {
 		t.Errorf("GetAllBooks() error = %v, wantErr %v", err, repo.err)
 	}
-	if len(books) != 0 {
-		t.Errorf("GetAllBooks() len(books) = %v, want %v", len(books), 0)
+	if page != nil {
+		t.Errorf("GetAllBooks() page = %v, want nil", page)
 	}
 }
 
@@ -156,13 +316,13 @@ func TestUpdateBook(t *testing.T) {
 		ID:        1,
 		Title:     "Test Book",
 		Author:    "Test Author",
-		Price:     100,
+		Price:     domain.Money{Amount: 100, Currency: "USD"},
 		Stock:     10,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
 
-	err := service.UpdateBook(context.Background(), book)
+	_, err := service.UpdateBook(context.Background(), book)
 	if err != nil {
 		t.Errorf("UpdateBook() error = %v, wantErr %v", err, repo.err)
 	}
@@ -176,13 +336,13 @@ func TestUpdateBook_Error(t *testing.T) {
 		ID:        1,
 		Title:     "Test Book",
 		Author:    "Test Author",
-		Price:     100,
+		Price:     domain.Money{Amount: 100, Currency: "USD"},
 		Stock:     10,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
 
-	err := service.UpdateBook(context.Background(), book)
+	_, err := service.UpdateBook(context.Background(), book)
 	if err == nil {
 		t.Errorf("UpdateBook() error = %v, wantErr %v", err, repo.err)
 	}
@@ -192,7 +352,7 @@ func TestDeleteBook(t *testing.T) {
 	repo := &mockBookRepository{}
 	service := NewBookService(repo)
 
-	err := service.DeleteBook(context.Background(), 1)
+	err := service.DeleteBook(context.Background(), 1, 0)
 	if err != nil {
 		t.Errorf("DeleteBook() error = %v, wantErr %v", err, repo.err)
 	}
@@ -202,8 +362,273 @@ func TestDeleteBook_Error(t *testing.T) {
 	repo := &mockBookRepository{err: errors.New("test error")}
 	service := NewBookService(repo)
 
-	err := service.DeleteBook(context.Background(), 1)
+	err := service.DeleteBook(context.Background(), 1, 0)
 	if err == nil {
 		t.Errorf("DeleteBook() error = %v, wantErr %v", err, repo.err)
 	}
 }
+
+func TestBookService_Version(t *testing.T) {
+	repo := &mockBookRepository{books: domain.Book{ID: 1, Version: 3}}
+	service := NewBookService(repo)
+	book := &domain.Book{ID: 1, Title: "Test Book", Author: "Test Author", Price: domain.Money{Amount: 100, Currency: "USD"}}
+
+	for version, want := range map[int]error{0: nil, 3: nil, 2: pkg.ErrConflict} {
+		book.Version = version
+		updated, err := service.UpdateBook(context.Background(), book)
+		if err != want {
+			t.Errorf("UpdateBook() at version %d error = %v, want %v", version, err, want)
+		}
+		if err == nil && updated.Version != 4 {
+			t.Errorf("UpdateBook() at version %d stored version %d, want 4", version, updated.Version)
+		}
+		if err := service.DeleteBook(context.Background(), 1, version); err != want {
+			t.Errorf("DeleteBook() at version %d error = %v, want %v", version, err, want)
+		}
+	}
+	if err := service.DeleteBook(context.Background(), 1, -1); err != pkg.ErrValidationError {
+		t.Errorf("DeleteBook() with a negative version error = %v, want %v", err, pkg.ErrValidationError)
+	}
+}
+
+func TestPatchBook(t *testing.T) {
+	published := domain.NewDate(2001, time.February, 3)
+	stored := domain.Book{ID: 1, Title: "Test Book", Author: "Test Author", Price: domain.Money{Amount: 1000, Currency: "USD"},
+		Stock: 7, Published: &published, Version: 2}
+	merge := func(body string) domain.Patch { return domain.Patch{Type: domain.MergePatchType, Body: []byte(body)} }
+	jsonPatch := func(body string) domain.Patch { return domain.Patch{Type: domain.JSONPatchType, Body: []byte(body)} }
+
+	cases := []struct {
+		name    string
+		patch   domain.Patch
+		version int
+		fields  []string
+		err     error
+	}{
+		{"merge price", merge(`{"price":{"amount":"12.50"}}`), 0, []string{"price"}, nil},
+		{"merge remove date", merge(`{"published":null,"title":"New"}`), 2, []string{"title", "published"}, nil},
+		{"merge no change", merge(`{"stock":7,"id":5,"version":9}`), 0, nil, nil},
+		{"merge required", merge(`{"title":null}`), 0, nil, pkg.ErrValidationError},
+		{"merge unknown field", merge(`{"subtitle":"x"}`), 0, nil, pkg.ErrValidationError},
+		{"merge wrong type", merge(`{"stock":"many"}`), 0, nil, pkg.ErrValidationError},
+		{"merge malformed", merge(`{"stock":`), 0, nil, pkg.ErrInvalidPatch},
+		{"stale version", merge(`{"stock":8}`), 1, nil, pkg.ErrConflict},
+		{"patch ops", jsonPatch(`[
+			{"op":"test","path":"/stock","value":7.0},
+			{"op":"replace","path":"/stock","value":9},
+			{"op":"copy","from":"/title","path":"/publisher"},
+			{"op":"add","path":"/language","value":"en"},
+			{"op":"move","from":"/language","path":"/language"}
+		]`), 2, []string{"stock", "publisher", "language"}, nil},
+		{"patch escaped pointer", jsonPatch(`[{"op":"add","path":"/a~1b","value":1}]`), 0, nil, pkg.ErrValidationError},
+		{"patch failed test", jsonPatch(`[{"op":"test","path":"/stock","value":8}]`), 0, nil, pkg.ErrPatchFailed},
+		{"patch missing path", jsonPatch(`[{"op":"remove","path":"/isbn13"}]`), 0, nil, pkg.ErrPatchFailed},
+		{"patch move into child", jsonPatch(`[{"op":"move","from":"/price","path":"/price/amount"}]`), 0, nil, pkg.ErrPatchFailed},
+		{"patch unknown op", jsonPatch(`[{"op":"merge","path":"/stock","value":1}]`), 0, nil, pkg.ErrInvalidPatch},
+		{"patch missing value", jsonPatch(`[{"op":"add","path":"/stock"}]`), 0, nil, pkg.ErrInvalidPatch},
+		{"patch bad pointer", jsonPatch(`[{"op":"remove","path":"stock"}]`), 0, nil, pkg.ErrInvalidPatch},
+		{"unsupported type", domain.Patch{Type: "application/json", Body: []byte(`{}`)}, 0, nil, pkg.ErrUnsupportedPatch},
+	}
+	for _, tc := range cases {
+		current := stored
+		repo := &mockBookRepository{books: current}
+		service := NewBookService(repo)
+		book, err := service.PatchBook(context.Background(), 1, tc.version, tc.patch)
+		if !errors.Is(err, tc.err) {
+			t.Errorf("%s: PatchBook() error = %v, want %v", tc.name, err, tc.err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if !reflect.DeepEqual(repo.fields, tc.fields) {
+			t.Errorf("%s: PatchBook() wrote %v, want %v", tc.name, repo.fields, tc.fields)
+		}
+		if book.ID != 1 || book.Author != "Test Author" {
+			t.Errorf("%s: PatchBook() = %#v", tc.name, book)
+		}
+	}
+
+	service := NewBookService(&mockBookRepository{books: stored})
+	book, err := service.PatchBook(context.Background(), 1, 0, merge(`{"price":{"amount":"12.50"}}`))
+	if err != nil || book.Price != (domain.Money{Amount: 1250, Currency: "USD"}) || book.Stock != 7 || book.Version != 3 {
+		t.Errorf("PatchBook() = %#v, %v", book, err)
+	}
+}
+
+func TestPatchBook_ConcurrentWrite(t *testing.T) {
+	stored := domain.Book{ID: 1, Title: "Test Book", Author: "Test Author", Price: domain.Money{Amount: 1000, Currency: "USD"},
+		Stock: 5, Version: 2}
+	patch := domain.Patch{Type: domain.JSONPatchType, Body: []byte(`[
+		{"op":"test","path":"/stock","value":5},
+		{"op":"replace","path":"/stock","value":4}
+	]`)}
+
+	repo := &mockBookRepository{books: stored, concurrentWrites: 1}
+	book, err := NewBookService(repo).PatchBook(context.Background(), 1, 0, patch)
+	if err != nil || book.Version != 4 {
+		t.Errorf("PatchBook() should retry on the changed book, got %#v, %v", book, err)
+	}
+
+	repo = &mockBookRepository{books: stored, concurrentWrites: patchAttempts}
+	if _, err := NewBookService(repo).PatchBook(context.Background(), 1, 0, patch); err != pkg.ErrEditConflict {
+		t.Errorf("PatchBook() error = %v, want %v", err, pkg.ErrEditConflict)
+	}
+
+	repo = &mockBookRepository{books: stored, concurrentWrites: 1}
+	if _, err := NewBookService(repo).PatchBook(context.Background(), 1, 2, patch); err != pkg.ErrConflict {
+		t.Errorf("PatchBook() with a version error = %v, want %v", err, pkg.ErrConflict)
+	}
+}
+
+func TestPurgeDeletedBooks(t *testing.T) {
+	repo := &mockBookRepository{}
+	if n, err := NewBookService(repo).PurgeDeletedBooks(context.Background()); n != 0 || err != nil || !repo.purgedBefore.IsZero() {
+		t.Fatalf("purge without retention should do nothing, got %d, %v", n, err)
+	}
+
+	for retention, want := range map[time.Duration]time.Duration{0: 30 * 24 * time.Hour, 48 * time.Hour: 48 * time.Hour} {
+		service := NewBookService(repo, WithTrashRetention(retention))
+		start := time.Now()
+		if _, err := service.PurgeDeletedBooks(context.Background()); err != nil {
+			t.Fatalf("PurgeDeletedBooks: %v", err)
+		}
+		if age := start.Sub(repo.purgedBefore); age > want || age < want-time.Minute {
+			t.Errorf("retention %v: expected cutoff %v ago, got %v", retention, want, age)
+		}
+	}
+}
+
+func TestGetAllBooks_Pagination(t *testing.T) {
+	repo := &mockBookRepository{list: []*domain.Book{
+		{ID: 1, Title: "A", Price: domain.Money{Amount: 300, Currency: "USD"}},
+		{ID: 2, Title: "B", Price: domain.Money{Amount: 100, Currency: "USD"}},
+		{ID: 3, Title: "C", Price: domain.Money{Amount: 200, Currency: "USD"}},
+	}}
+	service := NewBookService(repo)
+
+	page, err := service.GetAllBooks(context.Background(), domain.BookQuery{Sort: domain.BookSortPrice, Desc: true, Limit: 2})
+	if err != nil {
+		t.Fatalf("GetAllBooks() error = %v", err)
+	}
+	if len(page.Books) != 2 || page.Total != 3 || page.NextCursor == "" {
+		t.Fatalf("GetAllBooks() page = %+v, want 2 books of 3 and a next cursor", page)
+	}
+	if repo.lastQuery.Limit != 3 {
+		t.Errorf("GetAllBooks() should ask for one extra book, asked for %d", repo.lastQuery.Limit)
+	}
+
+	_, err = service.GetAllBooks(context.Background(), domain.BookQuery{Sort: domain.BookSortPrice, Desc: true, Limit: 2, Cursor: page.NextCursor})
+	if err != nil {
+		t.Fatalf("GetAllBooks() with cursor error = %v", err)
+	}
+	after := repo.lastQuery.After
+	if after == nil || after.ID != 2 || after.Value != "1.00" {
+		t.Errorf("GetAllBooks() cursor = %+v, want the last book of the first page", after)
+	}
+
+	// A cursor only continues the sort order it was issued for
+	_, err = service.GetAllBooks(context.Background(), domain.BookQuery{Sort: domain.BookSortTitle, Limit: 2, Cursor: page.NextCursor})
+	if err != pkg.ErrInvalidCursor {
+		t.Errorf("GetAllBooks() error = %v, want %v", err, pkg.ErrInvalidCursor)
+	}
+	_, err = service.GetAllBooks(context.Background(), domain.BookQuery{Cursor: "not a cursor"})
+	if err != pkg.ErrInvalidCursor {
+		t.Errorf("GetAllBooks() error = %v, want %v", err, pkg.ErrInvalidCursor)
+	}
+}
+
+func TestGetAllBooks_TamperedCursor(t *testing.T) {
+	service := NewBookService(&mockBookRepository{})
+	cases := []struct {
+		sort  string
+		value string
+		valid bool
+	}{
+		{domain.BookSortPrice, "12.50", true},
+		{domain.BookSortPrice, "0", true},
+		{domain.BookSortPrice, "abc", false},
+		{domain.BookSortPrice, "1e5", false},
+		{domain.BookSortPrice, "1.", false},
+		{domain.BookSortPrice, "", false},
+		{domain.BookSortCreatedAt, "2024-01-02T03:04:05.123456Z", true},
+		{domain.BookSortCreatedAt, "yesterday", false},
+		{domain.BookSortTitle, "Any title", true},
+		{domain.BookSortTitle, "bad\x00title", false},
+	}
+	for _, tc := range cases {
+		cursor := encodeBookCursor(&domain.BookCursor{Sort: tc.sort, Value: tc.value, ID: 1})
+		_, err := service.GetAllBooks(context.Background(), domain.BookQuery{Sort: tc.sort, Cursor: cursor})
+		if (err == nil) != tc.valid || (err != nil && err != pkg.ErrInvalidCursor) {
+			t.Errorf("GetAllBooks() with %s cursor %q error = %v, want valid %v", tc.sort, tc.value, err, tc.valid)
+		}
+	}
+}
+
+func TestGetAllBooks_InvalidQuery(t *testing.T) {
+	service := NewBookService(&mockBookRepository{})
+	minPrice := domain.Money{Amount: 500, Currency: "USD"}
+	maxPrice := domain.Money{Amount: 100, Currency: "USD"}
+	queries := []domain.BookQuery{
+		{Sort: "stock"},
+		{Limit: 101},
+		{Limit: -1},
+		{Filter: domain.BookFilter{Currency: "USD", MinPrice: &minPrice, MaxPrice: &maxPrice}},
+		{Filter: domain.BookFilter{MinPrice: &maxPrice}},
+		{Filter: domain.BookFilter{Currency: "XYZ"}},
+	}
+	for _, query := range queries {
+		if _, err := service.GetAllBooks(context.Background(), query); err != pkg.ErrValidationError {
+			t.Errorf("GetAllBooks(%+v) error = %v, want %v", query, err, pkg.ErrValidationError)
+		}
+	}
+}
+
+type mockBookSearch struct {
+	hits        []*domain.BookSearchHit
+	suggestions []string
+	lastQuery   domain.BookSearchQuery
+	suggested   bool
+}
+
+func (m *mockBookSearch) SearchBooks(ctx context.Context, query domain.BookSearchQuery) ([]*domain.BookSearchHit, error) {
+	m.lastQuery = query
+	return m.hits, nil
+}
+
+func (m *mockBookSearch) SuggestBooks(ctx context.Context, q string, limit int) ([]string, error) {
+	m.suggested = true
+	return m.suggestions, nil
+}
+
+func TestSearchBooks(t *testing.T) {
+	search := &mockBookSearch{hits: []*domain.BookSearchHit{{Book: &domain.Book{ID: 1}}}, suggestions: []string{"Dune"}}
+	service := NewBookService(&mockBookRepository{}, WithSearch(search))
+
+	result, err := service.SearchBooks(context.Background(), domain.BookSearchQuery{Q: "  dune  "})
+	if err != nil {
+		t.Fatalf("SearchBooks() error = %v", err)
+	}
+	if search.lastQuery.Q != "dune" || search.lastQuery.Limit != 20 {
+		t.Errorf("SearchBooks() query = %+v, want trimmed text and default limit", search.lastQuery)
+	}
+	if len(result.Hits) != 1 || search.suggested {
+		t.Errorf("SearchBooks() should only suggest when nothing matched, got %+v", result)
+	}
+
+	search.hits = []*domain.BookSearchHit{}
+	result, err = service.SearchBooks(context.Background(), domain.BookSearchQuery{Q: "dnue"})
+	if err != nil {
+		t.Fatalf("SearchBooks() error = %v", err)
+	}
+	if len(result.Suggestions) != 1 || result.Suggestions[0] != "Dune" {
+		t.Errorf("SearchBooks() suggestions = %v, want [Dune]", result.Suggestions)
+	}
+
+	if _, err := service.SearchBooks(context.Background(), domain.BookSearchQuery{Q: " "}); err != pkg.ErrValidationError {
+		t.Errorf("SearchBooks() error = %v, want %v", err, pkg.ErrValidationError)
+	}
+	if _, err := NewBookService(&mockBookRepository{}).SearchBooks(context.Background(), domain.BookSearchQuery{Q: "dune"}); err != pkg.ErrFeatureDisabled {
+		t.Errorf("SearchBooks() error = %v, want %v", err, pkg.ErrFeatureDisabled)
+	}
+}
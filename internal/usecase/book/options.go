@@ -0,0 +1,28 @@
+package usecase
+
+import (
+	"time"
+
+	"example.com/practice/fiber/internal/ports"
+)
+
+// Option configures optional collaborators of BookService.
+type Option func(*BookService)
+
+// WithSearch enables full-text search over the catalog.
+func WithSearch(search ports.BookSearch) Option {
+	return func(s *BookService) {
+		s.search = search
+	}
+}
+
+// WithTrashRetention keeps deleted books for retention, 30 days by default,
+// before PurgeDeletedBooks removes them for good.
+func WithTrashRetention(retention time.Duration) Option {
+	return func(s *BookService) {
+		if retention == 0 {
+			retention = defaultTrashRetention
+		}
+		s.retention = retention
+	}
+}
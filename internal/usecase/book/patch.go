@@ -0,0 +1,97 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+
+	domains "example.com/practice/fiber/internal/domain"
+	"example.com/practice/fiber/pkg"
+)
+
+// patchAttempts bounds how often PatchBook re-reads a book that changed
+// while a patch without a version was being applied.
+const patchAttempts = 3
+
+// PatchBook applies patch to book id and stores the fields it changed. The
+// patched book is validated like a full update; its ID, version and
+// timestamps cannot be patched. Like UpdateBook, a non-zero version must
+// match the stored one or PatchBook returns pkg.ErrConflict.
+//
+// The write is always conditional on the version the patch was applied to.
+// Without a version, PatchBook applies the patch again to a book that changed
+// in between, so JSON Patch "test" operations never pass on stale data, and
+// gives up with pkg.ErrEditConflict after patchAttempts tries.
+func (s *BookService) PatchBook(ctx context.Context, id, version int, patch domains.Patch) (*domains.Book, error) {
+	if !patch.Supported() {
+		return nil, pkg.ErrUnsupportedPatch
+	}
+	for attempt := 1; ; attempt++ {
+		book, err := s.patchBook(ctx, id, version, patch)
+		if err != pkg.ErrConflict || version != 0 {
+			return book, err
+		}
+		if attempt == patchAttempts {
+			return nil, pkg.ErrEditConflict
+		}
+	}
+}
+
+func (s *BookService) patchBook(ctx context.Context, id, version int, patch domains.Patch) (*domains.Book, error) {
+	current, err := s.GetBookByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if version != 0 && version != current.Version {
+		return nil, pkg.ErrConflict
+	}
+	doc, err := json.Marshal(current)
+	if err != nil {
+		return nil, err
+	}
+	if doc, err = patch.Apply(doc); err != nil {
+		return nil, err
+	}
+	patched := new(domains.Book)
+	if err := pkg.DecodeJSON(doc, patched); err != nil {
+		return nil, err
+	}
+	patched.ID, patched.Version = current.ID, current.Version
+	patched.CreatedAt, patched.UpdatedAt = current.CreatedAt, current.UpdatedAt
+	patched.DeletedAt = current.DeletedAt
+	if err := s.checkBook(ctx, patched); err != nil {
+		return nil, err
+	}
+	fields := changedBookFields(current, patched)
+	if len(fields) == 0 {
+		return current, nil
+	}
+	return s.repo.UpdateBookFields(ctx, patched, fields)
+}
+
+// changedBookFields returns the JSON names of the writable fields that differ
+// between before and after.
+func changedBookFields(before, after *domains.Book) []string {
+	var fields []string
+	changed := func(field string, differs bool) {
+		if differs {
+			fields = append(fields, field)
+		}
+	}
+	changed("title", before.Title != after.Title)
+	changed("author", before.Author != after.Author)
+	changed("price", before.Price != after.Price)
+	changed("stock", before.Stock != after.Stock)
+	changed("published", !sameDate(before.Published, after.Published))
+	changed("isbn13", before.ISBN13 != after.ISBN13)
+	changed("publisher", before.Publisher != after.Publisher)
+	changed("language", before.Language != after.Language)
+	changed("page_count", before.PageCount != after.PageCount)
+	return fields
+}
+
+func sameDate(a, b *domains.Date) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(b.Time)
+}
@@ -2,35 +2,270 @@ package usecase
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
 
 	domains "example.com/practice/fiber/internal/domain"
 	"example.com/practice/fiber/internal/ports"
+	"example.com/practice/fiber/pkg"
+)
+
+const (
+	defaultBookPageSize = 20
+	maxBookPageSize     = 100
+	// maxSearchQueryLength bounds the query text in bytes.
+	maxSearchQueryLength = 200
+	maxBookSuggestions   = 5
 )
 
 type BookService struct {
-	repo ports.BookRepository
+	repo      ports.BookRepository
+	search    ports.BookSearch
+	retention time.Duration
 }
 
-func NewBookService(repo ports.BookRepository) *BookService {
-	return &BookService{repo: repo}
+func NewBookService(repo ports.BookRepository, opts ...Option) *BookService {
+	s := &BookService{repo: repo}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
-func (s *BookService) CreateBook(ctx context.Context, book *domains.Book) error {
+// CreateBook stores a new book and returns it with its ID and timestamps.
+func (s *BookService) CreateBook(ctx context.Context, book *domains.Book) (*domains.Book, error) {
+	if err := s.checkBook(ctx, book); err != nil {
+		return nil, err
+	}
 	return s.repo.CreateBook(ctx, book)
 }
 
 func (s *BookService) GetBookByID(ctx context.Context, id int) (*domains.Book, error) {
-	return s.repo.GetBookByID(ctx, id)
+	book, err := s.repo.GetBookByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if book == nil {
+		return nil, pkg.ErrBookNotFound
+	}
+	return book, nil
 }
 
-func (s *BookService) GetAllBooks(ctx context.Context) ([]*domains.Book, error) {
-	return s.repo.GetAllBooks(ctx)
+// GetAllBooks returns one page of books. An empty sort lists books by
+// creation time; a zero limit returns 20 books. The returned NextCursor
+// continues the listing with the same filter and sort order.
+func (s *BookService) GetAllBooks(ctx context.Context, query domains.BookQuery) (*domains.BookPage, error) {
+	if query.Sort == "" {
+		query.Sort = domains.BookSortCreatedAt
+	}
+	if query.Limit == 0 {
+		query.Limit = defaultBookPageSize
+	}
+	if !validBookSort(query.Sort) || query.Limit < 1 || query.Limit > maxBookPageSize {
+		return nil, pkg.ErrValidationError
+	}
+	filter := query.Filter
+	if err := validatePriceFilter(filter); err != nil {
+		return nil, err
+	}
+	if query.Cursor != "" {
+		after, err := decodeBookCursor(query.Cursor)
+		if err != nil || after.ID <= 0 || after.Sort != query.Sort || after.Desc != query.Desc || !validBookSortValue(after.Sort, after.Value) {
+			return nil, pkg.ErrInvalidCursor
+		}
+		query.After = after
+	}
+	// One extra book tells whether there is a next page
+	limit := query.Limit
+	query.Limit++
+	books, err := s.repo.GetAllBooks(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	total, err := s.repo.CountBooks(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	page := &domains.BookPage{Books: books, Total: total}
+	if len(books) > limit {
+		page.Books = books[:limit]
+		last := page.Books[limit-1]
+		page.NextCursor = encodeBookCursor(&domains.BookCursor{
+			Sort:  query.Sort,
+			Desc:  query.Desc,
+			Value: bookSortValue(last, query.Sort),
+			ID:    last.ID,
+		})
+	}
+	return page, nil
 }
 
-func (s *BookService) UpdateBook(ctx context.Context, book *domains.Book) error {
+// SearchBooks ranks books by how well their title and author match the words
+// of query.Q. A zero limit returns 20 hits. When nothing matches, the result
+// suggests titles and authors that look like the query.
+func (s *BookService) SearchBooks(ctx context.Context, query domains.BookSearchQuery) (*domains.BookSearchResult, error) {
+	if s.search == nil {
+		return nil, pkg.ErrFeatureDisabled
+	}
+	query.Q = strings.TrimSpace(query.Q)
+	if query.Limit == 0 {
+		query.Limit = defaultBookPageSize
+	}
+	if query.Q == "" || len(query.Q) > maxSearchQueryLength || query.Limit < 1 || query.Limit > maxBookPageSize {
+		return nil, pkg.ErrValidationError
+	}
+	hits, err := s.search.SearchBooks(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	result := &domains.BookSearchResult{Hits: hits}
+	if len(hits) == 0 {
+		result.Suggestions, err = s.search.SuggestBooks(ctx, query.Q, maxBookSuggestions)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// UpdateBook replaces the book with book.ID and returns it as stored. When
+// book.Version is set, the book must still be at that version or UpdateBook
+// returns pkg.ErrConflict; a zero version overwrites whatever is stored.
+func (s *BookService) UpdateBook(ctx context.Context, book *domains.Book) (*domains.Book, error) {
+	if err := s.checkBook(ctx, book); err != nil {
+		return nil, err
+	}
 	return s.repo.UpdateBook(ctx, book)
 }
 
-func (s *BookService) DeleteBook(ctx context.Context, id int) error {
-	return s.repo.DeleteBook(ctx, id)
+// DeleteBook deletes book id. Like UpdateBook, a non-zero version must match
+// the stored one or DeleteBook returns pkg.ErrConflict.
+func (s *BookService) DeleteBook(ctx context.Context, id, version int) error {
+	if version < 0 {
+		return pkg.ErrValidationError
+	}
+	return s.repo.DeleteBook(ctx, id, version)
+}
+
+// checkBook validates book before it is stored, normalizes its ISBN to
+// ISBN-13 and makes sure no other book has that ISBN. Invalid fields are
+// reported together in a *pkg.ValidationError.
+func (s *BookService) checkBook(ctx context.Context, book *domains.Book) error {
+	invalid := &pkg.ValidationError{}
+	if err := invalid.AddValidatorErrors(pkg.ValidateStruct(ctx, book)); err != nil {
+		return err
+	}
+	switch {
+	case !book.Price.Valid():
+		invalid.Add("price", "currency", "must be an amount in a supported ISO 4217 currency")
+	case book.Price.IsNegative():
+		invalid.Add("price", "gte", "must not be negative")
+	}
+	if book.ISBN13 != "" {
+		isbn, err := domains.NormalizeISBN(book.ISBN13)
+		if err != nil {
+			invalid.Add("isbn13", "isbn", "must be an ISBN-10 or ISBN-13 with a valid check digit")
+		} else {
+			book.ISBN13 = isbn
+		}
+	}
+	if err := invalid.OrNil(); err != nil {
+		return err
+	}
+	if book.ISBN13 == "" {
+		return nil
+	}
+	existing, err := s.repo.GetBookByISBN(ctx, book.ISBN13)
+	if err != nil {
+		return err
+	}
+	if existing != nil && existing.ID != book.ID {
+		return pkg.ErrBookAlreadyExists
+	}
+	return nil
+}
+
+// validatePriceFilter checks that price bounds are in the filter's currency
+// and do not exclude every price.
+func validatePriceFilter(filter domains.BookFilter) error {
+	if filter.Currency != "" && !(domains.Money{Currency: filter.Currency}).Valid() {
+		return pkg.ErrValidationError
+	}
+	for _, bound := range []*domains.Money{filter.MinPrice, filter.MaxPrice} {
+		if bound != nil && bound.Currency != filter.Currency {
+			return pkg.ErrValidationError
+		}
+	}
+	if filter.MinPrice != nil && filter.MaxPrice != nil && filter.MinPrice.Amount > filter.MaxPrice.Amount {
+		return pkg.ErrValidationError
+	}
+	return nil
+}
+
+func validBookSort(sort string) bool {
+	switch sort {
+	case domains.BookSortCreatedAt, domains.BookSortPrice, domains.BookSortTitle:
+		return true
+	}
+	return false
+}
+
+// bookSortValue returns the sort field of book in the text form the
+// repository compares cursors with.
+func bookSortValue(book *domains.Book, sort string) string {
+	switch sort {
+	case domains.BookSortPrice:
+		return book.Price.Decimal()
+	case domains.BookSortTitle:
+		return book.Title
+	}
+	return book.CreatedAt.Format(time.RFC3339Nano)
+}
+
+// validBookSortValue reports whether value is what bookSortValue returns for
+// sort. Cursors come from clients, and a value the repository cannot cast
+// would otherwise fail the query.
+func validBookSortValue(sort, value string) bool {
+	switch sort {
+	case domains.BookSortPrice:
+		whole, frac, hasPoint := strings.Cut(strings.TrimPrefix(value, "-"), ".")
+		return isDigits(whole) && (!hasPoint || isDigits(frac))
+	case domains.BookSortTitle:
+		// Postgres text cannot hold NUL; JSON already made it valid UTF-8
+		return !strings.ContainsRune(value, 0)
+	}
+	_, err := time.Parse(time.RFC3339Nano, value)
+	return err == nil
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Cursors are opaque to clients: base64url-encoded JSON.
+func encodeBookCursor(cursor *domains.BookCursor) string {
+	raw, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeBookCursor(s string) (*domains.BookCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	var cursor domains.BookCursor
+	if err := json.Unmarshal(raw, &cursor); err != nil {
+		return nil, err
+	}
+	return &cursor, nil
 }
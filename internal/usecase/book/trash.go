@@ -0,0 +1,30 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	domains "example.com/practice/fiber/internal/domain"
+)
+
+const defaultTrashRetention = 30 * 24 * time.Hour
+
+// GetDeletedBooks lists the books in the trash, most recently deleted first.
+func (s *BookService) GetDeletedBooks(ctx context.Context) ([]*domains.Book, error) {
+	return s.repo.GetDeletedBooks(ctx)
+}
+
+// RestoreBook takes book id out of the trash and returns it.
+func (s *BookService) RestoreBook(ctx context.Context, id int) (*domains.Book, error) {
+	return s.repo.RestoreBook(ctx, id)
+}
+
+// PurgeDeletedBooks permanently deletes books that have been in the trash
+// longer than the retention period. It does nothing without
+// WithTrashRetention.
+func (s *BookService) PurgeDeletedBooks(ctx context.Context) (int64, error) {
+	if s.retention == 0 {
+		return 0, nil
+	}
+	return s.repo.PurgeDeletedBooks(ctx, time.Now().Add(-s.retention))
+}
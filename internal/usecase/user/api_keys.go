@@ -0,0 +1,158 @@
+package usercase
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+
+	"example.com/practice/fiber/internal/domain"
+	"example.com/practice/fiber/pkg"
+)
+
+const (
+	// API keys look like "ak_<8 hex chars>_<secret>". The part before the
+	// second underscore is stored in clear text to find the key.
+	apiKeyTag          = "ak_"
+	apiKeyPrefixLength = len(apiKeyTag) + 8
+	apiKeySecretBytes  = 32
+	// apiKeyTouchInterval limits how often last_used_at is written.
+	apiKeyTouchInterval = time.Minute
+)
+
+// CreateAPIKey issues a key for the caller limited to req.Scopes. Scopes must
+// be API key scopes the caller's role already has.
+func (s *UserService) CreateAPIKey(ctx context.Context, actor *domain.Principal, req *domain.CreateAPIKeyRequest) (*domain.CreateAPIKeyResponse, error) {
+	if s.apiKeys == nil {
+		return nil, pkg.ErrFeatureDisabled
+	}
+	if actor == nil || actor.APIKeyID != 0 {
+		return nil, pkg.ErrForbidden
+	}
+	if err := pkg.ValidateStruct(ctx, req); err != nil {
+		return nil, pkg.ErrValidationError
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, pkg.ErrValidationError
+	}
+	scopes, err := s.apiKeyScopes(actor.Role, req.Scopes)
+	if err != nil {
+		return nil, err
+	}
+	prefixBytes := make([]byte, 4)
+	if _, err := rand.Read(prefixBytes); err != nil {
+		return nil, err
+	}
+	secret, err := pkg.GenerateRandomToken(apiKeySecretBytes)
+	if err != nil {
+		return nil, err
+	}
+	prefix := apiKeyTag + hex.EncodeToString(prefixBytes)
+	plain := prefix + "_" + secret
+	key := &domain.APIKey{
+		UserID:    actor.UserID,
+		Name:      req.Name,
+		Prefix:    prefix,
+		KeyHash:   pkg.HashToken(plain),
+		Scopes:    scopes,
+		ExpiresAt: req.ExpiresAt,
+	}
+	if err := s.apiKeys.CreateAPIKey(ctx, key); err != nil {
+		return nil, err
+	}
+	return &domain.CreateAPIKeyResponse{APIKey: *key, Key: plain}, nil
+}
+
+func (s *UserService) ListAPIKeys(ctx context.Context, actor *domain.Principal) ([]*domain.APIKey, error) {
+	if s.apiKeys == nil {
+		return nil, pkg.ErrFeatureDisabled
+	}
+	if actor == nil {
+		return nil, pkg.ErrForbidden
+	}
+	return s.apiKeys.ListUserAPIKeys(ctx, actor.UserID)
+}
+
+func (s *UserService) RevokeAPIKey(ctx context.Context, actor *domain.Principal, id int64) error {
+	if s.apiKeys == nil {
+		return pkg.ErrFeatureDisabled
+	}
+	if actor == nil {
+		return pkg.ErrForbidden
+	}
+	ok, err := s.apiKeys.RevokeAPIKey(ctx, actor.UserID, id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return pkg.ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// AuthenticateAPIKey returns the principal for an active key. The role comes
+// from the owner's current account, so demoting or suspending the owner
+// also limits their keys.
+func (s *UserService) AuthenticateAPIKey(ctx context.Context, key string) (*domain.Principal, error) {
+	if s.apiKeys == nil || !strings.HasPrefix(key, apiKeyTag) || len(key) <= apiKeyPrefixLength+1 {
+		return nil, pkg.ErrInvalidAPIKey
+	}
+	stored, err := s.apiKeys.GetAPIKeyByPrefix(ctx, key[:apiKeyPrefixLength])
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if stored == nil ||
+		subtle.ConstantTimeCompare([]byte(stored.KeyHash), []byte(pkg.HashToken(key))) != 1 ||
+		stored.RevokedAt != nil ||
+		(stored.ExpiresAt != nil && !now.Before(*stored.ExpiresAt)) {
+		return nil, pkg.ErrInvalidAPIKey
+	}
+	userDB, err := s.repo.GetUserByID(ctx, strconv.Itoa(stored.UserID))
+	if err != nil {
+		return nil, err
+	}
+	if userDB == nil || userDB.ID == 0 || !userDB.IsActive {
+		return nil, pkg.ErrInvalidAPIKey
+	}
+	if stored.LastUsedAt == nil || now.Sub(*stored.LastUsedAt) >= apiKeyTouchInterval {
+		if err := s.apiKeys.TouchAPIKey(ctx, stored.ID, now); err != nil {
+			return nil, err
+		}
+	}
+	return &domain.Principal{
+		UserID:   userDB.ID,
+		Role:     userDB.Role,
+		APIKeyID: stored.ID,
+		Scopes:   stored.Scopes,
+	}, nil
+}
+
+// apiKeyScopes validates and de-duplicates requested scopes.
+func (s *UserService) apiKeyScopes(role string, requested []string) ([]string, error) {
+	scopes := make([]string, 0, len(requested))
+	seen := map[string]bool{}
+	for _, scope := range requested {
+		if seen[scope] {
+			continue
+		}
+		if !isAPIKeyScope(scope) || !s.policy.Allows(role, scope) {
+			return nil, pkg.ErrInvalidAPIKeyScope
+		}
+		seen[scope] = true
+		scopes = append(scopes, scope)
+	}
+	return scopes, nil
+}
+
+func isAPIKeyScope(scope string) bool {
+	for _, allowed := range domain.APIKeyScopes {
+		if scope == allowed {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,91 @@
+package usercase
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"example.com/practice/fiber/internal/domain"
+	"example.com/practice/fiber/pkg"
+)
+
+const emailVerificationTokenBytes = 32
+
+// VerifyEmail consumes a verification token and marks the user's email as
+// verified.
+func (s *UserService) VerifyEmail(ctx context.Context, token string) error {
+	if s.emailVerifications == nil {
+		return pkg.ErrFeatureDisabled
+	}
+	if token == "" {
+		return pkg.ErrValidationError
+	}
+	verification, err := s.emailVerifications.GetEmailVerificationByHash(ctx, pkg.HashToken(token))
+	if err != nil {
+		return err
+	}
+	if verification == nil || verification.UsedAt != nil || time.Now().After(verification.ExpiresAt) {
+		return pkg.ErrInvalidVerificationToken
+	}
+	ok, err := s.emailVerifications.MarkEmailVerificationUsed(ctx, verification.ID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return pkg.ErrInvalidVerificationToken
+	}
+	return s.repo.MarkEmailVerified(ctx, verification.UserID)
+}
+
+// ResendVerification sends a new verification link to an unverified account.
+// Like ForgotPassword it does not reveal whether the email is registered.
+func (s *UserService) ResendVerification(ctx context.Context, req *domain.ResendVerificationRequest) error {
+	if err := pkg.ValidateStruct(ctx, req); err != nil {
+		return pkg.ErrValidationError
+	}
+	if s.emailVerifications == nil || s.notifier == nil {
+		return pkg.ErrFeatureDisabled
+	}
+	userDB, err := s.repo.GetUserByEmail(ctx, req.Email)
+	if err != nil {
+		return err
+	}
+	if userDB == nil || userDB.ID == 0 || userDB.EmailVerifiedAt != nil {
+		return nil
+	}
+	return s.sendVerification(ctx, userDB)
+}
+
+// sendFirstVerification sends the verification link for a new account. The
+// account exists by then, so failures are only logged; the user can ask for
+// another link with ResendVerification.
+func (s *UserService) sendFirstVerification(ctx context.Context, user *domain.User) {
+	if s.emailVerifications == nil || s.notifier == nil {
+		return
+	}
+	if err := s.sendVerification(ctx, user); err != nil {
+		log.Printf("verification link for user %d: %v", user.ID, err)
+	}
+}
+
+func (s *UserService) sendVerification(ctx context.Context, user *domain.User) error {
+	token, err := pkg.GenerateRandomToken(emailVerificationTokenBytes)
+	if err != nil {
+		return err
+	}
+	err = s.emailVerifications.CreateEmailVerification(ctx, &domain.EmailVerification{
+		UserID:    user.ID,
+		TokenHash: pkg.HashToken(token),
+		ExpiresAt: time.Now().Add(s.emailVerificationTTL),
+	})
+	if err != nil {
+		return err
+	}
+	return s.notifier.Notify(ctx, domain.Notification{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Use this link to verify your email address. It expires in %s.\n%s",
+			s.emailVerificationTTL, tokenLink(s.emailVerificationURL, token)),
+	})
+}
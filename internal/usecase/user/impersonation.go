@@ -0,0 +1,104 @@
+package usercase
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"example.com/practice/fiber/internal/domain"
+	"example.com/practice/fiber/pkg"
+)
+
+const (
+	defaultImpersonationTTL = 15 * time.Minute
+	impersonationAuditLimit = 200
+)
+
+// Impersonate issues a short-lived access token with which actor acts as the
+// user id. The token names actor in its act claim and cannot be refreshed;
+// starting the impersonation and every request made with the token are
+// audited. Users who may manage or impersonate others cannot be
+// impersonated.
+func (s *UserService) Impersonate(ctx context.Context, actor *domain.Principal, id int, req *domain.ImpersonationRequest) (*domain.ImpersonationResponse, error) {
+	if s.impersonationAudit == nil {
+		return nil, pkg.ErrFeatureDisabled
+	}
+	if actor == nil || actor.Impersonated() || actor.UserID == id || !s.policy.Allows(actor.Role, domain.PermUsersImpersonate) {
+		return nil, pkg.ErrForbidden
+	}
+	if err := pkg.ValidateStruct(ctx, req); err != nil {
+		return nil, pkg.ErrValidationError
+	}
+	userDB, err := s.repo.GetUserByID(ctx, strconv.Itoa(id))
+	if err != nil {
+		return nil, err
+	}
+	if userDB == nil || userDB.ID == 0 {
+		return nil, pkg.ErrUserNotFound
+	}
+	if s.policy.Allows(userDB.Role, domain.PermUsersManage) || s.policy.Allows(userDB.Role, domain.PermUsersImpersonate) {
+		return nil, pkg.ErrForbidden
+	}
+	if !userDB.IsActive {
+		return nil, pkg.ErrAccountSuspended
+	}
+	tokenID, err := pkg.GenerateRandomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	// Per-user revocations only cover tokens younger than accessTTL
+	ttl := s.impersonationTTL
+	if s.accessTTL > 0 && s.accessTTL < ttl {
+		ttl = s.accessTTL
+	}
+	expiresAt := time.Now().Add(ttl)
+	token, err := s.tokenProvider.GenerateToken(domain.Claims{
+		ID:        tokenID,
+		Subject:   strconv.Itoa(userDB.ID),
+		Role:      userDB.Role,
+		Actor:     strconv.Itoa(actor.UserID),
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = s.impersonationAudit.CreateImpersonationAuditEntry(ctx, &domain.ImpersonationAuditEntry{
+		ActorID: actor.UserID,
+		UserID:  userDB.ID,
+		TokenID: tokenID,
+		Action:  domain.ImpersonationActionStart,
+		Reason:  req.Reason,
+		IP:      req.ClientIP,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &domain.ImpersonationResponse{
+		Token:     token,
+		ExpiresAt: expiresAt,
+		UserID:    userDB.ID,
+		ActorID:   actor.UserID,
+	}, nil
+}
+
+// AuditImpersonatedRequest records a request made with an impersonation
+// token.
+func (s *UserService) AuditImpersonatedRequest(ctx context.Context, entry *domain.ImpersonationAuditEntry) error {
+	if s.impersonationAudit == nil {
+		return pkg.ErrFeatureDisabled
+	}
+	entry.Action = domain.ImpersonationActionRequest
+	return s.impersonationAudit.CreateImpersonationAuditEntry(ctx, entry)
+}
+
+// ListImpersonations returns the latest audit entries about impersonations
+// of the user id.
+func (s *UserService) ListImpersonations(ctx context.Context, actor *domain.Principal, id int) ([]*domain.ImpersonationAuditEntry, error) {
+	if s.impersonationAudit == nil {
+		return nil, pkg.ErrFeatureDisabled
+	}
+	if actor == nil || !s.policy.Allows(actor.Role, domain.PermUsersManage) {
+		return nil, pkg.ErrForbidden
+	}
+	return s.impersonationAudit.ListImpersonationAuditEntries(ctx, id, impersonationAuditLimit)
+}
@@ -0,0 +1,91 @@
+package usercase
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"example.com/practice/fiber/pkg"
+)
+
+func accountAttemptKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipAttemptKey(ip string) string {
+	if ip == "" {
+		return ""
+	}
+	return "ip:" + ip
+}
+
+// checkLoginThrottle fails when the account or the client IP is locked.
+func (s *UserService) checkLoginThrottle(ctx context.Context, email, ip string) error {
+	if s.loginAttempts == nil {
+		return nil
+	}
+	now := time.Now()
+	attempt, err := s.loginAttempts.GetLoginAttempt(ctx, accountAttemptKey(email))
+	if err != nil {
+		return err
+	}
+	if attempt.Locked(now) {
+		return pkg.ErrAccountLocked
+	}
+	if key := ipAttemptKey(ip); key != "" {
+		attempt, err := s.loginAttempts.GetLoginAttempt(ctx, key)
+		if err != nil {
+			return err
+		}
+		if attempt.Locked(now) {
+			return pkg.ErrTooManyLoginAttempts
+		}
+	}
+	return nil
+}
+
+// loginFailed records a failed attempt for the account and the client IP,
+// locking either once its threshold is reached, and returns err.
+func (s *UserService) loginFailed(ctx context.Context, email, ip string, err error) error {
+	if s.loginAttempts == nil {
+		return err
+	}
+	if recordErr := s.recordLoginFailure(ctx, accountAttemptKey(email), s.lockout.MaxAttempts); recordErr != nil {
+		return recordErr
+	}
+	if key := ipAttemptKey(ip); key != "" {
+		if recordErr := s.recordLoginFailure(ctx, key, s.lockout.IPMaxAttempts); recordErr != nil {
+			return recordErr
+		}
+	}
+	return err
+}
+
+func (s *UserService) recordLoginFailure(ctx context.Context, key string, threshold int) error {
+	now := time.Now()
+	attempt, err := s.loginAttempts.RecordLoginFailure(ctx, key, now, now.Add(-s.lockout.Window))
+	if err != nil {
+		return err
+	}
+	if lock := s.lockout.LockDuration(attempt.Failures, threshold); lock > 0 {
+		return s.loginAttempts.LockLogin(ctx, key, now.Add(lock))
+	}
+	return nil
+}
+
+// loginSucceeded clears the failures of the account. Failures of the client
+// IP are kept so one valid account cannot unlock guessing at others.
+func (s *UserService) loginSucceeded(ctx context.Context, email string) error {
+	if s.loginAttempts == nil {
+		return nil
+	}
+	return s.loginAttempts.ResetLoginAttempts(ctx, accountAttemptKey(email))
+}
+
+// CleanupLoginAttempts drops failure counters that can no longer lock anyone.
+func (s *UserService) CleanupLoginAttempts(ctx context.Context) (int64, error) {
+	if s.loginAttempts == nil {
+		return 0, nil
+	}
+	return s.loginAttempts.DeleteStaleLoginAttempts(ctx, time.Now().Add(-s.lockout.Window))
+}
@@ -0,0 +1,364 @@
+package usercase
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"strconv"
+	"strings"
+	"time"
+
+	"example.com/practice/fiber/internal/domain"
+	"example.com/practice/fiber/pkg"
+)
+
+const (
+	// mfaTokenTTL bounds how long a password-verified login may wait for its
+	// second factor.
+	mfaTokenTTL       = 5 * time.Minute
+	mfaSkew           = 1
+	recoveryCodeCount = 10
+)
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// mfaChallenge is called by Login once the password is verified. It returns a
+// pending token when the user has 2FA enabled, an enrollment token when their
+// role requires 2FA they have not set up, and regular tokens otherwise.
+func (s *UserService) mfaChallenge(ctx context.Context, user *domain.User, client domain.Client) (*domain.AuthResponse, error) {
+	mfa, err := s.mfa.GetMFA(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if mfa.Enabled() {
+		token, err := s.mfaToken(user, domain.PurposeMFAPending)
+		if err != nil {
+			return nil, err
+		}
+		return &domain.AuthResponse{MFARequired: true, MFAToken: token}, nil
+	}
+	if s.mfaRequiredFor(user.Role) {
+		token, err := s.mfaToken(user, domain.PurposeMFAEnroll)
+		if err != nil {
+			return nil, err
+		}
+		return &domain.AuthResponse{MFAEnrollmentRequired: true, MFAToken: token}, nil
+	}
+	return s.startSession(ctx, user, client)
+}
+
+// CompleteMFALogin exchanges a pending MFA token and a TOTP or recovery code
+// for regular tokens.
+func (s *UserService) CompleteMFALogin(ctx context.Context, req *domain.MFALoginRequest) (*domain.AuthResponse, error) {
+	if s.mfa == nil {
+		return nil, pkg.ErrFeatureDisabled
+	}
+	if err := pkg.ValidateStruct(ctx, req); err != nil || req.Code == "" {
+		return nil, pkg.ErrValidationError
+	}
+	userDB, claims, err := s.userFromMFAToken(ctx, req.MFAToken, domain.PurposeMFAPending)
+	if err != nil {
+		return nil, err
+	}
+	mfa, err := s.mfa.GetMFA(ctx, userDB.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !mfa.Enabled() {
+		return nil, pkg.ErrInvalidMFAToken
+	}
+	// Wrong codes count towards the account lockout like wrong passwords
+	if err := s.checkLoginThrottle(ctx, userDB.Email, ""); err != nil {
+		return nil, err
+	}
+	if err := s.verifyMFACode(ctx, mfa, req.Code, true); err != nil {
+		if err == pkg.ErrInvalidMFACode {
+			return nil, s.loginFailed(ctx, userDB.Email, "", err)
+		}
+		return nil, err
+	}
+	if err := s.loginSucceeded(ctx, userDB.Email); err != nil {
+		return nil, err
+	}
+	if err := s.consumeMFAToken(ctx, claims); err != nil {
+		return nil, err
+	}
+	return s.startSession(ctx, userDB, domain.Client{IP: req.ClientIP, UserAgent: req.UserAgent})
+}
+
+// SetupMFA starts TOTP enrollment for the caller, who must confirm their
+// current password. The secret is not used for login until ConfirmMFA
+// succeeds.
+func (s *UserService) SetupMFA(ctx context.Context, actor *domain.Principal, req *domain.MFASetupRequest) (*domain.MFASetupResponse, error) {
+	if s.mfa == nil {
+		return nil, pkg.ErrFeatureDisabled
+	}
+	if actor == nil {
+		return nil, pkg.ErrForbidden
+	}
+	if err := pkg.ValidateStruct(ctx, req); err != nil {
+		return nil, pkg.ErrValidationError
+	}
+	userDB, err := s.repo.GetUserByID(ctx, strconv.Itoa(actor.UserID))
+	if err != nil {
+		return nil, err
+	}
+	if userDB == nil || userDB.ID == 0 {
+		return nil, pkg.ErrUserNotFound
+	}
+	// A stolen access token alone must not be enough to enroll
+	if !s.passwordMatches(userDB.Password, req.CurrentPassword) {
+		return nil, pkg.ErrInvalidCurrentPassword
+	}
+	return s.setupMFA(ctx, userDB)
+}
+
+// ConfirmMFA enables 2FA once the caller proves their authenticator works and
+// returns one-time recovery codes. The codes are only shown this once.
+func (s *UserService) ConfirmMFA(ctx context.Context, actor *domain.Principal, req *domain.MFACodeRequest) (*domain.MFARecoveryCodesResponse, error) {
+	if s.mfa == nil {
+		return nil, pkg.ErrFeatureDisabled
+	}
+	if actor == nil {
+		return nil, pkg.ErrForbidden
+	}
+	if err := pkg.ValidateStruct(ctx, req); err != nil {
+		return nil, pkg.ErrValidationError
+	}
+	codes, err := s.confirmMFA(ctx, actor.UserID, req.Code)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.MFARecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// DisableMFA turns 2FA off for the caller after checking a TOTP or recovery
+// code. Roles that require 2FA cannot disable it.
+func (s *UserService) DisableMFA(ctx context.Context, actor *domain.Principal, req *domain.MFACodeRequest) error {
+	if s.mfa == nil {
+		return pkg.ErrFeatureDisabled
+	}
+	if actor == nil {
+		return pkg.ErrForbidden
+	}
+	if err := pkg.ValidateStruct(ctx, req); err != nil {
+		return pkg.ErrValidationError
+	}
+	if s.mfaRequiredFor(actor.Role) {
+		return pkg.ErrMFARequired
+	}
+	mfa, err := s.mfa.GetMFA(ctx, actor.UserID)
+	if err != nil {
+		return err
+	}
+	if !mfa.Enabled() {
+		return pkg.ErrMFANotEnabled
+	}
+	if err := s.verifyMFACode(ctx, mfa, req.Code, true); err != nil {
+		return err
+	}
+	return s.mfa.DisableMFA(ctx, actor.UserID)
+}
+
+// SetupMFAEnrollment starts enrollment for a user who was told at login that
+// their role requires 2FA.
+func (s *UserService) SetupMFAEnrollment(ctx context.Context, req *domain.MFALoginRequest) (*domain.MFASetupResponse, error) {
+	if s.mfa == nil {
+		return nil, pkg.ErrFeatureDisabled
+	}
+	if err := pkg.ValidateStruct(ctx, req); err != nil {
+		return nil, pkg.ErrValidationError
+	}
+	userDB, _, err := s.userFromMFAToken(ctx, req.MFAToken, domain.PurposeMFAEnroll)
+	if err != nil {
+		return nil, err
+	}
+	return s.setupMFA(ctx, userDB)
+}
+
+// ConfirmMFAEnrollment finishes login-time enrollment and returns regular
+// tokens together with the recovery codes.
+func (s *UserService) ConfirmMFAEnrollment(ctx context.Context, req *domain.MFALoginRequest) (*domain.AuthResponse, error) {
+	if s.mfa == nil {
+		return nil, pkg.ErrFeatureDisabled
+	}
+	if err := pkg.ValidateStruct(ctx, req); err != nil || req.Code == "" {
+		return nil, pkg.ErrValidationError
+	}
+	userDB, claims, err := s.userFromMFAToken(ctx, req.MFAToken, domain.PurposeMFAEnroll)
+	if err != nil {
+		return nil, err
+	}
+	codes, err := s.confirmMFA(ctx, userDB.ID, req.Code)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.consumeMFAToken(ctx, claims); err != nil {
+		return nil, err
+	}
+	resp, err := s.startSession(ctx, userDB, domain.Client{IP: req.ClientIP, UserAgent: req.UserAgent})
+	if err != nil {
+		return nil, err
+	}
+	resp.RecoveryCodes = codes
+	return resp, nil
+}
+
+func (s *UserService) setupMFA(ctx context.Context, user *domain.User) (*domain.MFASetupResponse, error) {
+	mfa, err := s.mfa.GetMFA(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if mfa.Enabled() {
+		return nil, pkg.ErrMFAAlreadyEnabled
+	}
+	secret, err := pkg.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	saved, err := s.mfa.SaveMFASecret(ctx, user.ID, secret)
+	if err != nil {
+		return nil, err
+	}
+	if !saved {
+		return nil, pkg.ErrMFAAlreadyEnabled
+	}
+	return &domain.MFASetupResponse{
+		Secret: secret,
+		URI:    pkg.TOTPURI(s.mfaIssuer, user.Email, secret),
+	}, nil
+}
+
+func (s *UserService) confirmMFA(ctx context.Context, userID int, code string) ([]string, error) {
+	mfa, err := s.mfa.GetMFA(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if mfa == nil {
+		return nil, pkg.ErrMFANotEnabled
+	}
+	if mfa.Enabled() {
+		return nil, pkg.ErrMFAAlreadyEnabled
+	}
+	if err := s.verifyMFACode(ctx, mfa, code, false); err != nil {
+		return nil, err
+	}
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	enabled, err := s.mfa.EnableMFA(ctx, userID, hashes)
+	if err != nil {
+		return nil, err
+	}
+	if !enabled {
+		return nil, pkg.ErrMFAAlreadyEnabled
+	}
+	return codes, nil
+}
+
+// verifyMFACode accepts a TOTP code that has not been used before and, when
+// allowRecovery is set, an unused recovery code.
+func (s *UserService) verifyMFACode(ctx context.Context, mfa *domain.MFA, code string, allowRecovery bool) error {
+	if step, ok := pkg.ValidateTOTP(mfa.Secret, code, time.Now(), mfaSkew); ok {
+		fresh, err := s.mfa.UpdateMFALastUsedStep(ctx, mfa.UserID, step)
+		if err != nil {
+			return err
+		}
+		if !fresh {
+			return pkg.ErrInvalidMFACode
+		}
+		return nil
+	}
+	if !allowRecovery {
+		return pkg.ErrInvalidMFACode
+	}
+	ok, err := s.mfa.UseRecoveryCode(ctx, mfa.UserID, pkg.HashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return pkg.ErrInvalidMFACode
+	}
+	return nil
+}
+
+func (s *UserService) mfaToken(user *domain.User, purpose string) (string, error) {
+	return s.tokenProvider.GenerateToken(domain.Claims{
+		Subject:   strconv.Itoa(user.ID),
+		Role:      user.Role,
+		Purpose:   purpose,
+		ExpiresAt: time.Now().Add(mfaTokenTTL),
+	})
+}
+
+// userFromMFAToken returns the user an MFA token with purpose was issued
+// to. Tokens that were consumed, or revoked with the rest of the user's
+// tokens, are rejected.
+func (s *UserService) userFromMFAToken(ctx context.Context, token, purpose string) (*domain.User, *domain.Claims, error) {
+	claims, err := s.tokenProvider.ValidateToken(token)
+	if err != nil || claims == nil || claims.Purpose != purpose {
+		return nil, nil, pkg.ErrInvalidMFAToken
+	}
+	userDB, err := s.repo.GetUserByID(ctx, claims.Subject)
+	if err != nil {
+		return nil, nil, err
+	}
+	if userDB == nil || userDB.ID == 0 {
+		return nil, nil, pkg.ErrInvalidMFAToken
+	}
+	if s.revocations != nil {
+		revoked, err := s.revocations.IsRevoked(ctx, claims.ID, userDB.ID, claims.IssuedAt)
+		if err != nil {
+			return nil, nil, err
+		}
+		if revoked {
+			return nil, nil, pkg.ErrInvalidMFAToken
+		}
+	}
+	if !userDB.IsActive {
+		return nil, nil, pkg.ErrAccountSuspended
+	}
+	return userDB, claims, nil
+}
+
+// consumeMFAToken makes an MFA token single-use once it has been exchanged
+// for a session. It needs WithTokenRevocation; without it the token stays
+// usable until it expires.
+func (s *UserService) consumeMFAToken(ctx context.Context, claims *domain.Claims) error {
+	if s.revocations == nil || claims.ID == "" {
+		return nil
+	}
+	return s.revocations.RevokeToken(ctx, claims.ID, claims.ExpiresAt)
+}
+
+func (s *UserService) mfaRequiredFor(role string) bool {
+	for _, required := range s.mfaRequiredRoles {
+		if role == required {
+			return true
+		}
+	}
+	return false
+}
+
+// generateRecoveryCodes returns codes formatted as "xxxx-xxxx" and their
+// hashes for storage.
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		raw := strings.ToLower(recoveryCodeEncoding.EncodeToString(b))
+		codes = append(codes, raw[:4]+"-"+raw[4:])
+		hashes = append(hashes, pkg.HashToken(raw))
+	}
+	return codes, hashes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
@@ -0,0 +1,227 @@
+package usercase
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"example.com/practice/fiber/internal/domain"
+	"example.com/practice/fiber/internal/ports"
+	"example.com/practice/fiber/pkg"
+)
+
+const (
+	oauthStateTTL = 10 * time.Minute
+	// oauthUsernameLength bounds the part of the username taken from the
+	// email address; a random suffix keeps it unique.
+	oauthUsernameLength = 12
+)
+
+// StartOAuth begins a sign-in at provider. The returned state must come back
+// in the callback; the PKCE verifier and nonce never leave the server.
+func (s *UserService) StartOAuth(ctx context.Context, provider string) (*domain.OAuthStart, error) {
+	idp, err := s.identityProvider(provider)
+	if err != nil {
+		return nil, err
+	}
+	state, err := pkg.GenerateRandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	verifier, err := pkg.GenerateRandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := pkg.GenerateRandomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	authURL, err := idp.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		return nil, err
+	}
+	err = s.oauthStates.CreateOAuthState(ctx, &domain.OAuthState{
+		StateHash:    pkg.HashToken(state),
+		Provider:     provider,
+		CodeVerifier: verifier,
+		Nonce:        nonce,
+		ExpiresAt:    time.Now().Add(oauthStateTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &domain.OAuthStart{AuthorizationURL: authURL, State: state}, nil
+}
+
+// CompleteOAuth finishes a sign-in started with StartOAuth. The external
+// identity is linked to an existing user with the same verified email, or a
+// new user is created. The result is the same as for Login.
+func (s *UserService) CompleteOAuth(ctx context.Context, req *domain.OAuthCallbackRequest) (*domain.AuthResponse, error) {
+	idp, err := s.identityProvider(req.Provider)
+	if err != nil {
+		return nil, err
+	}
+	if err := pkg.ValidateStruct(ctx, req); err != nil {
+		return nil, pkg.ErrInvalidOAuthState
+	}
+	// States are single-use, even when the provider reports an error
+	state, err := s.oauthStates.ConsumeOAuthState(ctx, pkg.HashToken(req.State))
+	if err != nil {
+		return nil, err
+	}
+	if state == nil || state.Provider != req.Provider || time.Now().After(state.ExpiresAt) {
+		return nil, pkg.ErrInvalidOAuthState
+	}
+	if req.Error != "" || req.Code == "" {
+		return nil, pkg.ErrOAuthDenied
+	}
+	external, err := idp.Exchange(ctx, req.Code, state.CodeVerifier, state.Nonce)
+	if err != nil {
+		return nil, pkg.ErrOAuthExchangeFailed
+	}
+	user, identity, err := s.resolveOAuthUser(ctx, req.Provider, external)
+	if err != nil {
+		return nil, err
+	}
+	if !user.IsActive {
+		return nil, pkg.ErrAccountSuspended
+	}
+	if s.requireVerifiedEmail && user.EmailVerifiedAt == nil {
+		return nil, pkg.ErrEmailNotVerified
+	}
+	if err := s.identities.TouchUserIdentity(ctx, identity.ID, time.Now()); err != nil {
+		return nil, err
+	}
+	client := domain.Client{IP: req.ClientIP, UserAgent: req.UserAgent}
+	if s.mfa != nil {
+		return s.mfaChallenge(ctx, user, client)
+	}
+	return s.startSession(ctx, user, client)
+}
+
+// CleanupOAuthStates deletes abandoned sign-in attempts.
+func (s *UserService) CleanupOAuthStates(ctx context.Context) (int64, error) {
+	if s.oauthStates == nil {
+		return 0, nil
+	}
+	return s.oauthStates.DeleteExpiredOAuthStates(ctx, time.Now())
+}
+
+func (s *UserService) identityProvider(name string) (ports.IdentityProvider, error) {
+	if s.identities == nil || s.oauthStates == nil {
+		return nil, pkg.ErrFeatureDisabled
+	}
+	idp, ok := s.identityProviders[name]
+	if !ok {
+		return nil, pkg.ErrOAuthProviderNotFound
+	}
+	return idp, nil
+}
+
+// resolveOAuthUser finds the user linked to external, links it to the user
+// with the same email when the provider verified that email, or creates a
+// new user.
+func (s *UserService) resolveOAuthUser(ctx context.Context, provider string, external *domain.ExternalIdentity) (*domain.User, *domain.UserIdentity, error) {
+	identity, err := s.identities.GetUserIdentity(ctx, provider, external.Subject)
+	if err != nil {
+		return nil, nil, err
+	}
+	if identity != nil {
+		userDB, err := s.repo.GetUserByID(ctx, strconv.Itoa(identity.UserID))
+		if err != nil {
+			return nil, nil, err
+		}
+		if userDB == nil || userDB.ID == 0 {
+			return nil, nil, pkg.ErrUserNotFound
+		}
+		return userDB, identity, nil
+	}
+	if external.Email == "" {
+		return nil, nil, pkg.ErrOAuthEmailRequired
+	}
+	identity = &domain.UserIdentity{Provider: provider, Subject: external.Subject, Email: external.Email}
+	userDB, err := s.repo.GetUserByEmail(ctx, external.Email)
+	if err != nil {
+		return nil, nil, err
+	}
+	if userDB != nil && userDB.ID > 0 {
+		// Linking on an unverified email would let anyone who registers the
+		// address at the provider take over the account
+		if !external.EmailVerified {
+			return nil, nil, pkg.ErrUserAlreadyExists
+		}
+		identity.UserID = userDB.ID
+		if err := s.identities.CreateUserIdentity(ctx, identity); err != nil {
+			return nil, nil, err
+		}
+		if userDB.EmailVerifiedAt == nil {
+			if err := s.repo.MarkEmailVerified(ctx, userDB.ID); err != nil {
+				return nil, nil, err
+			}
+			now := time.Now()
+			userDB.EmailVerifiedAt = &now
+		}
+		return userDB, identity, nil
+	}
+	user, err := s.newOAuthUser(external)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.identities.CreateUserWithIdentity(ctx, user, identity); err != nil {
+		return nil, nil, err
+	}
+	if user.EmailVerifiedAt == nil {
+		s.sendFirstVerification(ctx, user)
+	}
+	return user, identity, nil
+}
+
+// newOAuthUser builds a user for a first sign-in. The random password cannot
+// be used until the user sets one through the password reset flow.
+func (s *UserService) newOAuthUser(external *domain.ExternalIdentity) (*domain.User, error) {
+	password, err := pkg.GenerateRandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	hashedPassword, err := s.hasher.Hash(password)
+	if err != nil {
+		return nil, err
+	}
+	suffix, err := pkg.GenerateRandomToken(4)
+	if err != nil {
+		return nil, err
+	}
+	user := &domain.User{
+		Email:     external.Email,
+		Password:  hashedPassword,
+		Username:  oauthUsername(external.Email) + "_" + suffix,
+		FirstName: external.GivenName,
+		LastName:  external.FamilyName,
+		Role:      "user",
+		IsActive:  true,
+	}
+	if external.EmailVerified {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+	}
+	return user, nil
+}
+
+// oauthUsername derives a username from the local part of email.
+func oauthUsername(email string) string {
+	local, _, _ := strings.Cut(strings.ToLower(email), "@")
+	var b strings.Builder
+	for _, r := range local {
+		if b.Len() == oauthUsernameLength {
+			break
+		}
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '.' || r == '_' || r == '-' {
+			b.WriteRune(r)
+		}
+	}
+	if b.Len() == 0 {
+		return "user"
+	}
+	return b.String()
+}
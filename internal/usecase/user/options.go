@@ -0,0 +1,186 @@
+package usercase
+
+import (
+	"time"
+
+	"example.com/practice/fiber/internal/domain"
+	"example.com/practice/fiber/internal/ports"
+)
+
+// Option configures optional collaborators of UserService.
+type Option func(*UserService)
+
+// WithRefreshTokens enables refresh tokens on login. Issued tokens are stored
+// through repo and expire after ttl.
+func WithRefreshTokens(repo ports.RefreshTokenRepository, ttl time.Duration) Option {
+	return func(s *UserService) {
+		s.refreshTokens = repo
+		s.refreshTTL = ttl
+	}
+}
+
+// WithPasswordHasher sets how passwords are hashed. Logins rehash passwords
+// whose stored hash the hasher reports as outdated. It defaults to bcrypt at
+// the default cost.
+func WithPasswordHasher(hasher ports.PasswordHasher) Option {
+	return func(s *UserService) {
+		s.hasher = hasher
+	}
+}
+
+// WithPasswordPolicy sets the rules for new passwords on registration,
+// change and reset. Zero lengths fall back to domain.DefaultPasswordPolicy.
+// history keeps replaced passwords when policy.History is above one; without
+// it only the current password is checked for reuse.
+func WithPasswordPolicy(policy domain.PasswordPolicy, history ports.PasswordHistoryRepository) Option {
+	return func(s *UserService) {
+		defaults := domain.DefaultPasswordPolicy()
+		if policy.MinLength == 0 {
+			policy.MinLength = defaults.MinLength
+		}
+		if policy.MaxLength == 0 {
+			policy.MaxLength = defaults.MaxLength
+		}
+		s.passwordPolicy = policy
+		s.passwordHistory = history
+	}
+}
+
+// WithBreachedPasswordCheck rejects new passwords that checker knows from
+// data breaches.
+func WithBreachedPasswordCheck(checker ports.BreachedPasswordChecker) Option {
+	return func(s *UserService) {
+		s.breachedPasswords = checker
+	}
+}
+
+// WithRolePolicy sets the policy used to decide whether a caller may act on
+// accounts other than their own. It defaults to domain.DefaultRolePolicy.
+func WithRolePolicy(policy domain.RolePolicy) Option {
+	return func(s *UserService) {
+		s.policy = policy
+	}
+}
+
+// WithTokenRevocation enables logout and revoking all tokens of a user.
+// accessTTL is the lifetime of access tokens and bounds how long a
+// per-user revocation has to be kept.
+func WithTokenRevocation(store ports.TokenRevocationStore, accessTTL time.Duration) Option {
+	return func(s *UserService) {
+		s.revocations = store
+		s.accessTTL = accessTTL
+	}
+}
+
+// WithNotifier sets where user-facing messages such as reset links are sent.
+func WithNotifier(notifier ports.Notifier) Option {
+	return func(s *UserService) {
+		s.notifier = notifier
+	}
+}
+
+// WithPasswordReset enables the forgot/reset password flow. Reset tokens
+// expire after ttl; linkURL is the page that receives the token as a query
+// parameter.
+func WithPasswordReset(repo ports.PasswordResetRepository, ttl time.Duration, linkURL string) Option {
+	return func(s *UserService) {
+		s.passwordResets = repo
+		s.passwordResetTTL = ttl
+		s.passwordResetURL = linkURL
+	}
+}
+
+// WithEmailVerification sends a verification link on registration. Tokens
+// expire after ttl; linkURL is the page that receives the token. When
+// required is set, Login rejects accounts whose email is not verified.
+func WithEmailVerification(repo ports.EmailVerificationRepository, ttl time.Duration, linkURL string, required bool) Option {
+	return func(s *UserService) {
+		s.emailVerifications = repo
+		s.emailVerificationTTL = ttl
+		s.emailVerificationURL = linkURL
+		s.requireVerifiedEmail = required
+	}
+}
+
+// WithMFA enables TOTP two-factor authentication. issuer is the name shown in
+// authenticator apps; users with one of requiredRoles must enroll before
+// they can finish logging in.
+func WithMFA(repo ports.MFARepository, issuer string, requiredRoles []string) Option {
+	return func(s *UserService) {
+		s.mfa = repo
+		s.mfaIssuer = issuer
+		s.mfaRequiredRoles = requiredRoles
+	}
+}
+
+// WithLoginThrottle locks accounts and client IPs after repeated login
+// failures. Zero fields of policy fall back to domain.DefaultLockoutPolicy.
+func WithLoginThrottle(store ports.LoginAttemptStore, policy domain.LockoutPolicy) Option {
+	return func(s *UserService) {
+		defaults := domain.DefaultLockoutPolicy()
+		if policy.MaxAttempts == 0 {
+			policy.MaxAttempts = defaults.MaxAttempts
+		}
+		if policy.IPMaxAttempts == 0 {
+			policy.IPMaxAttempts = defaults.IPMaxAttempts
+		}
+		if policy.Window == 0 {
+			policy.Window = defaults.Window
+		}
+		if policy.BaseLockout == 0 {
+			policy.BaseLockout = defaults.BaseLockout
+		}
+		if policy.MaxLockout == 0 {
+			policy.MaxLockout = defaults.MaxLockout
+		}
+		s.loginAttempts = store
+		s.lockout = policy
+	}
+}
+
+// WithAPIKeys enables API keys for machine-to-machine access.
+func WithAPIKeys(repo ports.APIKeyRepository) Option {
+	return func(s *UserService) {
+		s.apiKeys = repo
+	}
+}
+
+// WithSessions records a session per login that users can list and end.
+func WithSessions(repo ports.SessionRepository) Option {
+	return func(s *UserService) {
+		s.sessions = repo
+	}
+}
+
+// WithImpersonation lets admins act as other users with tokens that expire
+// after ttl, 15 minutes by default, and records their use in audit.
+func WithImpersonation(audit ports.ImpersonationAuditRepository, ttl time.Duration) Option {
+	return func(s *UserService) {
+		if ttl == 0 {
+			ttl = defaultImpersonationTTL
+		}
+		s.impersonationAudit = audit
+		s.impersonationTTL = ttl
+	}
+}
+
+// WithOAuth enables sign-in through external OpenID Connect providers, keyed
+// by the name used in /v1/oauth/:provider routes.
+func WithOAuth(identities ports.UserIdentityRepository, states ports.OAuthStateRepository, providers map[string]ports.IdentityProvider) Option {
+	return func(s *UserService) {
+		s.identities = identities
+		s.oauthStates = states
+		s.identityProviders = providers
+	}
+}
+
+// WithTrashRetention keeps deleted users for retention, 30 days by default,
+// before PurgeDeletedUsers removes them for good.
+func WithTrashRetention(retention time.Duration) Option {
+	return func(s *UserService) {
+		if retention == 0 {
+			retention = defaultTrashRetention
+		}
+		s.trashRetention = retention
+	}
+}
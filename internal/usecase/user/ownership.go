@@ -0,0 +1,18 @@
+package usercase
+
+import (
+	"example.com/practice/fiber/internal/domain"
+	"example.com/practice/fiber/pkg"
+)
+
+// authorizeOwner allows actor to act on the user with the given ID when it is
+// their own account or when their role may manage other users.
+func (s *UserService) authorizeOwner(actor *domain.Principal, id int) error {
+	if actor == nil {
+		return pkg.ErrForbidden
+	}
+	if actor.UserID == id || s.policy.Allows(actor.Role, domain.PermUsersManage) {
+		return nil
+	}
+	return pkg.ErrForbidden
+}
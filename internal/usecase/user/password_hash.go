@@ -0,0 +1,59 @@
+package usercase
+
+import (
+	"context"
+	"errors"
+
+	"example.com/practice/fiber/pkg"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// defaultHasher keeps bcrypt at the default cost when no hasher is
+// configured with WithPasswordHasher.
+type defaultHasher struct{}
+
+func (defaultHasher) Hash(password string) (string, error) {
+	return pkg.HashPassword(password)
+}
+
+func (defaultHasher) Verify(hash, password string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (defaultHasher) NeedsRehash(hash string) bool {
+	return false
+}
+
+// passwordMatches reports whether password matches hash. Unreadable hashes
+// never match.
+func (s *UserService) passwordMatches(hash, password string) bool {
+	match, err := s.hasher.Verify(hash, password)
+	return err == nil && match
+}
+
+// compareDummyPassword spends the same time as checking a real password so
+// that unknown emails cannot be told apart by response time.
+func (s *UserService) compareDummyPassword(password string) {
+	s.dummyHashOnce.Do(func() {
+		s.dummyHash, _ = s.hasher.Hash("dummy-password")
+	})
+	_, _ = s.hasher.Verify(s.dummyHash, password)
+}
+
+// upgradePasswordHash rehashes a just verified password when its hash uses an
+// outdated algorithm or cost. It is best effort: a failed upgrade is retried
+// on the next login.
+func (s *UserService) upgradePasswordHash(ctx context.Context, userID int, hash, password string) {
+	if !s.hasher.NeedsRehash(hash) {
+		return
+	}
+	newHash, err := s.hasher.Hash(password)
+	if err != nil {
+		return
+	}
+	_ = s.repo.RehashPassword(ctx, userID, hash, newHash)
+}
@@ -0,0 +1,134 @@
+package usercase
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"example.com/practice/fiber/internal/domain"
+	"example.com/practice/fiber/pkg"
+)
+
+// minPersonalInfoLength keeps very short usernames from ruling out most
+// passwords.
+const minPersonalInfoLength = 3
+
+// checkPassword applies the password policy to a new password for user. user
+// is the stored user when the password replaces an existing one; its ID is
+// zero on registration. It returns a *pkg.PasswordPolicyError listing every
+// broken rule.
+func (s *UserService) checkPassword(ctx context.Context, user *domain.User, password string) error {
+	policy := s.passwordPolicy
+	var violations []pkg.PasswordViolation
+	violate := func(code, message string) {
+		violations = append(violations, pkg.PasswordViolation{Code: code, Message: message})
+	}
+	if utf8.RuneCountInString(password) < policy.MinLength {
+		violate("too_short", fmt.Sprintf("must be at least %d characters long", policy.MinLength))
+	}
+	if policy.MaxLength > 0 && len(password) > policy.MaxLength {
+		violate("too_long", fmt.Sprintf("must be at most %d bytes long", policy.MaxLength))
+	}
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case !unicode.IsLetter(r):
+			symbol = true
+		}
+	}
+	if policy.RequireUpper && !upper {
+		violate("missing_upper", "must contain an uppercase letter")
+	}
+	if policy.RequireLower && !lower {
+		violate("missing_lower", "must contain a lowercase letter")
+	}
+	if policy.RequireDigit && !digit {
+		violate("missing_digit", "must contain a digit")
+	}
+	if policy.RequireSymbol && !symbol {
+		violate("missing_symbol", "must contain a symbol")
+	}
+	if policy.ForbidPersonalInfo && containsPersonalInfo(password, user) {
+		violate("personal_info", "must not contain your username or email address")
+	}
+	if user.ID > 0 && policy.History > 0 {
+		reused, err := s.passwordReused(ctx, user, password)
+		if err != nil {
+			return err
+		}
+		if reused {
+			violate("reused", fmt.Sprintf("must not be one of your last %d passwords", policy.History))
+		}
+	}
+	if s.breachedPasswords != nil {
+		breached, err := s.breachedPasswords.IsBreached(ctx, password)
+		if err != nil {
+			return err
+		}
+		if breached {
+			violate("breached", "has appeared in a data breach and must not be used")
+		}
+	}
+	if len(violations) > 0 {
+		return &pkg.PasswordPolicyError{Violations: violations}
+	}
+	return nil
+}
+
+func containsPersonalInfo(password string, user *domain.User) bool {
+	password = strings.ToLower(password)
+	local, _, _ := strings.Cut(user.Email, "@")
+	for _, info := range []string{user.Username, local} {
+		info = strings.ToLower(info)
+		if len(info) >= minPersonalInfoLength && strings.Contains(password, info) {
+			return true
+		}
+	}
+	return false
+}
+
+// passwordReused reports whether password matches the current password of
+// user or one of the previous ones kept in the history.
+func (s *UserService) passwordReused(ctx context.Context, user *domain.User, password string) (bool, error) {
+	if s.passwordMatches(user.Password, password) {
+		return true, nil
+	}
+	if s.passwordHistory == nil || s.passwordPolicy.History < 2 {
+		return false, nil
+	}
+	hashes, err := s.passwordHistory.ListPasswordHistory(ctx, user.ID, s.passwordPolicy.History-1)
+	if err != nil {
+		return false, err
+	}
+	for _, hash := range hashes {
+		if s.passwordMatches(hash, password) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// setPassword replaces the password of user with password, which has passed
+// checkPassword, and keeps the replaced hash in the history.
+func (s *UserService) setPassword(ctx context.Context, user *domain.User, password string) error {
+	replaced := user.Password
+	hashedPassword, err := s.hasher.Hash(password)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.UpdatePassword(ctx, user.ID, hashedPassword); err != nil {
+		return err
+	}
+	if s.passwordHistory != nil && s.passwordPolicy.History > 1 {
+		return s.passwordHistory.AddPasswordHistory(ctx, user.ID, replaced, s.passwordPolicy.History-1)
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package usercase
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"example.com/practice/fiber/internal/domain"
+	"example.com/practice/fiber/pkg"
+)
+
+const passwordResetTokenBytes = 32
+
+// ForgotPassword sends a single-use reset link to the account with the given
+// email. It succeeds silently for unknown emails so callers cannot probe
+// which addresses are registered.
+func (s *UserService) ForgotPassword(ctx context.Context, req *domain.ForgotPasswordRequest) error {
+	if err := pkg.ValidateStruct(ctx, req); err != nil {
+		return pkg.ErrValidationError
+	}
+	if s.passwordResets == nil || s.notifier == nil {
+		return pkg.ErrFeatureDisabled
+	}
+	userDB, err := s.repo.GetUserByEmail(ctx, req.Email)
+	if err != nil {
+		return err
+	}
+	if userDB == nil || userDB.ID == 0 {
+		return nil
+	}
+	// Only the newest link stays valid
+	if err := s.passwordResets.InvalidateUserPasswordResets(ctx, userDB.ID); err != nil {
+		return err
+	}
+	token, err := pkg.GenerateRandomToken(passwordResetTokenBytes)
+	if err != nil {
+		return err
+	}
+	err = s.passwordResets.CreatePasswordReset(ctx, &domain.PasswordReset{
+		UserID:    userDB.ID,
+		TokenHash: pkg.HashToken(token),
+		ExpiresAt: time.Now().Add(s.passwordResetTTL),
+	})
+	if err != nil {
+		return err
+	}
+	return s.notifier.Notify(ctx, domain.Notification{
+		To:      userDB.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Use this link to reset your password. It expires in %s.\n%s",
+			s.passwordResetTTL, tokenLink(s.passwordResetURL, token)),
+	})
+}
+
+// ResetPassword sets a new password using a token from ForgotPassword. The
+// token is consumed and every existing session of the user is revoked.
+func (s *UserService) ResetPassword(ctx context.Context, req *domain.ResetPasswordRequest) error {
+	if err := pkg.ValidateStruct(ctx, req); err != nil {
+		return pkg.ErrValidationError
+	}
+	if s.passwordResets == nil {
+		return pkg.ErrFeatureDisabled
+	}
+	reset, err := s.passwordResets.GetPasswordResetByHash(ctx, pkg.HashToken(req.Token))
+	if err != nil {
+		return err
+	}
+	if reset == nil || reset.UsedAt != nil || time.Now().After(reset.ExpiresAt) {
+		return pkg.ErrInvalidResetToken
+	}
+	userDB, err := s.repo.GetUserByID(ctx, strconv.Itoa(reset.UserID))
+	if err != nil {
+		return err
+	}
+	if userDB == nil || userDB.ID == 0 {
+		return pkg.ErrInvalidResetToken
+	}
+	// Check the policy before consuming the token so that the user can try
+	// another password with the same link
+	if err := s.checkPassword(ctx, userDB, req.NewPassword); err != nil {
+		return err
+	}
+	ok, err := s.passwordResets.MarkPasswordResetUsed(ctx, reset.ID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return pkg.ErrInvalidResetToken
+	}
+	if err := s.setPassword(ctx, userDB, req.NewPassword); err != nil {
+		return err
+	}
+	return s.revokeAllTokens(ctx, reset.UserID)
+}
+
+// tokenLink appends token as the "token" query parameter of base. Without a
+// base URL the raw token is returned.
+func tokenLink(base, token string) string {
+	if base == "" {
+		return token
+	}
+	u, err := url.Parse(base)
+	if err != nil {
+		return token
+	}
+	q := u.Query()
+	q.Set("token", token)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
@@ -0,0 +1,59 @@
+package usercase
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+
+	"example.com/practice/fiber/internal/domain"
+	"example.com/practice/fiber/pkg"
+)
+
+// PatchUser applies patch to the names of user id and stores the ones it
+// changed. The patch sees the same document UpdateUser takes, so other
+// fields of the account cannot be patched.
+func (s *UserService) PatchUser(ctx context.Context, actor *domain.Principal, id int, patch domain.Patch) (*domain.User, error) {
+	if err := s.authorizeOwner(actor, id); err != nil {
+		return nil, err
+	}
+	if !patch.Supported() {
+		return nil, pkg.ErrUnsupportedPatch
+	}
+	userDB, err := s.repo.GetUserByID(ctx, strconv.Itoa(id))
+	if err != nil {
+		return nil, err
+	}
+	if userDB == nil || userDB.ID == 0 {
+		return nil, pkg.ErrUserNotFound
+	}
+	current := domain.UpdateUserRequest{FirstName: userDB.FirstName, LastName: userDB.LastName}
+	doc, err := json.Marshal(current)
+	if err != nil {
+		return nil, err
+	}
+	if doc, err = patch.Apply(doc); err != nil {
+		return nil, err
+	}
+	var patched domain.UpdateUserRequest
+	if err := pkg.DecodeJSON(doc, &patched); err != nil {
+		return nil, err
+	}
+	if err := pkg.ValidateStruct(ctx, &patched); err != nil {
+		return nil, pkg.ErrValidationError
+	}
+	var fields []string
+	if patched.FirstName != current.FirstName {
+		fields = append(fields, "first_name")
+	}
+	if patched.LastName != current.LastName {
+		fields = append(fields, "last_name")
+	}
+	if len(fields) == 0 {
+		return userDB, nil
+	}
+	return s.repo.UpdateUserFields(ctx, &domain.User{
+		ID:        id,
+		FirstName: patched.FirstName,
+		LastName:  patched.LastName,
+	}, fields)
+}
@@ -0,0 +1,78 @@
+package usercase
+
+import (
+	"context"
+	"time"
+
+	"example.com/practice/fiber/internal/domain"
+	"example.com/practice/fiber/pkg"
+)
+
+// Logout revokes the access token of actor and ends its session. A refresh
+// token given in req has its family revoked as well.
+func (s *UserService) Logout(ctx context.Context, actor *domain.Principal, req *domain.LogoutRequest) error {
+	if actor == nil {
+		return pkg.ErrForbidden
+	}
+	if s.revocations != nil && actor.TokenID != "" {
+		if err := s.revocations.RevokeToken(ctx, actor.TokenID, actor.TokenExpiresAt); err != nil {
+			return err
+		}
+	}
+	if s.sessions != nil && actor.SessionID != "" {
+		if _, err := s.sessions.RevokeSession(ctx, actor.UserID, actor.SessionID); err != nil {
+			return err
+		}
+		if s.refreshTokens != nil {
+			if err := s.refreshTokens.RevokeRefreshTokenFamily(ctx, actor.SessionID); err != nil {
+				return err
+			}
+		}
+	}
+	if s.refreshTokens != nil && req != nil && req.RefreshToken != "" {
+		stored, err := s.refreshTokens.GetRefreshTokenByHash(ctx, pkg.HashToken(req.RefreshToken))
+		if err != nil {
+			return err
+		}
+		if stored != nil && stored.UserID == actor.UserID {
+			return s.refreshTokens.RevokeRefreshTokenFamily(ctx, stored.FamilyID)
+		}
+	}
+	return nil
+}
+
+// RevokeUserTokens revokes every access and refresh token issued to the user
+// so far.
+func (s *UserService) RevokeUserTokens(ctx context.Context, actor *domain.Principal, id int) error {
+	if err := s.authorizeOwner(actor, id); err != nil {
+		return err
+	}
+	return s.revokeAllTokens(ctx, id)
+}
+
+func (s *UserService) revokeAllTokens(ctx context.Context, id int) error {
+	if s.revocations != nil {
+		// Issue times have microsecond precision, as stored by Postgres
+		now := time.Now().Truncate(time.Microsecond)
+		if err := s.revocations.RevokeUserTokens(ctx, id, now, now.Add(s.accessTTL)); err != nil {
+			return err
+		}
+	}
+	if s.sessions != nil {
+		if err := s.sessions.RevokeUserSessions(ctx, id); err != nil {
+			return err
+		}
+	}
+	if s.refreshTokens != nil {
+		return s.refreshTokens.RevokeUserRefreshTokens(ctx, id)
+	}
+	return nil
+}
+
+// CleanupRevocations drops revocation entries whose tokens have expired anyway.
+func (s *UserService) CleanupRevocations(ctx context.Context) (int64, error) {
+	if s.revocations == nil {
+		return 0, nil
+	}
+	return s.revocations.DeleteExpired(ctx, time.Now())
+}
@@ -3,6 +3,8 @@ package usercase
 import (
 	"context"
 	"strconv"
+	"sync"
+	"time"
 
 	"example.com/practice/fiber/internal/domain"
 	"example.com/practice/fiber/internal/ports"
@@ -12,84 +14,193 @@ import (
 type UserService struct {
 	repo          ports.UserRepository
 	tokenProvider ports.TokenProvider
+	hasher        ports.PasswordHasher
+	dummyHashOnce sync.Once
+	dummyHash     string
+	refreshTokens ports.RefreshTokenRepository
+	refreshTTL    time.Duration
+	policy        domain.RolePolicy
+	revocations   ports.TokenRevocationStore
+	accessTTL     time.Duration
+	notifier      ports.Notifier
+
+	passwordPolicy    domain.PasswordPolicy
+	passwordHistory   ports.PasswordHistoryRepository
+	breachedPasswords ports.BreachedPasswordChecker
+
+	passwordResets   ports.PasswordResetRepository
+	passwordResetTTL time.Duration
+	passwordResetURL string
+
+	emailVerifications   ports.EmailVerificationRepository
+	emailVerificationTTL time.Duration
+	emailVerificationURL string
+	requireVerifiedEmail bool
+
+	mfa              ports.MFARepository
+	mfaIssuer        string
+	mfaRequiredRoles []string
+
+	loginAttempts ports.LoginAttemptStore
+	lockout       domain.LockoutPolicy
+
+	apiKeys ports.APIKeyRepository
+
+	sessions ports.SessionRepository
+
+	impersonationAudit ports.ImpersonationAuditRepository
+	impersonationTTL   time.Duration
+
+	identities        ports.UserIdentityRepository
+	oauthStates       ports.OAuthStateRepository
+	identityProviders map[string]ports.IdentityProvider
+
+	trashRetention time.Duration
 }
 
-func NewUserService(repo ports.UserRepository, tokenProvider ports.TokenProvider) *UserService {
-	return &UserService{repo: repo, tokenProvider: tokenProvider}
+func NewUserService(repo ports.UserRepository, tokenProvider ports.TokenProvider, opts ...Option) *UserService {
+	s := &UserService{
+		repo:           repo,
+		tokenProvider:  tokenProvider,
+		hasher:         defaultHasher{},
+		policy:         domain.DefaultRolePolicy(),
+		passwordPolicy: domain.DefaultPasswordPolicy(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
-func (s *UserService) Register(ctx context.Context, user *domain.User) error {
+// Register creates user and returns the stored account.
+func (s *UserService) Register(ctx context.Context, user *domain.User) (*domain.User, error) {
 	// Validate user input
 	if err := pkg.ValidateStruct(ctx, user); err != nil {
-		return pkg.ErrValidationError
+		return nil, pkg.ErrValidationError
+	}
+	// Only the submitted fields count; the user does not exist yet
+	if err := s.checkPassword(ctx, &domain.User{Email: user.Email, Username: user.Username}, user.Password); err != nil {
+		return nil, err
 	}
 	// Check if user already exists
 	userDB, err := s.repo.GetUserByEmail(ctx, user.Email)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if userDB != nil && userDB.ID > 0 {
-		return pkg.ErrUserAlreadyExists
+		return nil, pkg.ErrUserAlreadyExists
 	}
 	// Hash password
-	hashedPassword, err := pkg.HashPassword(user.Password)
+	hashedPassword, err := s.hasher.Hash(user.Password)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	user.Password = hashedPassword
 	user.Role = "user"
-	return s.repo.CreateUser(ctx, user)
+	created, err := s.repo.CreateUser(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	// Send verification link
+	s.sendFirstVerification(ctx, created)
+	return created, nil
 }
 
-func (s *UserService) Login(ctx context.Context, user *domain.AuthRequest) (string, error) {
+func (s *UserService) Login(ctx context.Context, user *domain.AuthRequest) (*domain.AuthResponse, error) {
 	// Validate user input
 	if err := pkg.ValidateStruct(ctx, user); err != nil {
-		return "", pkg.ErrValidationError
+		return nil, pkg.ErrValidationError
+	}
+	// Refuse locked accounts and clients before checking the password
+	if err := s.checkLoginThrottle(ctx, user.Email, user.ClientIP); err != nil {
+		return nil, err
 	}
 	// Get user by email
 	userDB, err := s.repo.GetUserByEmail(ctx, user.Email)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
+	// Unknown emails and wrong passwords fail the same way
 	if userDB == nil || userDB.ID == 0 {
-		return "", pkg.ErrUserNotFound
+		s.compareDummyPassword(user.Password)
+		return nil, s.loginFailed(ctx, user.Email, user.ClientIP, pkg.ErrInvalidCredentials)
 	}
 	// Compare password
-	err = userDB.ComparePassword(user.Password)
+	if !s.passwordMatches(userDB.Password, user.Password) {
+		return nil, s.loginFailed(ctx, user.Email, user.ClientIP, pkg.ErrInvalidCredentials)
+	}
+	s.upgradePasswordHash(ctx, userDB.ID, userDB.Password, user.Password)
+	if !userDB.IsActive {
+		return nil, pkg.ErrAccountSuspended
+	}
+	if s.requireVerifiedEmail && userDB.EmailVerifiedAt == nil {
+		return nil, pkg.ErrEmailNotVerified
+	}
+	// Ask for a second factor when enabled; failures stay counted until
+	// it is passed
+	client := domain.Client{IP: user.ClientIP, UserAgent: user.UserAgent}
+	if s.mfa != nil {
+		resp, err := s.mfaChallenge(ctx, userDB, client)
+		if err != nil || resp.Token == "" {
+			return resp, err
+		}
+		return resp, s.loginSucceeded(ctx, user.Email)
+	}
+	if err := s.loginSucceeded(ctx, user.Email); err != nil {
+		return nil, err
+	}
+	// Start a session with access and refresh tokens
+	return s.startSession(ctx, userDB, client)
+}
+
+func (s *UserService) GetUserByID(ctx context.Context, actor *domain.Principal, id string) (*domain.User, error) {
+	userID, err := strconv.Atoi(id)
 	if err != nil {
-		return "", err
+		return nil, pkg.ErrUserNotFound
 	}
-	// Generate token
-	token, err := s.tokenProvider.GenerateToken(strconv.Itoa(userDB.ID))
+	if err := s.authorizeOwner(actor, userID); err != nil {
+		return nil, err
+	}
+	userDB, err := s.repo.GetUserByID(ctx, id)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	return token, nil
-}
-
-func (s *UserService) GetUserByID(ctx context.Context, id string) (*domain.User, error) {
-	return s.repo.GetUserByID(ctx, id)
+	if userDB == nil || userDB.ID == 0 {
+		return nil, pkg.ErrUserNotFound
+	}
+	return userDB, nil
 }
 
 func (s *UserService) GetUserByEmail(ctx context.Context, email string) (*domain.User, error) {
-	return s.repo.GetUserByEmail(ctx, email)
+	userDB, err := s.repo.GetUserByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	if userDB == nil || userDB.ID == 0 {
+		return nil, pkg.ErrUserNotFound
+	}
+	return userDB, nil
 }
 
 func (s *UserService) GetAllUsers(ctx context.Context) ([]*domain.User, error) {
 	return s.repo.GetAllUsers(ctx)
 }
 
-func (s *UserService) UpdateUser(ctx context.Context, id int, user *domain.UpdateUserRequest) error {
+// UpdateUser changes the names of user id and returns the stored account.
+func (s *UserService) UpdateUser(ctx context.Context, actor *domain.Principal, id int, user *domain.UpdateUserRequest) (*domain.User, error) {
+	if err := s.authorizeOwner(actor, id); err != nil {
+		return nil, err
+	}
 	// Validate user input
 	if err := pkg.ValidateStruct(ctx, user); err != nil {
-		return pkg.ErrValidationError
+		return nil, pkg.ErrValidationError
 	}
 	userDB, err := s.repo.GetUserByID(ctx, strconv.Itoa(id))
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if userDB == nil || userDB.ID == 0 {
-		return pkg.ErrUserNotFound
+		return nil, pkg.ErrUserNotFound
 	}
 	return s.repo.UpdateUser(ctx, &domain.User{
 		ID:        id,
@@ -98,11 +209,21 @@ func (s *UserService) UpdateUser(ctx context.Context, id int, user *domain.Updat
 	})
 }
 
-func (s *UserService) DeleteUser(ctx context.Context, id int) error {
-	return s.repo.DeleteUser(ctx, id)
+// DeleteUser moves user id to the trash and signs them out everywhere.
+func (s *UserService) DeleteUser(ctx context.Context, actor *domain.Principal, id int) error {
+	if err := s.authorizeOwner(actor, id); err != nil {
+		return err
+	}
+	if err := s.repo.DeleteUser(ctx, id); err != nil {
+		return err
+	}
+	return s.revokeAllTokens(ctx, id)
 }
 
-func (s *UserService) UpdatePassword(ctx context.Context, id int, req domain.UpdatePasswordRequest) error {
+func (s *UserService) UpdatePassword(ctx context.Context, actor *domain.Principal, id int, req domain.UpdatePasswordRequest) error {
+	if err := s.authorizeOwner(actor, id); err != nil {
+		return err
+	}
 	// Validate password
 	if err := pkg.ValidateStruct(ctx, req); err != nil {
 		return pkg.ErrValidationError
@@ -115,10 +236,14 @@ func (s *UserService) UpdatePassword(ctx context.Context, id int, req domain.Upd
 	if userDB == nil || userDB.ID == 0 {
 		return pkg.ErrUserNotFound
 	}
-	// Hash password
-	hashedPassword, err := pkg.HashPassword(req.NewPassword)
-	if err != nil {
+	// Changing your own password requires the current one
+	if actor.UserID == id {
+		if req.CurrentPassword == "" || !s.passwordMatches(userDB.Password, req.CurrentPassword) {
+			return pkg.ErrInvalidCurrentPassword
+		}
+	}
+	if err := s.checkPassword(ctx, userDB, req.NewPassword); err != nil {
 		return err
 	}
-	return s.repo.UpdatePassword(ctx, id, hashedPassword)
+	return s.setPassword(ctx, userDB, req.NewPassword)
 }
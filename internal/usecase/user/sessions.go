@@ -0,0 +1,143 @@
+package usercase
+
+import (
+	"context"
+	"time"
+
+	"example.com/practice/fiber/internal/domain"
+	"example.com/practice/fiber/pkg"
+)
+
+const (
+	sessionIDBytes = 16
+	// sessionTouchInterval limits how often last_seen_at is written.
+	sessionTouchInterval = time.Minute
+	maxUserAgentLength   = 512
+)
+
+// startSession records a new session for user and issues its first tokens.
+// The session ID doubles as the refresh token family.
+func (s *UserService) startSession(ctx context.Context, user *domain.User, client domain.Client) (*domain.AuthResponse, error) {
+	if s.sessions == nil {
+		return s.issueTokens(ctx, user, "")
+	}
+	sessionID, err := pkg.GenerateRandomToken(sessionIDBytes)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.createSession(ctx, sessionID, user.ID, client); err != nil {
+		return nil, err
+	}
+	return s.issueTokens(ctx, user, sessionID)
+}
+
+func (s *UserService) createSession(ctx context.Context, id string, userID int, client domain.Client) error {
+	userAgent := client.UserAgent
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+	return s.sessions.CreateSession(ctx, &domain.Session{
+		ID:        id,
+		UserID:    userID,
+		UserAgent: userAgent,
+		IP:        client.IP,
+	})
+}
+
+// continueSession is called when a refresh token of familyID is rotated. It
+// records the activity and rejects ended sessions. Families from before
+// sessions were enabled get a session on their first refresh.
+func (s *UserService) continueSession(ctx context.Context, familyID string, userID int, client domain.Client) error {
+	if s.sessions == nil {
+		return nil
+	}
+	session, err := s.sessions.GetSession(ctx, familyID)
+	if err != nil {
+		return err
+	}
+	if session == nil {
+		return s.createSession(ctx, familyID, userID, client)
+	}
+	if session.UserID != userID || session.RevokedAt != nil {
+		return pkg.ErrInvalidRefreshToken
+	}
+	return s.sessions.TouchSession(ctx, familyID, time.Now())
+}
+
+// CheckSession reports whether sessionID is an active session of userID and
+// records the activity at most once a minute.
+func (s *UserService) CheckSession(ctx context.Context, userID int, sessionID string) (bool, error) {
+	if s.sessions == nil {
+		return true, nil
+	}
+	session, err := s.sessions.GetSession(ctx, sessionID)
+	if err != nil {
+		return false, err
+	}
+	if session == nil || session.UserID != userID || session.RevokedAt != nil {
+		return false, nil
+	}
+	if now := time.Now(); now.Sub(session.LastSeenAt) >= sessionTouchInterval {
+		if err := s.sessions.TouchSession(ctx, sessionID, now); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// ListSessions returns the caller's active sessions and marks the one the
+// request was made with.
+func (s *UserService) ListSessions(ctx context.Context, actor *domain.Principal) ([]*domain.Session, error) {
+	if s.sessions == nil {
+		return nil, pkg.ErrFeatureDisabled
+	}
+	if actor == nil {
+		return nil, pkg.ErrForbidden
+	}
+	sessions, err := s.sessions.ListUserSessions(ctx, actor.UserID)
+	if err != nil {
+		return nil, err
+	}
+	for _, session := range sessions {
+		session.Current = session.ID == actor.SessionID
+	}
+	return sessions, nil
+}
+
+// RevokeSession signs the caller out on one device. Its access tokens are
+// rejected from the next request on and its refresh tokens stop working.
+func (s *UserService) RevokeSession(ctx context.Context, actor *domain.Principal, id string) error {
+	if s.sessions == nil {
+		return pkg.ErrFeatureDisabled
+	}
+	if actor == nil {
+		return pkg.ErrForbidden
+	}
+	ok, err := s.sessions.RevokeSession(ctx, actor.UserID, id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return pkg.ErrSessionNotFound
+	}
+	if s.refreshTokens != nil {
+		return s.refreshTokens.RevokeRefreshTokenFamily(ctx, id)
+	}
+	return nil
+}
+
+// CleanupSessions deletes sessions that have been idle for longer than any
+// of their tokens can live.
+func (s *UserService) CleanupSessions(ctx context.Context) (int64, error) {
+	if s.sessions == nil {
+		return 0, nil
+	}
+	lifetime := s.accessTTL
+	if s.refreshTokens != nil && s.refreshTTL > lifetime {
+		lifetime = s.refreshTTL
+	}
+	if lifetime == 0 {
+		lifetime = 24 * time.Hour
+	}
+	return s.sessions.DeleteStaleSessions(ctx, time.Now().Add(-lifetime))
+}
@@ -0,0 +1,55 @@
+package usercase
+
+import (
+	"context"
+	"strconv"
+
+	"example.com/practice/fiber/internal/domain"
+	"example.com/practice/fiber/pkg"
+)
+
+// SuspendUser deactivates an account and revokes its tokens, so the user is
+// signed out on their next request and cannot log in again until
+// reactivated.
+func (s *UserService) SuspendUser(ctx context.Context, actor *domain.Principal, id int, req *domain.UserStatusRequest) (*domain.UserStatusChange, error) {
+	change, err := s.setUserStatus(ctx, actor, id, false, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.revokeAllTokens(ctx, id); err != nil {
+		return nil, err
+	}
+	return change, nil
+}
+
+// ReactivateUser lifts a suspension.
+func (s *UserService) ReactivateUser(ctx context.Context, actor *domain.Principal, id int, req *domain.UserStatusRequest) (*domain.UserStatusChange, error) {
+	return s.setUserStatus(ctx, actor, id, true, req)
+}
+
+func (s *UserService) setUserStatus(ctx context.Context, actor *domain.Principal, id int, active bool, req *domain.UserStatusRequest) (*domain.UserStatusChange, error) {
+	// Only user managers may do this, and never to themselves
+	if actor == nil || actor.UserID == id || !s.policy.Allows(actor.Role, domain.PermUsersManage) {
+		return nil, pkg.ErrForbidden
+	}
+	if err := pkg.ValidateStruct(ctx, req); err != nil {
+		return nil, pkg.ErrValidationError
+	}
+	userDB, err := s.repo.GetUserByID(ctx, strconv.Itoa(id))
+	if err != nil {
+		return nil, err
+	}
+	if userDB == nil || userDB.ID == 0 {
+		return nil, pkg.ErrUserNotFound
+	}
+	change := &domain.UserStatusChange{
+		UserID:    id,
+		Active:    active,
+		Reason:    req.Reason,
+		ChangedBy: actor.UserID,
+	}
+	if err := s.repo.SetUserStatus(ctx, change); err != nil {
+		return nil, err
+	}
+	return change, nil
+}
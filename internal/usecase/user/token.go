@@ -0,0 +1,116 @@
+package usercase
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"example.com/practice/fiber/internal/domain"
+	"example.com/practice/fiber/pkg"
+)
+
+const refreshTokenBytes = 32
+
+// RefreshToken rotates a refresh token: the presented token is marked as used
+// and a new access/refresh pair from the same family is returned. Presenting a
+// token that was already rotated revokes the whole family.
+func (s *UserService) RefreshToken(ctx context.Context, req *domain.RefreshRequest) (*domain.AuthResponse, error) {
+	if s.refreshTokens == nil {
+		return nil, pkg.ErrInvalidRefreshToken
+	}
+	if err := pkg.ValidateStruct(ctx, req); err != nil {
+		return nil, pkg.ErrValidationError
+	}
+	stored, err := s.refreshTokens.GetRefreshTokenByHash(ctx, pkg.HashToken(req.RefreshToken))
+	if err != nil {
+		return nil, err
+	}
+	if stored == nil || stored.RevokedAt != nil {
+		return nil, pkg.ErrInvalidRefreshToken
+	}
+	if stored.UsedAt != nil {
+		return nil, s.revokeReusedFamily(ctx, stored.UserID, stored.FamilyID)
+	}
+	if time.Now().After(stored.ExpiresAt) {
+		return nil, pkg.ErrInvalidRefreshToken
+	}
+	ok, err := s.refreshTokens.MarkRefreshTokenUsed(ctx, stored.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		// Another request rotated this token first.
+		return nil, s.revokeReusedFamily(ctx, stored.UserID, stored.FamilyID)
+	}
+	userDB, err := s.repo.GetUserByID(ctx, strconv.Itoa(stored.UserID))
+	if err != nil {
+		return nil, err
+	}
+	if userDB == nil || userDB.ID == 0 {
+		return nil, pkg.ErrInvalidRefreshToken
+	}
+	if !userDB.IsActive {
+		return nil, pkg.ErrAccountSuspended
+	}
+	if err := s.continueSession(ctx, stored.FamilyID, userDB.ID, domain.Client{IP: req.ClientIP, UserAgent: req.UserAgent}); err != nil {
+		return nil, err
+	}
+	return s.issueTokens(ctx, userDB, stored.FamilyID)
+}
+
+// revokeReusedFamily revokes the refresh family and ends the session of the
+// same ID, so its access tokens stop working too.
+func (s *UserService) revokeReusedFamily(ctx context.Context, userID int, familyID string) error {
+	if err := s.refreshTokens.RevokeRefreshTokenFamily(ctx, familyID); err != nil {
+		return err
+	}
+	if s.sessions != nil {
+		if _, err := s.sessions.RevokeSession(ctx, userID, familyID); err != nil {
+			return err
+		}
+	}
+	return pkg.ErrRefreshTokenReused
+}
+
+// issueTokens creates an access token for user and, when refresh tokens are
+// enabled, a refresh token in familyID. An empty familyID starts a new family.
+// With sessions enabled familyID is the session ID, which the access token
+// carries as well.
+func (s *UserService) issueTokens(ctx context.Context, user *domain.User, familyID string) (*domain.AuthResponse, error) {
+	claims := domain.Claims{
+		Subject: strconv.Itoa(user.ID),
+		Role:    user.Role,
+	}
+	if s.sessions != nil {
+		claims.SessionID = familyID
+	}
+	token, err := s.tokenProvider.GenerateToken(claims)
+	if err != nil {
+		return nil, err
+	}
+	resp := &domain.AuthResponse{Token: token}
+	if s.refreshTokens == nil {
+		return resp, nil
+	}
+	if familyID == "" {
+		familyID, err = pkg.GenerateRandomToken(16)
+		if err != nil {
+			return nil, err
+		}
+	}
+	refreshToken, err := pkg.GenerateRandomToken(refreshTokenBytes)
+	if err != nil {
+		return nil, err
+	}
+	err = s.refreshTokens.CreateRefreshToken(ctx, &domain.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		TokenHash: pkg.HashToken(refreshToken),
+		ExpiresAt: time.Now().Add(s.refreshTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp.RefreshToken = refreshToken
+	return resp, nil
+}
@@ -0,0 +1,38 @@
+package usercase
+
+import (
+	"context"
+	"time"
+
+	"example.com/practice/fiber/internal/domain"
+	"example.com/practice/fiber/pkg"
+)
+
+const defaultTrashRetention = 30 * 24 * time.Hour
+
+// GetDeletedUsers lists the users in the trash.
+func (s *UserService) GetDeletedUsers(ctx context.Context, actor *domain.Principal) ([]*domain.User, error) {
+	if actor == nil || !s.policy.Allows(actor.Role, domain.PermUsersManage) {
+		return nil, pkg.ErrForbidden
+	}
+	return s.repo.GetDeletedUsers(ctx)
+}
+
+// RestoreUser takes user id out of the trash. Their tokens stay revoked, so
+// they have to log in again.
+func (s *UserService) RestoreUser(ctx context.Context, actor *domain.Principal, id int) (*domain.User, error) {
+	if actor == nil || !s.policy.Allows(actor.Role, domain.PermUsersManage) {
+		return nil, pkg.ErrForbidden
+	}
+	return s.repo.RestoreUser(ctx, id)
+}
+
+// PurgeDeletedUsers permanently deletes users that have been in the trash
+// longer than the retention period. It does nothing without
+// WithTrashRetention.
+func (s *UserService) PurgeDeletedUsers(ctx context.Context) (int64, error) {
+	if s.trashRetention == 0 {
+		return 0, nil
+	}
+	return s.repo.PurgeDeletedUsers(ctx, time.Now().Add(-s.trashRetention))
+}
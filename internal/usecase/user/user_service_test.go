@@ -2,21 +2,42 @@ package usercase
 
 import (
 	"context"
+	"crypto/sha1"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 	"testing"
+	"time"
 
+	jwtadapter "example.com/practice/fiber/internal/adapters/auth/jwt"
+	"example.com/practice/fiber/internal/adapters/memory"
+	"example.com/practice/fiber/internal/adapters/password"
 	"example.com/practice/fiber/internal/domain"
+	"example.com/practice/fiber/internal/ports"
 	"example.com/practice/fiber/pkg"
+	"golang.org/x/crypto/bcrypt"
 )
 
 type mockUserRepository struct {
 	users []domain.User
 	err   error
+	// fields are the fields UpdateUserFields last wrote.
+	fields []string
+	// purgedBefore is the cutoff PurgeDeletedUsers was last called with.
+	purgedBefore time.Time
 }
 
-func (m *mockUserRepository) CreateUser(ctx context.Context, user *domain.User) error {
-	return m.err
+func (m *mockUserRepository) CreateUser(ctx context.Context, user *domain.User) (*domain.User, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	stored := *user
+	stored.ID = len(m.users) + 1
+	return &stored, nil
 }
 
 func (m *mockUserRepository) GetUserByID(ctx context.Context, id string) (*domain.User, error) {
@@ -49,8 +70,21 @@ func (m *mockUserRepository) GetAllUsers(ctx context.Context) ([]*domain.User, e
 	return []*domain.User{&m.users[0]}, m.err
 }
 
-func (m *mockUserRepository) UpdateUser(ctx context.Context, user *domain.User) error {
-	return m.err
+func (m *mockUserRepository) UpdateUser(ctx context.Context, user *domain.User) (*domain.User, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	stored := *user
+	return &stored, nil
+}
+
+func (m *mockUserRepository) UpdateUserFields(ctx context.Context, user *domain.User, fields []string) (*domain.User, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	m.fields = fields
+	stored := *user
+	return &stored, nil
 }
 
 func (m *mockUserRepository) DeleteUser(ctx context.Context, id int) error {
@@ -58,21 +92,502 @@ func (m *mockUserRepository) DeleteUser(ctx context.Context, id int) error {
 }
 
 func (m *mockUserRepository) UpdatePassword(ctx context.Context, id int, password string) error {
+	if m.err != nil {
+		return m.err
+	}
+	for i := range m.users {
+		if m.users[i].ID == id {
+			m.users[i].Password = password
+		}
+	}
+	return nil
+}
+
+func (m *mockUserRepository) RehashPassword(ctx context.Context, id int, oldHash, newHash string) error {
+	for i := range m.users {
+		if m.users[i].ID == id && m.users[i].Password == oldHash {
+			m.users[i].Password = newHash
+		}
+	}
+	return m.err
+}
+
+func (m *mockUserRepository) MarkEmailVerified(ctx context.Context, id int) error {
+	for i := range m.users {
+		if m.users[i].ID == id {
+			now := time.Now()
+			m.users[i].EmailVerifiedAt = &now
+		}
+	}
+	return m.err
+}
+
+func (m *mockUserRepository) SetUserStatus(ctx context.Context, change *domain.UserStatusChange) error {
+	for i := range m.users {
+		if m.users[i].ID == change.UserID {
+			m.users[i].IsActive = change.Active
+		}
+	}
+	change.ChangedAt = time.Now()
 	return m.err
 }
 
+func (m *mockUserRepository) GetDeletedUsers(ctx context.Context) ([]*domain.User, error) {
+	var users []*domain.User
+	for i := range m.users {
+		if m.users[i].DeletedAt != nil {
+			users = append(users, &m.users[i])
+		}
+	}
+	return users, m.err
+}
+
+func (m *mockUserRepository) RestoreUser(ctx context.Context, id int) (*domain.User, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	for i := range m.users {
+		if m.users[i].ID == id && m.users[i].DeletedAt != nil {
+			m.users[i].DeletedAt = nil
+			restored := m.users[i]
+			return &restored, nil
+		}
+	}
+	return nil, pkg.ErrUserNotFound
+}
+
+func (m *mockUserRepository) PurgeDeletedUsers(ctx context.Context, before time.Time) (int64, error) {
+	m.purgedBefore = before
+	return 0, m.err
+}
+
+var adminActor = &domain.Principal{UserID: 99, Role: domain.RoleAdmin}
+
 type mockTokenProvider struct {
 	token string
 	sub   string
 	err   error
 }
 
-func (m *mockTokenProvider) GenerateToken(userId string) (string, error) {
+func (m *mockTokenProvider) GenerateToken(claims domain.Claims) (string, error) {
 	return m.token, m.err
 }
 
-func (m *mockTokenProvider) ValidateToken(tokenString string) (string, error) {
-	return m.sub, m.err
+func (m *mockTokenProvider) ValidateToken(tokenString string) (*domain.Claims, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &domain.Claims{Subject: m.sub}, nil
+}
+
+type mockRefreshTokenRepository struct {
+	tokens  map[string]*domain.RefreshToken
+	revoked []string
+	nextID  int64
+}
+
+func newMockRefreshTokenRepository() *mockRefreshTokenRepository {
+	return &mockRefreshTokenRepository{tokens: map[string]*domain.RefreshToken{}}
+}
+
+func (m *mockRefreshTokenRepository) CreateRefreshToken(ctx context.Context, token *domain.RefreshToken) error {
+	m.nextID++
+	token.ID = m.nextID
+	m.tokens[token.TokenHash] = token
+	return nil
+}
+
+func (m *mockRefreshTokenRepository) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (*domain.RefreshToken, error) {
+	return m.tokens[tokenHash], nil
+}
+
+func (m *mockRefreshTokenRepository) MarkRefreshTokenUsed(ctx context.Context, id int64) (bool, error) {
+	for _, token := range m.tokens {
+		if token.ID == id {
+			if token.UsedAt != nil || token.RevokedAt != nil {
+				return false, nil
+			}
+			now := time.Now()
+			token.UsedAt = &now
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *mockRefreshTokenRepository) RevokeRefreshTokenFamily(ctx context.Context, familyID string) error {
+	now := time.Now()
+	for _, token := range m.tokens {
+		if token.FamilyID == familyID && token.RevokedAt == nil {
+			token.RevokedAt = &now
+		}
+	}
+	m.revoked = append(m.revoked, familyID)
+	return nil
+}
+
+func (m *mockRefreshTokenRepository) RevokeUserRefreshTokens(ctx context.Context, userID int) error {
+	now := time.Now()
+	for _, token := range m.tokens {
+		if token.UserID == userID && token.RevokedAt == nil {
+			token.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+type mockPasswordResetRepository struct {
+	resets map[string]*domain.PasswordReset
+	nextID int64
+}
+
+func newMockPasswordResetRepository() *mockPasswordResetRepository {
+	return &mockPasswordResetRepository{resets: map[string]*domain.PasswordReset{}}
+}
+
+func (m *mockPasswordResetRepository) CreatePasswordReset(ctx context.Context, reset *domain.PasswordReset) error {
+	m.nextID++
+	reset.ID = m.nextID
+	m.resets[reset.TokenHash] = reset
+	return nil
+}
+
+func (m *mockPasswordResetRepository) GetPasswordResetByHash(ctx context.Context, tokenHash string) (*domain.PasswordReset, error) {
+	return m.resets[tokenHash], nil
+}
+
+func (m *mockPasswordResetRepository) MarkPasswordResetUsed(ctx context.Context, id int64) (bool, error) {
+	for _, reset := range m.resets {
+		if reset.ID == id {
+			if reset.UsedAt != nil {
+				return false, nil
+			}
+			now := time.Now()
+			reset.UsedAt = &now
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *mockPasswordResetRepository) InvalidateUserPasswordResets(ctx context.Context, userID int) error {
+	now := time.Now()
+	for _, reset := range m.resets {
+		if reset.UserID == userID && reset.UsedAt == nil {
+			reset.UsedAt = &now
+		}
+	}
+	return nil
+}
+
+type mockEmailVerificationRepository struct {
+	verifications map[string]*domain.EmailVerification
+}
+
+func newMockEmailVerificationRepository() *mockEmailVerificationRepository {
+	return &mockEmailVerificationRepository{verifications: map[string]*domain.EmailVerification{}}
+}
+
+func (m *mockEmailVerificationRepository) CreateEmailVerification(ctx context.Context, verification *domain.EmailVerification) error {
+	verification.ID = int64(len(m.verifications) + 1)
+	m.verifications[verification.TokenHash] = verification
+	return nil
+}
+
+func (m *mockEmailVerificationRepository) GetEmailVerificationByHash(ctx context.Context, tokenHash string) (*domain.EmailVerification, error) {
+	return m.verifications[tokenHash], nil
+}
+
+func (m *mockEmailVerificationRepository) MarkEmailVerificationUsed(ctx context.Context, id int64) (bool, error) {
+	for _, verification := range m.verifications {
+		if verification.ID == id {
+			if verification.UsedAt != nil {
+				return false, nil
+			}
+			now := time.Now()
+			verification.UsedAt = &now
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+type mockNotifier struct {
+	sent []domain.Notification
+	err  error
+}
+
+func (m *mockNotifier) Notify(ctx context.Context, notification domain.Notification) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.sent = append(m.sent, notification)
+	return nil
+}
+
+// lastToken returns the token at the end of the last notification body.
+func (m *mockNotifier) lastToken() string {
+	if len(m.sent) == 0 {
+		return ""
+	}
+	lines := strings.Split(m.sent[len(m.sent)-1].Body, "\n")
+	return lines[len(lines)-1]
+}
+
+type mockMFARepository struct {
+	mfa           map[int]*domain.MFA
+	recoveryCodes map[string]bool
+}
+
+func newMockMFARepository() *mockMFARepository {
+	return &mockMFARepository{mfa: map[int]*domain.MFA{}, recoveryCodes: map[string]bool{}}
+}
+
+func (m *mockMFARepository) GetMFA(ctx context.Context, userID int) (*domain.MFA, error) {
+	mfa, ok := m.mfa[userID]
+	if !ok {
+		return nil, nil
+	}
+	copied := *mfa
+	return &copied, nil
+}
+
+func (m *mockMFARepository) SaveMFASecret(ctx context.Context, userID int, secret string) (bool, error) {
+	if m.mfa[userID].Enabled() {
+		return false, nil
+	}
+	m.mfa[userID] = &domain.MFA{UserID: userID, Secret: secret}
+	return true, nil
+}
+
+func (m *mockMFARepository) EnableMFA(ctx context.Context, userID int, recoveryCodeHashes []string) (bool, error) {
+	mfa := m.mfa[userID]
+	if mfa == nil || mfa.Enabled() {
+		return false, nil
+	}
+	now := time.Now()
+	mfa.EnabledAt = &now
+	for _, codeHash := range recoveryCodeHashes {
+		m.recoveryCodes[fmt.Sprintf("%d:%s", userID, codeHash)] = false
+	}
+	return true, nil
+}
+
+func (m *mockMFARepository) DisableMFA(ctx context.Context, userID int) error {
+	delete(m.mfa, userID)
+	return nil
+}
+
+func (m *mockMFARepository) UpdateMFALastUsedStep(ctx context.Context, userID int, step int64) (bool, error) {
+	if step <= m.mfa[userID].LastUsedStep {
+		return false, nil
+	}
+	m.mfa[userID].LastUsedStep = step
+	return true, nil
+}
+
+func (m *mockMFARepository) UseRecoveryCode(ctx context.Context, userID int, codeHash string) (bool, error) {
+	key := fmt.Sprintf("%d:%s", userID, codeHash)
+	used, ok := m.recoveryCodes[key]
+	if !ok || used {
+		return false, nil
+	}
+	m.recoveryCodes[key] = true
+	return true, nil
+}
+
+type mockAPIKeyRepository struct {
+	keys    map[int64]*domain.APIKey
+	touched int
+}
+
+func newMockAPIKeyRepository() *mockAPIKeyRepository {
+	return &mockAPIKeyRepository{keys: map[int64]*domain.APIKey{}}
+}
+
+func (m *mockAPIKeyRepository) CreateAPIKey(ctx context.Context, key *domain.APIKey) error {
+	key.ID = int64(len(m.keys) + 1)
+	key.CreatedAt = time.Now()
+	stored := *key
+	m.keys[key.ID] = &stored
+	return nil
+}
+
+func (m *mockAPIKeyRepository) GetAPIKeyByPrefix(ctx context.Context, prefix string) (*domain.APIKey, error) {
+	for _, key := range m.keys {
+		if key.Prefix == prefix {
+			copied := *key
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *mockAPIKeyRepository) ListUserAPIKeys(ctx context.Context, userID int) ([]*domain.APIKey, error) {
+	keys := []*domain.APIKey{}
+	for _, key := range m.keys {
+		if key.UserID == userID {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+func (m *mockAPIKeyRepository) RevokeAPIKey(ctx context.Context, userID int, id int64) (bool, error) {
+	key, ok := m.keys[id]
+	if !ok || key.UserID != userID || key.RevokedAt != nil {
+		return false, nil
+	}
+	now := time.Now()
+	key.RevokedAt = &now
+	return true, nil
+}
+
+func (m *mockAPIKeyRepository) TouchAPIKey(ctx context.Context, id int64, usedAt time.Time) error {
+	m.keys[id].LastUsedAt = &usedAt
+	m.touched++
+	return nil
+}
+
+type mockSessionRepository struct {
+	sessions map[string]*domain.Session
+}
+
+func newMockSessionRepository() *mockSessionRepository {
+	return &mockSessionRepository{sessions: map[string]*domain.Session{}}
+}
+
+func (m *mockSessionRepository) CreateSession(ctx context.Context, session *domain.Session) error {
+	session.CreatedAt = time.Now()
+	session.LastSeenAt = session.CreatedAt
+	m.sessions[session.ID] = session
+	return nil
+}
+
+func (m *mockSessionRepository) GetSession(ctx context.Context, id string) (*domain.Session, error) {
+	return m.sessions[id], nil
+}
+
+func (m *mockSessionRepository) ListUserSessions(ctx context.Context, userID int) ([]*domain.Session, error) {
+	sessions := []*domain.Session{}
+	for _, session := range m.sessions {
+		if session.UserID == userID && session.RevokedAt == nil {
+			sessions = append(sessions, session)
+		}
+	}
+	return sessions, nil
+}
+
+func (m *mockSessionRepository) TouchSession(ctx context.Context, id string, seenAt time.Time) error {
+	m.sessions[id].LastSeenAt = seenAt
+	return nil
+}
+
+func (m *mockSessionRepository) RevokeSession(ctx context.Context, userID int, id string) (bool, error) {
+	session, ok := m.sessions[id]
+	if !ok || session.UserID != userID || session.RevokedAt != nil {
+		return false, nil
+	}
+	now := time.Now()
+	session.RevokedAt = &now
+	return true, nil
+}
+
+func (m *mockSessionRepository) RevokeUserSessions(ctx context.Context, userID int) error {
+	for _, session := range m.sessions {
+		if session.UserID == userID && session.RevokedAt == nil {
+			now := time.Now()
+			session.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+func (m *mockSessionRepository) DeleteStaleSessions(ctx context.Context, before time.Time) (int64, error) {
+	var deleted int64
+	for id, session := range m.sessions {
+		if session.LastSeenAt.Before(before) {
+			delete(m.sessions, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+type mockIdentityProvider struct {
+	identity *domain.ExternalIdentity
+	verifier string
+	nonce    string
+}
+
+func (m *mockIdentityProvider) AuthCodeURL(ctx context.Context, state, nonce, codeVerifier string) (string, error) {
+	m.verifier, m.nonce = codeVerifier, nonce
+	return "https://idp.example.com/authorize?state=" + state, nil
+}
+
+func (m *mockIdentityProvider) Exchange(ctx context.Context, code, codeVerifier, nonce string) (*domain.ExternalIdentity, error) {
+	if code != "good-code" || codeVerifier != m.verifier || nonce != m.nonce {
+		return nil, errors.New("invalid_grant")
+	}
+	return m.identity, nil
+}
+
+type mockUserIdentityRepository struct {
+	identities []*domain.UserIdentity
+	created    []*domain.User
+}
+
+func (m *mockUserIdentityRepository) GetUserIdentity(ctx context.Context, provider, subject string) (*domain.UserIdentity, error) {
+	for _, identity := range m.identities {
+		if identity.Provider == provider && identity.Subject == subject {
+			return identity, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *mockUserIdentityRepository) CreateUserIdentity(ctx context.Context, identity *domain.UserIdentity) error {
+	identity.ID = int64(len(m.identities) + 1)
+	m.identities = append(m.identities, identity)
+	return nil
+}
+
+func (m *mockUserIdentityRepository) CreateUserWithIdentity(ctx context.Context, user *domain.User, identity *domain.UserIdentity) error {
+	user.ID = 100 + len(m.created)
+	m.created = append(m.created, user)
+	identity.UserID = user.ID
+	return m.CreateUserIdentity(ctx, identity)
+}
+
+func (m *mockUserIdentityRepository) TouchUserIdentity(ctx context.Context, id int64, loginAt time.Time) error {
+	m.identities[id-1].LastLoginAt = &loginAt
+	return nil
+}
+
+type mockOAuthStateRepository struct {
+	states map[string]*domain.OAuthState
+}
+
+func (m *mockOAuthStateRepository) CreateOAuthState(ctx context.Context, state *domain.OAuthState) error {
+	m.states[state.StateHash] = state
+	return nil
+}
+
+func (m *mockOAuthStateRepository) ConsumeOAuthState(ctx context.Context, stateHash string) (*domain.OAuthState, error) {
+	state := m.states[stateHash]
+	delete(m.states, stateHash)
+	return state, nil
+}
+
+func (m *mockOAuthStateRepository) DeleteExpiredOAuthStates(ctx context.Context, before time.Time) (int64, error) {
+	var deleted int64
+	for hash, state := range m.states {
+		if state.ExpiresAt.Before(before) {
+			delete(m.states, hash)
+			deleted++
+		}
+	}
+	return deleted, nil
 }
 
 func TestUserService_Login(t *testing.T) {
@@ -84,6 +599,7 @@ func TestUserService_Login(t *testing.T) {
 				ID:       1,
 				Email:    "test@example.com",
 				Password: passwordHash,
+				IsActive: true,
 			},
 		},
 	}
@@ -100,8 +616,8 @@ func TestUserService_Login(t *testing.T) {
 	if err != nil {
 		t.Errorf("Login failed: %v", err)
 	}
-	if token != tokenProvider.token {
-		t.Errorf("Login token mismatch: expected %s, got %s", tokenProvider.token, token)
+	if token.Token != tokenProvider.token {
+		t.Errorf("Login token mismatch: expected %s, got %s", tokenProvider.token, token.Token)
 	}
 }
 
@@ -114,6 +630,7 @@ func TestUserService_Login_InvalidCredentials(t *testing.T) {
 				ID:       1,
 				Email:    "test@example.com",
 				Password: passwordHash,
+				IsActive: true,
 			},
 		},
 	}
@@ -147,8 +664,8 @@ func TestUserService_Login_InvalidEmail(t *testing.T) {
 		Email:    "test@example.com",
 		Password: "password123",
 	})
-	if err == nil || err != pkg.ErrUserNotFound {
-		t.Errorf("Login should have failed with invalid email: %v", err)
+	if err == nil || err != pkg.ErrInvalidCredentials {
+		t.Errorf("Login should have failed with invalid credentials: %v", err)
 	}
 }
 
@@ -241,6 +758,7 @@ func TestUserService_Login_GenerateToken(t *testing.T) {
 				ID:       1,
 				Email:    "test@example.com",
 				Password: passwordHash,
+				IsActive: true,
 			},
 		},
 	}
@@ -257,8 +775,8 @@ func TestUserService_Login_GenerateToken(t *testing.T) {
 	if err != nil {
 		t.Errorf("Login failed: %v", err)
 	}
-	if token != tokenProvider.token {
-		t.Errorf("Login token mismatch: expected %s, got %s", tokenProvider.token, token)
+	if token.Token != tokenProvider.token {
+		t.Errorf("Login token mismatch: expected %s, got %s", tokenProvider.token, token.Token)
 	}
 }
 
@@ -290,7 +808,7 @@ func TestUserService_Register(t *testing.T) {
 	}
 	userService := NewUserService(repo, tokenProvider)
 	// Test Register with valid user
-	err := userService.Register(context.Background(), &domain.User{
+	_, err := userService.Register(context.Background(), &domain.User{
 		Email:     "test@example.com",
 		Password:  "password123",
 		Username:  "testuser",
@@ -311,7 +829,7 @@ func TestUserService_Register_EmptyEmail(t *testing.T) {
 	}
 	userService := NewUserService(repo, tokenProvider)
 	// Test Register with empty email
-	err := userService.Register(context.Background(), &domain.User{
+	_, err := userService.Register(context.Background(), &domain.User{
 		Email:     "",
 		Password:  "password123",
 		Username:  "testuser",
@@ -332,7 +850,7 @@ func TestUserService_Register_EmptyPassword(t *testing.T) {
 	}
 	userService := NewUserService(repo, tokenProvider)
 	// Test Register with empty password
-	err := userService.Register(context.Background(), &domain.User{
+	_, err := userService.Register(context.Background(), &domain.User{
 		Email:     "test@example.com",
 		Password:  "",
 		Username:  "testuser",
@@ -353,7 +871,7 @@ func TestUserService_Register_EmptyUsername(t *testing.T) {
 	}
 	userService := NewUserService(repo, tokenProvider)
 	// Test Register with empty username
-	err := userService.Register(context.Background(), &domain.User{
+	_, err := userService.Register(context.Background(), &domain.User{
 		Email:     "test@example.com",
 		Password:  "password123",
 		Username:  "",
@@ -374,7 +892,7 @@ func TestUserService_Register_EmptyFirstName(t *testing.T) {
 	}
 	userService := NewUserService(repo, tokenProvider)
 	// Test Register with empty first name
-	err := userService.Register(context.Background(), &domain.User{
+	_, err := userService.Register(context.Background(), &domain.User{
 		Email:     "test@example.com",
 		Password:  "password123",
 		Username:  "testuser",
@@ -395,7 +913,7 @@ func TestUserService_Register_EmptyLastName(t *testing.T) {
 	}
 	userService := NewUserService(repo, tokenProvider)
 	// Test Register with empty last name
-	err := userService.Register(context.Background(), &domain.User{
+	_, err := userService.Register(context.Background(), &domain.User{
 		Email:     "test@example.com",
 		Password:  "password123",
 		Username:  "testuser",
@@ -420,7 +938,7 @@ func TestUserService_Register_UserAlreadyExists(t *testing.T) {
 	}
 	userService := NewUserService(repo, tokenProvider)
 	// Test Register with user already exists
-	err := userService.Register(context.Background(), &domain.User{
+	_, err := userService.Register(context.Background(), &domain.User{
 		Email:     "test@example.com",
 		Password:  "password123",
 		Username:  "testuser",
@@ -449,7 +967,7 @@ func TestUserService_UpdatePassword(t *testing.T) {
 	}
 	userService := NewUserService(repo, tokenProvider)
 	// Test UpdatePassword with valid user
-	err = userService.UpdatePassword(context.Background(), 1, domain.UpdatePasswordRequest{
+	err = userService.UpdatePassword(context.Background(), adminActor, 1, domain.UpdatePasswordRequest{
 		NewPassword: "newpassword123",
 	})
 	if err != nil {
@@ -468,7 +986,7 @@ func TestUserService_UpdatePassword_UserNotFound(t *testing.T) {
 	}
 	userService := NewUserService(repo, tokenProvider)
 	// Test UpdatePassword with user not found
-	err := userService.UpdatePassword(context.Background(), 2, domain.UpdatePasswordRequest{
+	err := userService.UpdatePassword(context.Background(), adminActor, 2, domain.UpdatePasswordRequest{
 		NewPassword: "newpassword123",
 	})
 	if err != pkg.ErrUserNotFound {
@@ -485,7 +1003,7 @@ func TestUserService_UpdatePassword_EmptyPassword(t *testing.T) {
 	}
 	userService := NewUserService(repo, tokenProvider)
 	// Test UpdatePassword with empty password
-	err := userService.UpdatePassword(context.Background(), 1, domain.UpdatePasswordRequest{
+	err := userService.UpdatePassword(context.Background(), adminActor, 1, domain.UpdatePasswordRequest{
 		NewPassword: "",
 	})
 	if err == nil {
@@ -502,7 +1020,7 @@ func TestUserService_UpdatePassword_InvalidPassword(t *testing.T) {
 	}
 	userService := NewUserService(repo, tokenProvider)
 	// Test UpdatePassword with invalid password
-	err := userService.UpdatePassword(context.Background(), 1, domain.UpdatePasswordRequest{
+	err := userService.UpdatePassword(context.Background(), adminActor, 1, domain.UpdatePasswordRequest{
 		NewPassword: "123456",
 	})
 	if err == nil {
@@ -523,7 +1041,7 @@ func TestUserService_UpdateUser(t *testing.T) {
 	}
 	userService := NewUserService(repo, tokenProvider)
 	// Test UpdateUser with valid user
-	err := userService.UpdateUser(context.Background(), 1, &domain.UpdateUserRequest{
+	_, err := userService.UpdateUser(context.Background(), adminActor, 1, &domain.UpdateUserRequest{
 		FirstName: "New",
 		LastName:  "User",
 	})
@@ -543,7 +1061,7 @@ func TestUserService_UpdateUser_UserNotFound(t *testing.T) {
 	}
 	userService := NewUserService(repo, tokenProvider)
 	// Test UpdateUser with user not found
-	err := userService.UpdateUser(context.Background(), 2, &domain.UpdateUserRequest{
+	_, err := userService.UpdateUser(context.Background(), adminActor, 2, &domain.UpdateUserRequest{
 		FirstName: "New",
 		LastName:  "User",
 	})
@@ -552,6 +1070,47 @@ func TestUserService_UpdateUser_UserNotFound(t *testing.T) {
 	}
 }
 
+func TestUserService_PatchUser(t *testing.T) {
+	repo := &mockUserRepository{
+		users: []domain.User{
+			{ID: 1, Email: "test@example.com", FirstName: "Test", LastName: "User"},
+		},
+	}
+	userService := NewUserService(repo, &mockTokenProvider{})
+	actor := &domain.Principal{UserID: 1, Role: domain.RoleUser}
+
+	cases := []struct {
+		patch  domain.Patch
+		fields []string
+		err    error
+	}{
+		{domain.Patch{Type: domain.MergePatchType, Body: []byte(`{"first_name":"Patched"}`)}, []string{"first_name"}, nil},
+		{domain.Patch{Type: domain.JSONPatchType, Body: []byte(`[{"op":"replace","path":"/last_name","value":"Person"}]`)}, []string{"last_name"}, nil},
+		{domain.Patch{Type: domain.MergePatchType, Body: []byte(`{"first_name":"Test"}`)}, nil, nil},
+		{domain.Patch{Type: domain.MergePatchType, Body: []byte(`{"first_name":null}`)}, nil, pkg.ErrValidationError},
+		{domain.Patch{Type: domain.MergePatchType, Body: []byte(`{"email":"new@example.com"}`)}, nil, pkg.ErrValidationError},
+		{domain.Patch{Type: domain.JSONPatchType, Body: []byte(`[{"op":"remove","path":"/email"}]`)}, nil, pkg.ErrPatchFailed},
+		{domain.Patch{Type: domain.JSONPatchType, Body: []byte(`{"op":"remove"}`)}, nil, pkg.ErrInvalidPatch},
+		{domain.Patch{Type: "application/json", Body: []byte(`{}`)}, nil, pkg.ErrUnsupportedPatch},
+	}
+	for _, tc := range cases {
+		repo.fields = nil
+		user, err := userService.PatchUser(context.Background(), actor, 1, tc.patch)
+		if !errors.Is(err, tc.err) {
+			t.Errorf("PatchUser(%s) error = %v, want %v", tc.patch.Body, err, tc.err)
+			continue
+		}
+		if err == nil && (user == nil || fmt.Sprint(repo.fields) != fmt.Sprint(tc.fields)) {
+			t.Errorf("PatchUser(%s) wrote %v, want %v", tc.patch.Body, repo.fields, tc.fields)
+		}
+	}
+
+	other := &domain.Principal{UserID: 2, Role: domain.RoleUser}
+	if _, err := userService.PatchUser(context.Background(), other, 1, domain.Patch{Type: domain.MergePatchType, Body: []byte(`{}`)}); err != pkg.ErrForbidden {
+		t.Errorf("PatchUser() of another user error = %v, want %v", err, pkg.ErrForbidden)
+	}
+}
+
 func TestUserService_UpdateUser_EmptyFirstName(t *testing.T) {
 	// Test UpdateUser with empty first name
 	repo := &mockUserRepository{
@@ -565,7 +1124,7 @@ func TestUserService_UpdateUser_EmptyFirstName(t *testing.T) {
 	}
 	userService := NewUserService(repo, tokenProvider)
 	// Test UpdateUser with empty first name
-	err := userService.UpdateUser(context.Background(), 1, &domain.UpdateUserRequest{
+	_, err := userService.UpdateUser(context.Background(), adminActor, 1, &domain.UpdateUserRequest{
 		FirstName: "",
 		LastName:  "User",
 	})
@@ -587,7 +1146,7 @@ func TestUserService_UpdateUser_EmptyLastName(t *testing.T) {
 	}
 	userService := NewUserService(repo, tokenProvider)
 	// Test UpdateUser with empty last name
-	err := userService.UpdateUser(context.Background(), 1, &domain.UpdateUserRequest{
+	_, err := userService.UpdateUser(context.Background(), adminActor, 1, &domain.UpdateUserRequest{
 		FirstName: "New",
 		LastName:  "",
 	})
@@ -607,7 +1166,7 @@ func TestUserService_UpdateUser_InvalidUserID(t *testing.T) {
 	}
 	userService := NewUserService(repo, tokenProvider)
 	// Test UpdateUser with invalid user ID
-	err := userService.UpdateUser(context.Background(), 0, &domain.UpdateUserRequest{
+	_, err := userService.UpdateUser(context.Background(), adminActor, 0, &domain.UpdateUserRequest{
 		FirstName: "New",
 		LastName:  "User",
 	})
@@ -629,7 +1188,7 @@ func TestUserService_GetUserByID(t *testing.T) {
 	}
 	userService := NewUserService(repo, tokenProvider)
 	// Test GetUserByID with valid user ID
-	user, err := userService.GetUserByID(context.Background(), "1")
+	user, err := userService.GetUserByID(context.Background(), adminActor, "1")
 	if err != nil {
 		t.Errorf("GetUserByID failed: %v", err)
 	}
@@ -649,7 +1208,7 @@ func TestUserService_GetUserByID_UserNotFound(t *testing.T) {
 	}
 	userService := NewUserService(repo, tokenProvider)
 	// Test GetUserByID with user not found
-	_, err := userService.GetUserByID(context.Background(), "2")
+	_, err := userService.GetUserByID(context.Background(), adminActor, "2")
 	fmt.Println(err)
 	if err != pkg.ErrUserNotFound {
 		t.Errorf("GetUserByID should have failed with user not found: %v", err)
@@ -749,7 +1308,7 @@ func TestUserService_DeleteUser(t *testing.T) {
 	}
 	userService := NewUserService(repo, tokenProvider)
 	// Test DeleteUser with valid user ID
-	err := userService.DeleteUser(context.Background(), 1)
+	err := userService.DeleteUser(context.Background(), adminActor, 1)
 	if err != nil {
 		t.Errorf("DeleteUser failed: %v", err)
 	}
@@ -766,9 +1325,1231 @@ func TestUserService_DeleteUser_UserNotFound(t *testing.T) {
 	}
 	userService := NewUserService(repo, tokenProvider)
 	// Test DeleteUser with user not found
-	err := userService.DeleteUser(context.Background(), 2)
+	err := userService.DeleteUser(context.Background(), adminActor, 2)
 	fmt.Println(err)
 	if err != pkg.ErrUserNotFound {
 		t.Errorf("DeleteUser should have failed with user not found: %v", err)
 	}
 }
+
+func TestUserService_DeleteUser_RevokesTokens(t *testing.T) {
+	// Test deleting a user signs them out everywhere
+	repo := &mockUserRepository{users: []domain.User{{ID: 1, Email: "test@example.com"}}}
+	store := memory.NewTokenRevocationStore()
+	userService := NewUserService(repo, &mockTokenProvider{}, WithTokenRevocation(store, time.Minute))
+	issuedAt := time.Now().Truncate(time.Microsecond)
+	if err := userService.DeleteUser(context.Background(), adminActor, 1); err != nil {
+		t.Fatalf("DeleteUser failed: %v", err)
+	}
+	if revoked, _ := store.IsRevoked(context.Background(), "any", 1, issuedAt); !revoked {
+		t.Errorf("DeleteUser should have revoked existing tokens")
+	}
+}
+
+func TestUserService_Trash(t *testing.T) {
+	// Test listing and restoring deleted users is limited to user managers
+	deletedAt := time.Now()
+	repo := &mockUserRepository{users: []domain.User{{ID: 1, Email: "test@example.com", DeletedAt: &deletedAt}}}
+	userService := NewUserService(repo, &mockTokenProvider{})
+	member := &domain.Principal{UserID: 2, Role: domain.RoleUser}
+	if _, err := userService.GetDeletedUsers(context.Background(), member); err != pkg.ErrForbidden {
+		t.Errorf("GetDeletedUsers should have failed with forbidden: %v", err)
+	}
+	if _, err := userService.RestoreUser(context.Background(), member, 1); err != pkg.ErrForbidden {
+		t.Errorf("RestoreUser should have failed with forbidden: %v", err)
+	}
+	users, err := userService.GetDeletedUsers(context.Background(), adminActor)
+	if err != nil || len(users) != 1 || users[0].ID != 1 {
+		t.Fatalf("GetDeletedUsers returned %v, %v", users, err)
+	}
+	restored, err := userService.RestoreUser(context.Background(), adminActor, 1)
+	if err != nil || restored.DeletedAt != nil {
+		t.Fatalf("RestoreUser returned %+v, %v", restored, err)
+	}
+	if _, err := userService.RestoreUser(context.Background(), adminActor, 1); err != pkg.ErrUserNotFound {
+		t.Errorf("RestoreUser should have failed with user not found: %v", err)
+	}
+}
+
+func TestUserService_PurgeDeletedUsers(t *testing.T) {
+	// Test the purge only runs with a retention period and honours it
+	repo := &mockUserRepository{}
+	if _, err := NewUserService(repo, &mockTokenProvider{}).PurgeDeletedUsers(context.Background()); err != nil || !repo.purgedBefore.IsZero() {
+		t.Fatalf("PurgeDeletedUsers without retention should do nothing: %v", err)
+	}
+	userService := NewUserService(repo, &mockTokenProvider{}, WithTrashRetention(24*time.Hour))
+	start := time.Now()
+	if _, err := userService.PurgeDeletedUsers(context.Background()); err != nil {
+		t.Fatalf("PurgeDeletedUsers failed: %v", err)
+	}
+	if age := start.Sub(repo.purgedBefore); age > 24*time.Hour || age < 24*time.Hour-time.Minute {
+		t.Errorf("PurgeDeletedUsers used cutoff %v ago", age)
+	}
+}
+
+func TestUserService_Login_IssuesRefreshToken(t *testing.T) {
+	// Test Login returns a refresh token when refresh tokens are enabled
+	passwordHash, _ := pkg.HashPassword("password123")
+	repo := &mockUserRepository{
+		users: []domain.User{
+			{ID: 1, Email: "test@example.com", Password: passwordHash, IsActive: true},
+		},
+	}
+	refreshRepo := newMockRefreshTokenRepository()
+	userService := NewUserService(repo, &mockTokenProvider{token: "token"}, WithRefreshTokens(refreshRepo, time.Hour))
+	resp, err := userService.Login(context.Background(), &domain.AuthRequest{
+		Email:    "test@example.com",
+		Password: "password123",
+	})
+	if err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+	if resp.RefreshToken == "" {
+		t.Fatalf("Login should have returned a refresh token")
+	}
+	stored := refreshRepo.tokens[pkg.HashToken(resp.RefreshToken)]
+	if stored == nil || stored.UserID != 1 || stored.FamilyID == "" {
+		t.Errorf("Login stored unexpected refresh token: %#v", stored)
+	}
+}
+
+func TestUserService_RefreshToken_Rotates(t *testing.T) {
+	// Test RefreshToken returns a new pair in the same family
+	repo := &mockUserRepository{
+		users: []domain.User{{ID: 1, Email: "test@example.com", IsActive: true}},
+	}
+	refreshRepo := newMockRefreshTokenRepository()
+	userService := NewUserService(repo, &mockTokenProvider{token: "token"}, WithRefreshTokens(refreshRepo, time.Hour))
+	first, err := userService.issueTokens(context.Background(), &repo.users[0], "")
+	if err != nil {
+		t.Fatalf("issueTokens failed: %v", err)
+	}
+	second, err := userService.RefreshToken(context.Background(), &domain.RefreshRequest{RefreshToken: first.RefreshToken})
+	if err != nil {
+		t.Fatalf("RefreshToken failed: %v", err)
+	}
+	if second.RefreshToken == "" || second.RefreshToken == first.RefreshToken {
+		t.Fatalf("RefreshToken should have rotated the refresh token")
+	}
+	old := refreshRepo.tokens[pkg.HashToken(first.RefreshToken)]
+	rotated := refreshRepo.tokens[pkg.HashToken(second.RefreshToken)]
+	if old.UsedAt == nil {
+		t.Errorf("RefreshToken should have marked the old token as used")
+	}
+	if rotated.FamilyID != old.FamilyID {
+		t.Errorf("RefreshToken family mismatch: expected %s, got %s", old.FamilyID, rotated.FamilyID)
+	}
+}
+
+func TestUserService_RefreshToken_ReuseRevokesFamily(t *testing.T) {
+	// Test replaying a rotated refresh token revokes the family
+	repo := &mockUserRepository{
+		users: []domain.User{{ID: 1, Email: "test@example.com", IsActive: true}},
+	}
+	refreshRepo := newMockRefreshTokenRepository()
+	userService := NewUserService(repo, &mockTokenProvider{token: "token"}, WithRefreshTokens(refreshRepo, time.Hour))
+	first, _ := userService.issueTokens(context.Background(), &repo.users[0], "")
+	second, err := userService.RefreshToken(context.Background(), &domain.RefreshRequest{RefreshToken: first.RefreshToken})
+	if err != nil {
+		t.Fatalf("RefreshToken failed: %v", err)
+	}
+	_, err = userService.RefreshToken(context.Background(), &domain.RefreshRequest{RefreshToken: first.RefreshToken})
+	if err != pkg.ErrRefreshTokenReused {
+		t.Fatalf("RefreshToken should have failed with reuse detected: %v", err)
+	}
+	_, err = userService.RefreshToken(context.Background(), &domain.RefreshRequest{RefreshToken: second.RefreshToken})
+	if err != pkg.ErrInvalidRefreshToken {
+		t.Errorf("RefreshToken should reject tokens from a revoked family: %v", err)
+	}
+}
+
+func TestUserService_RefreshToken_Expired(t *testing.T) {
+	// Test RefreshToken with an expired token
+	repo := &mockUserRepository{
+		users: []domain.User{{ID: 1, Email: "test@example.com"}},
+	}
+	refreshRepo := newMockRefreshTokenRepository()
+	userService := NewUserService(repo, &mockTokenProvider{token: "token"}, WithRefreshTokens(refreshRepo, -time.Minute))
+	first, _ := userService.issueTokens(context.Background(), &repo.users[0], "")
+	_, err := userService.RefreshToken(context.Background(), &domain.RefreshRequest{RefreshToken: first.RefreshToken})
+	if err != pkg.ErrInvalidRefreshToken {
+		t.Errorf("RefreshToken should have failed with invalid refresh token: %v", err)
+	}
+}
+
+func TestUserService_RefreshToken_Unknown(t *testing.T) {
+	// Test RefreshToken with a token that was never issued
+	refreshRepo := newMockRefreshTokenRepository()
+	userService := NewUserService(&mockUserRepository{}, &mockTokenProvider{token: "token"}, WithRefreshTokens(refreshRepo, time.Hour))
+	_, err := userService.RefreshToken(context.Background(), &domain.RefreshRequest{RefreshToken: "unknown"})
+	if err != pkg.ErrInvalidRefreshToken {
+		t.Errorf("RefreshToken should have failed with invalid refresh token: %v", err)
+	}
+}
+
+func TestUserService_UpdatePassword_Self(t *testing.T) {
+	// Test UpdatePassword on your own account with the current password
+	password, _ := pkg.HashPassword("oldpassword123")
+	repo := &mockUserRepository{
+		users: []domain.User{
+			{ID: 1, Email: "test@example.com", Password: password},
+		},
+	}
+	userService := NewUserService(repo, &mockTokenProvider{})
+	self := &domain.Principal{UserID: 1, Role: domain.RoleUser}
+	err := userService.UpdatePassword(context.Background(), self, 1, domain.UpdatePasswordRequest{
+		CurrentPassword: "oldpassword123",
+		NewPassword:     "newpassword123",
+	})
+	if err != nil {
+		t.Errorf("UpdatePassword failed: %v", err)
+	}
+}
+
+func TestUserService_UpdatePassword_Self_WrongCurrentPassword(t *testing.T) {
+	// Test UpdatePassword on your own account with a wrong or missing current password
+	password, _ := pkg.HashPassword("oldpassword123")
+	repo := &mockUserRepository{
+		users: []domain.User{
+			{ID: 1, Email: "test@example.com", Password: password},
+		},
+	}
+	userService := NewUserService(repo, &mockTokenProvider{})
+	self := &domain.Principal{UserID: 1, Role: domain.RoleUser}
+	for _, current := range []string{"", "wrongpassword"} {
+		err := userService.UpdatePassword(context.Background(), self, 1, domain.UpdatePasswordRequest{
+			CurrentPassword: current,
+			NewPassword:     "newpassword123",
+		})
+		if err != pkg.ErrInvalidCurrentPassword {
+			t.Errorf("UpdatePassword should have failed with invalid current password for %q: %v", current, err)
+		}
+	}
+}
+
+func TestUserService_OtherUser_Forbidden(t *testing.T) {
+	// Test a regular user acting on another user's account
+	repo := &mockUserRepository{
+		users: []domain.User{{ID: 2, Email: "other@example.com"}},
+	}
+	userService := NewUserService(repo, &mockTokenProvider{})
+	actor := &domain.Principal{UserID: 1, Role: domain.RoleUser}
+	if _, err := userService.GetUserByID(context.Background(), actor, "2"); err != pkg.ErrForbidden {
+		t.Errorf("GetUserByID should have failed with forbidden: %v", err)
+	}
+	if _, err := userService.UpdateUser(context.Background(), actor, 2, &domain.UpdateUserRequest{FirstName: "New", LastName: "User"}); err != pkg.ErrForbidden {
+		t.Errorf("UpdateUser should have failed with forbidden: %v", err)
+	}
+	if err := userService.UpdatePassword(context.Background(), actor, 2, domain.UpdatePasswordRequest{NewPassword: "newpassword123"}); err != pkg.ErrForbidden {
+		t.Errorf("UpdatePassword should have failed with forbidden: %v", err)
+	}
+	if err := userService.DeleteUser(context.Background(), actor, 2); err != pkg.ErrForbidden {
+		t.Errorf("DeleteUser should have failed with forbidden: %v", err)
+	}
+}
+
+func TestUserService_Logout(t *testing.T) {
+	// Test Logout revokes the access token and the refresh token family
+	repo := &mockUserRepository{
+		users: []domain.User{{ID: 1, Email: "test@example.com"}},
+	}
+	refreshRepo := newMockRefreshTokenRepository()
+	store := memory.NewTokenRevocationStore()
+	userService := NewUserService(repo, &mockTokenProvider{token: "token"},
+		WithRefreshTokens(refreshRepo, time.Hour),
+		WithTokenRevocation(store, time.Minute))
+	pair, _ := userService.issueTokens(context.Background(), &repo.users[0], "")
+	actor := &domain.Principal{UserID: 1, Role: domain.RoleUser, TokenID: "jti-1", TokenExpiresAt: time.Now().Add(time.Minute)}
+	if err := userService.Logout(context.Background(), actor, &domain.LogoutRequest{RefreshToken: pair.RefreshToken}); err != nil {
+		t.Fatalf("Logout failed: %v", err)
+	}
+	revoked, _ := store.IsRevoked(context.Background(), "jti-1", 1, time.Now())
+	if !revoked {
+		t.Errorf("Logout should have revoked the access token")
+	}
+	_, err := userService.RefreshToken(context.Background(), &domain.RefreshRequest{RefreshToken: pair.RefreshToken})
+	if err != pkg.ErrInvalidRefreshToken {
+		t.Errorf("Logout should have revoked the refresh token: %v", err)
+	}
+}
+
+func TestUserService_RevokeUserTokens(t *testing.T) {
+	// Test RevokeUserTokens rejects tokens issued before the revocation
+	repo := &mockUserRepository{
+		users: []domain.User{{ID: 2, Email: "test@example.com"}},
+	}
+	refreshRepo := newMockRefreshTokenRepository()
+	store := memory.NewTokenRevocationStore()
+	userService := NewUserService(repo, &mockTokenProvider{token: "token"},
+		WithRefreshTokens(refreshRepo, time.Hour),
+		WithTokenRevocation(store, time.Minute))
+	pair, _ := userService.issueTokens(context.Background(), &repo.users[0], "")
+	issuedAt := time.Now().Truncate(time.Microsecond)
+	if err := userService.RevokeUserTokens(context.Background(), adminActor, 2); err != nil {
+		t.Fatalf("RevokeUserTokens failed: %v", err)
+	}
+	// issuedAt is usually in the same second as the revocation
+	revoked, _ := store.IsRevoked(context.Background(), "any", 2, issuedAt)
+	if !revoked {
+		t.Errorf("RevokeUserTokens should have revoked earlier access tokens")
+	}
+	revoked, _ = store.IsRevoked(context.Background(), "any", 2, issuedAt.Truncate(time.Second))
+	if !revoked {
+		t.Errorf("RevokeUserTokens should have revoked tokens issued earlier in the same second")
+	}
+	revoked, _ = store.IsRevoked(context.Background(), "any", 2, time.Now().Add(time.Millisecond))
+	if revoked {
+		t.Errorf("RevokeUserTokens should not revoke tokens issued afterwards")
+	}
+	if _, err := userService.RefreshToken(context.Background(), &domain.RefreshRequest{RefreshToken: pair.RefreshToken}); err != pkg.ErrInvalidRefreshToken {
+		t.Errorf("RevokeUserTokens should have revoked refresh tokens: %v", err)
+	}
+	if err := userService.RevokeUserTokens(context.Background(), &domain.Principal{UserID: 1, Role: domain.RoleUser}, 2); err != pkg.ErrForbidden {
+		t.Errorf("RevokeUserTokens should have failed with forbidden: %v", err)
+	}
+}
+
+func TestUserService_CleanupRevocations(t *testing.T) {
+	// Test CleanupRevocations removes expired entries
+	store := memory.NewTokenRevocationStore()
+	userService := NewUserService(&mockUserRepository{}, &mockTokenProvider{}, WithTokenRevocation(store, time.Minute))
+	_ = store.RevokeToken(context.Background(), "expired", time.Now().Add(-time.Minute))
+	_ = store.RevokeToken(context.Background(), "active", time.Now().Add(time.Minute))
+	deleted, err := userService.CleanupRevocations(context.Background())
+	if err != nil {
+		t.Fatalf("CleanupRevocations failed: %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("CleanupRevocations deleted %d entries, expected 1", deleted)
+	}
+}
+
+func TestUserService_ForgotPassword_ResetPassword(t *testing.T) {
+	// Test the full forgot/reset flow
+	repo := &mockUserRepository{
+		users: []domain.User{{ID: 1, Email: "test@example.com"}},
+	}
+	resets := newMockPasswordResetRepository()
+	notifier := &mockNotifier{}
+	store := memory.NewTokenRevocationStore()
+	userService := NewUserService(repo, &mockTokenProvider{},
+		WithNotifier(notifier),
+		WithPasswordReset(resets, time.Hour, ""),
+		WithTokenRevocation(store, time.Minute))
+	issuedAt := time.Now().Truncate(time.Microsecond)
+	if err := userService.ForgotPassword(context.Background(), &domain.ForgotPasswordRequest{Email: "test@example.com"}); err != nil {
+		t.Fatalf("ForgotPassword failed: %v", err)
+	}
+	if len(notifier.sent) != 1 || notifier.sent[0].To != "test@example.com" {
+		t.Fatalf("ForgotPassword should have notified the user: %#v", notifier.sent)
+	}
+	token := notifier.lastToken()
+	err := userService.ResetPassword(context.Background(), &domain.ResetPasswordRequest{Token: token, NewPassword: "newpassword123"})
+	if err != nil {
+		t.Fatalf("ResetPassword failed: %v", err)
+	}
+	revoked, _ := store.IsRevoked(context.Background(), "any", 1, issuedAt)
+	if !revoked {
+		t.Errorf("ResetPassword should have revoked existing sessions")
+	}
+	// Tokens are single-use
+	err = userService.ResetPassword(context.Background(), &domain.ResetPasswordRequest{Token: token, NewPassword: "newpassword456"})
+	if err != pkg.ErrInvalidResetToken {
+		t.Errorf("ResetPassword should have failed with invalid reset token: %v", err)
+	}
+}
+
+func TestUserService_ForgotPassword_UnknownEmail(t *testing.T) {
+	// Test ForgotPassword does not reveal unknown emails
+	notifier := &mockNotifier{}
+	userService := NewUserService(&mockUserRepository{}, &mockTokenProvider{},
+		WithNotifier(notifier),
+		WithPasswordReset(newMockPasswordResetRepository(), time.Hour, ""))
+	if err := userService.ForgotPassword(context.Background(), &domain.ForgotPasswordRequest{Email: "nobody@example.com"}); err != nil {
+		t.Errorf("ForgotPassword should succeed for unknown emails: %v", err)
+	}
+	if len(notifier.sent) != 0 {
+		t.Errorf("ForgotPassword should not notify unknown emails")
+	}
+}
+
+func TestUserService_ResetPassword_Expired(t *testing.T) {
+	// Test ResetPassword with an expired token
+	repo := &mockUserRepository{
+		users: []domain.User{{ID: 1, Email: "test@example.com"}},
+	}
+	notifier := &mockNotifier{}
+	userService := NewUserService(repo, &mockTokenProvider{},
+		WithNotifier(notifier),
+		WithPasswordReset(newMockPasswordResetRepository(), -time.Minute, ""))
+	_ = userService.ForgotPassword(context.Background(), &domain.ForgotPasswordRequest{Email: "test@example.com"})
+	err := userService.ResetPassword(context.Background(), &domain.ResetPasswordRequest{Token: notifier.lastToken(), NewPassword: "newpassword123"})
+	if err != pkg.ErrInvalidResetToken {
+		t.Errorf("ResetPassword should have failed with invalid reset token: %v", err)
+	}
+}
+
+func TestUserService_ForgotPassword_OnlyLatestTokenValid(t *testing.T) {
+	// Test requesting a new link invalidates the previous one
+	repo := &mockUserRepository{
+		users: []domain.User{{ID: 1, Email: "test@example.com"}},
+	}
+	notifier := &mockNotifier{}
+	userService := NewUserService(repo, &mockTokenProvider{},
+		WithNotifier(notifier),
+		WithPasswordReset(newMockPasswordResetRepository(), time.Hour, "http://localhost/reset"))
+	_ = userService.ForgotPassword(context.Background(), &domain.ForgotPasswordRequest{Email: "test@example.com"})
+	first := notifier.lastToken()
+	_ = userService.ForgotPassword(context.Background(), &domain.ForgotPasswordRequest{Email: "test@example.com"})
+	if !strings.HasPrefix(first, "http://localhost/reset?token=") {
+		t.Fatalf("ForgotPassword should have sent a link, got %s", first)
+	}
+	token := strings.TrimPrefix(first, "http://localhost/reset?token=")
+	err := userService.ResetPassword(context.Background(), &domain.ResetPasswordRequest{Token: token, NewPassword: "newpassword123"})
+	if err != pkg.ErrInvalidResetToken {
+		t.Errorf("ResetPassword should reject superseded tokens: %v", err)
+	}
+}
+
+func TestUserService_Register_SendsVerification(t *testing.T) {
+	// Test Register sends a verification link that VerifyEmail accepts once
+	repo := &mockUserRepository{}
+	notifier := &mockNotifier{}
+	userService := NewUserService(repo, &mockTokenProvider{},
+		WithNotifier(notifier),
+		WithEmailVerification(newMockEmailVerificationRepository(), time.Hour, "", true))
+	_, err := userService.Register(context.Background(), &domain.User{
+		Email:     "test@example.com",
+		Password:  "password123",
+		Username:  "testuser",
+		FirstName: "Test",
+		LastName:  "User",
+	})
+	if err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	if len(notifier.sent) != 1 || notifier.sent[0].To != "test@example.com" {
+		t.Fatalf("Register should have sent a verification link: %#v", notifier.sent)
+	}
+	token := notifier.lastToken()
+	if err := userService.VerifyEmail(context.Background(), token); err != nil {
+		t.Errorf("VerifyEmail failed: %v", err)
+	}
+	if err := userService.VerifyEmail(context.Background(), token); err != pkg.ErrInvalidVerificationToken {
+		t.Errorf("VerifyEmail should reject a used token: %v", err)
+	}
+}
+
+func TestUserService_Register_NotifierFails(t *testing.T) {
+	// Test Register keeps the account when the verification link cannot be sent
+	repo := &mockUserRepository{}
+	userService := NewUserService(repo, &mockTokenProvider{},
+		WithNotifier(&mockNotifier{err: errors.New("smtp down")}),
+		WithEmailVerification(newMockEmailVerificationRepository(), time.Hour, "", true))
+	created, err := userService.Register(context.Background(), &domain.User{
+		Email:     "test@example.com",
+		Password:  "password123",
+		Username:  "testuser",
+		FirstName: "Test",
+		LastName:  "User",
+	})
+	if err != nil || created == nil || created.ID == 0 {
+		t.Fatalf("Register should return the stored user: %+v, %v", created, err)
+	}
+}
+
+func TestUserService_Login_EmailNotVerified(t *testing.T) {
+	// Test Login is blocked for unverified accounts when verification is required
+	passwordHash, _ := pkg.HashPassword("password123")
+	repo := &mockUserRepository{
+		users: []domain.User{
+			{ID: 1, Email: "test@example.com", Password: passwordHash, IsActive: true},
+		},
+	}
+	userService := NewUserService(repo, &mockTokenProvider{token: "token"},
+		WithEmailVerification(newMockEmailVerificationRepository(), time.Hour, "", true))
+	req := &domain.AuthRequest{Email: "test@example.com", Password: "password123"}
+	if _, err := userService.Login(context.Background(), req); err != pkg.ErrEmailNotVerified {
+		t.Fatalf("Login should have failed with email not verified: %v", err)
+	}
+	_ = repo.MarkEmailVerified(context.Background(), 1)
+	if _, err := userService.Login(context.Background(), req); err != nil {
+		t.Errorf("Login failed after verification: %v", err)
+	}
+}
+
+func currentTOTP(t *testing.T, secret string) string {
+	t.Helper()
+	code, err := pkg.TOTPCode(secret, pkg.TOTPStep(time.Now()))
+	if err != nil {
+		t.Fatalf("TOTPCode failed: %v", err)
+	}
+	return code
+}
+
+func TestUserService_MFA_SetupAndLogin(t *testing.T) {
+	// Test enrolling in 2FA and completing a two-step login
+	passwordHash, _ := pkg.HashPassword("password123")
+	repo := &mockUserRepository{
+		users: []domain.User{{ID: 1, Email: "test@example.com", Password: passwordHash, IsActive: true, Role: domain.RoleUser}},
+	}
+	mfaRepo := newMockMFARepository()
+	userService := NewUserService(repo, jwtadapter.NewProvider([]byte("secret"), time.Minute),
+		WithMFA(mfaRepo, "CMAS", []string{domain.RoleAdmin}))
+	actor := &domain.Principal{UserID: 1, Role: domain.RoleUser}
+	setup, err := userService.SetupMFA(context.Background(), actor, &domain.MFASetupRequest{CurrentPassword: "password123"})
+	if err != nil {
+		t.Fatalf("SetupMFA failed: %v", err)
+	}
+	if !strings.HasPrefix(setup.URI, "otpauth://totp/") {
+		t.Errorf("SetupMFA returned an unexpected URI: %s", setup.URI)
+	}
+	// Login is not challenged until enrollment is confirmed
+	req := &domain.AuthRequest{Email: "test@example.com", Password: "password123"}
+	resp, err := userService.Login(context.Background(), req)
+	if err != nil || resp.Token == "" || resp.MFARequired {
+		t.Fatalf("Login should issue tokens before 2FA is confirmed: %#v, %v", resp, err)
+	}
+	if _, err := userService.ConfirmMFA(context.Background(), actor, &domain.MFACodeRequest{Code: "000000"}); err != pkg.ErrInvalidMFACode {
+		t.Errorf("ConfirmMFA should reject a wrong code: %v", err)
+	}
+	confirmed, err := userService.ConfirmMFA(context.Background(), actor, &domain.MFACodeRequest{Code: currentTOTP(t, setup.Secret)})
+	if err != nil {
+		t.Fatalf("ConfirmMFA failed: %v", err)
+	}
+	if len(confirmed.RecoveryCodes) != recoveryCodeCount {
+		t.Errorf("ConfirmMFA returned %d recovery codes", len(confirmed.RecoveryCodes))
+	}
+	resp, err = userService.Login(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+	if !resp.MFARequired || resp.MFAToken == "" || resp.Token != "" {
+		t.Fatalf("Login should return an mfa_pending token: %#v", resp)
+	}
+	// The code used to confirm cannot be replayed
+	_, err = userService.CompleteMFALogin(context.Background(), &domain.MFALoginRequest{MFAToken: resp.MFAToken, Code: currentTOTP(t, setup.Secret)})
+	if err != pkg.ErrInvalidMFACode {
+		t.Errorf("CompleteMFALogin should reject a replayed code: %v", err)
+	}
+	recovery := &domain.MFALoginRequest{MFAToken: resp.MFAToken, Code: strings.ToUpper(confirmed.RecoveryCodes[0])}
+	tokens, err := userService.CompleteMFALogin(context.Background(), recovery)
+	if err != nil || tokens.Token == "" {
+		t.Fatalf("CompleteMFALogin with a recovery code failed: %v", err)
+	}
+	if _, err := userService.CompleteMFALogin(context.Background(), recovery); err != pkg.ErrInvalidMFACode {
+		t.Errorf("Recovery codes should be single-use: %v", err)
+	}
+	// The pending token is not an enrollment token
+	_, err = userService.SetupMFAEnrollment(context.Background(), &domain.MFALoginRequest{MFAToken: resp.MFAToken})
+	if err != pkg.ErrInvalidMFAToken {
+		t.Errorf("SetupMFAEnrollment should reject a pending token: %v", err)
+	}
+	if err := userService.DisableMFA(context.Background(), actor, &domain.MFACodeRequest{Code: confirmed.RecoveryCodes[1]}); err != nil {
+		t.Errorf("DisableMFA failed: %v", err)
+	}
+	if _, err := mfaRepo.GetMFA(context.Background(), 1); err != nil || mfaRepo.mfa[1] != nil {
+		t.Errorf("DisableMFA should have removed the enrollment")
+	}
+}
+
+func TestUserService_MFA_SetupGuards(t *testing.T) {
+	// Test setup needs the current password and cannot replace an enabled secret
+	passwordHash, _ := pkg.HashPassword("password123")
+	repo := &mockUserRepository{
+		users: []domain.User{{ID: 1, Email: "test@example.com", Password: passwordHash, IsActive: true, Role: domain.RoleUser}},
+	}
+	mfaRepo := newMockMFARepository()
+	userService := NewUserService(repo, &mockTokenProvider{token: "token"}, WithMFA(mfaRepo, "CMAS", nil))
+	actor := &domain.Principal{UserID: 1, Role: domain.RoleUser}
+	if _, err := userService.SetupMFA(context.Background(), actor, &domain.MFASetupRequest{}); err != pkg.ErrValidationError {
+		t.Errorf("SetupMFA should require the current password: %v", err)
+	}
+	if _, err := userService.SetupMFA(context.Background(), actor, &domain.MFASetupRequest{CurrentPassword: "wrongpassword"}); err != pkg.ErrInvalidCurrentPassword {
+		t.Errorf("SetupMFA should reject a wrong password: %v", err)
+	}
+	if mfaRepo.mfa[1] != nil {
+		t.Fatalf("SetupMFA should not store a secret without the password")
+	}
+	setup, err := userService.SetupMFA(context.Background(), actor, &domain.MFASetupRequest{CurrentPassword: "password123"})
+	if err != nil {
+		t.Fatalf("SetupMFA failed: %v", err)
+	}
+	if _, err := userService.ConfirmMFA(context.Background(), actor, &domain.MFACodeRequest{Code: currentTOTP(t, setup.Secret)}); err != nil {
+		t.Fatalf("ConfirmMFA failed: %v", err)
+	}
+	if _, err := userService.SetupMFA(context.Background(), actor, &domain.MFASetupRequest{CurrentPassword: "password123"}); err != pkg.ErrMFAAlreadyEnabled {
+		t.Errorf("SetupMFA should refuse while 2FA is enabled: %v", err)
+	}
+	if mfaRepo.mfa[1].Secret != setup.Secret {
+		t.Errorf("the enabled secret should not have changed")
+	}
+}
+
+func TestUserService_MFA_PendingTokenSingleUse(t *testing.T) {
+	// Test an mfa_pending token cannot be replayed once it was exchanged
+	passwordHash, _ := pkg.HashPassword("password123")
+	repo := &mockUserRepository{
+		users: []domain.User{{ID: 1, Email: "test@example.com", Password: passwordHash, IsActive: true, Role: domain.RoleUser}},
+	}
+	mfaRepo := newMockMFARepository()
+	userService := NewUserService(repo, jwtadapter.NewProvider([]byte("secret"), time.Minute),
+		WithMFA(mfaRepo, "CMAS", nil),
+		WithTokenRevocation(memory.NewTokenRevocationStore(), time.Minute))
+	actor := &domain.Principal{UserID: 1, Role: domain.RoleUser}
+	setup, err := userService.SetupMFA(context.Background(), actor, &domain.MFASetupRequest{CurrentPassword: "password123"})
+	if err != nil {
+		t.Fatalf("SetupMFA failed: %v", err)
+	}
+	confirmed, err := userService.ConfirmMFA(context.Background(), actor, &domain.MFACodeRequest{Code: currentTOTP(t, setup.Secret)})
+	if err != nil {
+		t.Fatalf("ConfirmMFA failed: %v", err)
+	}
+	resp, err := userService.Login(context.Background(), &domain.AuthRequest{Email: "test@example.com", Password: "password123"})
+	if err != nil || resp.MFAToken == "" {
+		t.Fatalf("Login should return an mfa_pending token: %#v, %v", resp, err)
+	}
+	// Wrong codes do not use the token up
+	if _, err := userService.CompleteMFALogin(context.Background(), &domain.MFALoginRequest{MFAToken: resp.MFAToken, Code: "000000"}); err != pkg.ErrInvalidMFACode {
+		t.Fatalf("CompleteMFALogin should reject a wrong code: %v", err)
+	}
+	if _, err := userService.CompleteMFALogin(context.Background(), &domain.MFALoginRequest{MFAToken: resp.MFAToken, Code: confirmed.RecoveryCodes[0]}); err != nil {
+		t.Fatalf("CompleteMFALogin failed: %v", err)
+	}
+	_, err = userService.CompleteMFALogin(context.Background(), &domain.MFALoginRequest{MFAToken: resp.MFAToken, Code: confirmed.RecoveryCodes[1]})
+	if err != pkg.ErrInvalidMFAToken {
+		t.Errorf("CompleteMFALogin should reject a used mfa token: %v", err)
+	}
+}
+
+func TestUserService_MFA_RequiredRole(t *testing.T) {
+	// Test admins must enroll in 2FA during login and cannot disable it
+	passwordHash, _ := pkg.HashPassword("password123")
+	repo := &mockUserRepository{
+		users: []domain.User{{ID: 1, Email: "admin@example.com", Password: passwordHash, IsActive: true, Role: domain.RoleAdmin}},
+	}
+	userService := NewUserService(repo, jwtadapter.NewProvider([]byte("secret"), time.Minute),
+		WithMFA(newMockMFARepository(), "CMAS", []string{domain.RoleAdmin}))
+	resp, err := userService.Login(context.Background(), &domain.AuthRequest{Email: "admin@example.com", Password: "password123"})
+	if err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+	if !resp.MFAEnrollmentRequired || resp.MFAToken == "" || resp.Token != "" {
+		t.Fatalf("Login should require enrollment: %#v", resp)
+	}
+	if _, err := userService.CompleteMFALogin(context.Background(), &domain.MFALoginRequest{MFAToken: resp.MFAToken, Code: "000000"}); err != pkg.ErrInvalidMFAToken {
+		t.Errorf("CompleteMFALogin should reject an enrollment token: %v", err)
+	}
+	setup, err := userService.SetupMFAEnrollment(context.Background(), &domain.MFALoginRequest{MFAToken: resp.MFAToken})
+	if err != nil {
+		t.Fatalf("SetupMFAEnrollment failed: %v", err)
+	}
+	tokens, err := userService.ConfirmMFAEnrollment(context.Background(), &domain.MFALoginRequest{MFAToken: resp.MFAToken, Code: currentTOTP(t, setup.Secret)})
+	if err != nil {
+		t.Fatalf("ConfirmMFAEnrollment failed: %v", err)
+	}
+	if tokens.Token == "" || len(tokens.RecoveryCodes) != recoveryCodeCount {
+		t.Errorf("ConfirmMFAEnrollment should return tokens and recovery codes: %#v", tokens)
+	}
+	err = userService.DisableMFA(context.Background(), &domain.Principal{UserID: 1, Role: domain.RoleAdmin}, &domain.MFACodeRequest{Code: tokens.RecoveryCodes[0]})
+	if err != pkg.ErrMFARequired {
+		t.Errorf("DisableMFA should be refused for admins: %v", err)
+	}
+}
+
+func TestUserService_Login_AccountLockout(t *testing.T) {
+	// Test repeated failures lock the account with exponential backoff
+	passwordHash, _ := pkg.HashPassword("password123")
+	repo := &mockUserRepository{
+		users: []domain.User{{ID: 1, Email: "test@example.com", Password: passwordHash, IsActive: true}},
+	}
+	store := memory.NewLoginAttemptStore()
+	userService := NewUserService(repo, &mockTokenProvider{token: "token"},
+		WithLoginThrottle(store, domain.LockoutPolicy{MaxAttempts: 3, BaseLockout: time.Minute, MaxLockout: time.Hour}))
+	wrong := &domain.AuthRequest{Email: "test@example.com", Password: "wrongpassword", ClientIP: "10.0.0.1"}
+	for i := 0; i < 3; i++ {
+		if _, err := userService.Login(context.Background(), wrong); err != pkg.ErrInvalidCredentials {
+			t.Fatalf("Login attempt %d should have failed with invalid credentials: %v", i+1, err)
+		}
+	}
+	right := &domain.AuthRequest{Email: "TEST@example.com", Password: "password123", ClientIP: "10.0.0.1"}
+	if _, err := userService.Login(context.Background(), right); err != pkg.ErrAccountLocked {
+		t.Fatalf("Login should have failed with account locked: %v", err)
+	}
+	attempt, _ := store.GetLoginAttempt(context.Background(), accountAttemptKey("test@example.com"))
+	if attempt == nil || attempt.LockedUntil == nil || time.Until(*attempt.LockedUntil) > time.Minute {
+		t.Fatalf("first lock should last one minute: %+v", attempt)
+	}
+	// Once the lock expires another failure doubles it
+	expired := time.Now().Add(-time.Second)
+	_ = store.LockLogin(context.Background(), accountAttemptKey("test@example.com"), expired)
+	_, _ = userService.Login(context.Background(), wrong)
+	attempt, _ = store.GetLoginAttempt(context.Background(), accountAttemptKey("test@example.com"))
+	if attempt.Failures != 4 || time.Until(*attempt.LockedUntil) <= time.Minute {
+		t.Fatalf("second lock should be longer: %+v", attempt)
+	}
+	// A successful login clears the account's failures
+	_ = store.LockLogin(context.Background(), accountAttemptKey("test@example.com"), expired)
+	if _, err := userService.Login(context.Background(), right); err != nil {
+		t.Fatalf("Login failed after the lock expired: %v", err)
+	}
+	if attempt, _ := store.GetLoginAttempt(context.Background(), accountAttemptKey("test@example.com")); attempt != nil {
+		t.Errorf("Login should have reset failures: %+v", attempt)
+	}
+}
+
+func TestUserService_Login_IPThrottle(t *testing.T) {
+	// Test failures across accounts lock the client IP
+	userService := NewUserService(&mockUserRepository{}, &mockTokenProvider{token: "token"},
+		WithLoginThrottle(memory.NewLoginAttemptStore(), domain.LockoutPolicy{MaxAttempts: 10, IPMaxAttempts: 2}))
+	for i := 0; i < 2; i++ {
+		req := &domain.AuthRequest{Email: fmt.Sprintf("user%d@example.com", i), Password: "password123", ClientIP: "10.0.0.2"}
+		if _, err := userService.Login(context.Background(), req); err != pkg.ErrInvalidCredentials {
+			t.Fatalf("Login should have failed with invalid credentials: %v", err)
+		}
+	}
+	req := &domain.AuthRequest{Email: "other@example.com", Password: "password123", ClientIP: "10.0.0.2"}
+	if _, err := userService.Login(context.Background(), req); err != pkg.ErrTooManyLoginAttempts {
+		t.Errorf("Login should have failed with too many attempts: %v", err)
+	}
+	req.ClientIP = "10.0.0.3"
+	if _, err := userService.Login(context.Background(), req); err != pkg.ErrInvalidCredentials {
+		t.Errorf("Other clients should not be throttled: %v", err)
+	}
+}
+
+func TestUserService_SuspendUser(t *testing.T) {
+	// Test suspending blocks login and revokes tokens; reactivating lifts it
+	passwordHash, _ := pkg.HashPassword("password123")
+	repo := &mockUserRepository{
+		users: []domain.User{{ID: 1, Email: "test@example.com", Password: passwordHash, IsActive: true}},
+	}
+	store := memory.NewTokenRevocationStore()
+	userService := NewUserService(repo, &mockTokenProvider{token: "token"}, WithTokenRevocation(store, time.Minute))
+	issuedAt := time.Now().Truncate(time.Microsecond)
+	reason := &domain.UserStatusRequest{Reason: "chargeback fraud"}
+	change, err := userService.SuspendUser(context.Background(), adminActor, 1, reason)
+	if err != nil {
+		t.Fatalf("SuspendUser failed: %v", err)
+	}
+	if change.Active || change.ChangedBy != adminActor.UserID || change.Reason != reason.Reason {
+		t.Errorf("SuspendUser recorded an unexpected change: %+v", change)
+	}
+	if revoked, _ := store.IsRevoked(context.Background(), "any", 1, issuedAt); !revoked {
+		t.Errorf("SuspendUser should have revoked existing tokens")
+	}
+	login := &domain.AuthRequest{Email: "test@example.com", Password: "password123"}
+	if _, err := userService.Login(context.Background(), login); err != pkg.ErrAccountSuspended {
+		t.Fatalf("Login should have failed with account suspended: %v", err)
+	}
+	if _, err := userService.ReactivateUser(context.Background(), adminActor, 1, &domain.UserStatusRequest{Reason: "resolved"}); err != nil {
+		t.Fatalf("ReactivateUser failed: %v", err)
+	}
+	if _, err := userService.Login(context.Background(), login); err != nil {
+		t.Errorf("Login failed after reactivation: %v", err)
+	}
+}
+
+func TestUserService_SuspendUser_Forbidden(t *testing.T) {
+	// Test users cannot suspend others and admins cannot suspend themselves
+	repo := &mockUserRepository{users: []domain.User{{ID: 1, IsActive: true}}}
+	userService := NewUserService(repo, &mockTokenProvider{})
+	reason := &domain.UserStatusRequest{Reason: "spam"}
+	if _, err := userService.SuspendUser(context.Background(), &domain.Principal{UserID: 2, Role: domain.RoleUser}, 1, reason); err != pkg.ErrForbidden {
+		t.Errorf("SuspendUser should have failed with forbidden: %v", err)
+	}
+	if _, err := userService.SuspendUser(context.Background(), adminActor, adminActor.UserID, reason); err != pkg.ErrForbidden {
+		t.Errorf("SuspendUser should refuse self-suspension: %v", err)
+	}
+	if _, err := userService.SuspendUser(context.Background(), adminActor, 1, &domain.UserStatusRequest{}); err != pkg.ErrValidationError {
+		t.Errorf("SuspendUser should require a reason: %v", err)
+	}
+}
+
+func TestUserService_APIKeys(t *testing.T) {
+	// Test creating, using and revoking an API key
+	repo := &mockUserRepository{users: []domain.User{{ID: 1, Role: domain.RoleUser, IsActive: true}}}
+	keys := newMockAPIKeyRepository()
+	userService := NewUserService(repo, &mockTokenProvider{}, WithAPIKeys(keys))
+	actor := &domain.Principal{UserID: 1, Role: domain.RoleUser}
+	// Users cannot grant a key more than their role has
+	_, err := userService.CreateAPIKey(context.Background(), actor, &domain.CreateAPIKeyRequest{Name: "sync", Scopes: []string{domain.PermBooksWrite}})
+	if err != pkg.ErrInvalidAPIKeyScope {
+		t.Errorf("CreateAPIKey should reject scopes outside the role: %v", err)
+	}
+	created, err := userService.CreateAPIKey(context.Background(), actor, &domain.CreateAPIKeyRequest{Name: "sync", Scopes: []string{domain.PermBooksRead, domain.PermBooksRead}})
+	if err != nil {
+		t.Fatalf("CreateAPIKey failed: %v", err)
+	}
+	if !strings.HasPrefix(created.Key, created.Prefix+"_") || len(created.Scopes) != 1 {
+		t.Fatalf("CreateAPIKey returned an unexpected key: %+v", created)
+	}
+	if keys.keys[created.ID].KeyHash == created.Key {
+		t.Errorf("CreateAPIKey should store only the hash")
+	}
+	principal, err := userService.AuthenticateAPIKey(context.Background(), created.Key)
+	if err != nil {
+		t.Fatalf("AuthenticateAPIKey failed: %v", err)
+	}
+	if principal.UserID != 1 || principal.APIKeyID != created.ID || !principal.HasScope(domain.PermBooksRead) || principal.HasScope(domain.PermProfileRead) {
+		t.Errorf("AuthenticateAPIKey returned an unexpected principal: %+v", principal)
+	}
+	_, _ = userService.AuthenticateAPIKey(context.Background(), created.Key)
+	if keys.touched != 1 || keys.keys[created.ID].LastUsedAt == nil {
+		t.Errorf("AuthenticateAPIKey should record last use at most once a minute, touched %d times", keys.touched)
+	}
+	if _, err := userService.AuthenticateAPIKey(context.Background(), created.Key+"x"); err != pkg.ErrInvalidAPIKey {
+		t.Errorf("AuthenticateAPIKey should reject a wrong secret: %v", err)
+	}
+	// API keys cannot create more keys
+	if _, err := userService.CreateAPIKey(context.Background(), principal, &domain.CreateAPIKeyRequest{Name: "x", Scopes: []string{domain.PermBooksRead}}); err != pkg.ErrForbidden {
+		t.Errorf("CreateAPIKey should refuse API key callers: %v", err)
+	}
+	if err := userService.RevokeAPIKey(context.Background(), &domain.Principal{UserID: 2, Role: domain.RoleUser}, created.ID); err != pkg.ErrAPIKeyNotFound {
+		t.Errorf("RevokeAPIKey should not revoke other users' keys: %v", err)
+	}
+	if err := userService.RevokeAPIKey(context.Background(), actor, created.ID); err != nil {
+		t.Fatalf("RevokeAPIKey failed: %v", err)
+	}
+	if _, err := userService.AuthenticateAPIKey(context.Background(), created.Key); err != pkg.ErrInvalidAPIKey {
+		t.Errorf("AuthenticateAPIKey should reject a revoked key: %v", err)
+	}
+}
+
+func TestUserService_APIKeys_Expiry(t *testing.T) {
+	// Test expired keys and keys of suspended users are rejected
+	repo := &mockUserRepository{users: []domain.User{{ID: 1, Role: domain.RoleAdmin, IsActive: true}}}
+	keys := newMockAPIKeyRepository()
+	userService := NewUserService(repo, &mockTokenProvider{}, WithAPIKeys(keys))
+	actor := &domain.Principal{UserID: 1, Role: domain.RoleAdmin}
+	past := time.Now().Add(-time.Minute)
+	if _, err := userService.CreateAPIKey(context.Background(), actor, &domain.CreateAPIKeyRequest{Name: "old", Scopes: []string{domain.PermBooksWrite}, ExpiresAt: &past}); err != pkg.ErrValidationError {
+		t.Errorf("CreateAPIKey should reject an expiry in the past: %v", err)
+	}
+	future := time.Now().Add(time.Hour)
+	created, err := userService.CreateAPIKey(context.Background(), actor, &domain.CreateAPIKeyRequest{Name: "sync", Scopes: []string{domain.PermBooksWrite}, ExpiresAt: &future})
+	if err != nil {
+		t.Fatalf("CreateAPIKey failed: %v", err)
+	}
+	keys.keys[created.ID].ExpiresAt = &past
+	if _, err := userService.AuthenticateAPIKey(context.Background(), created.Key); err != pkg.ErrInvalidAPIKey {
+		t.Errorf("AuthenticateAPIKey should reject an expired key: %v", err)
+	}
+	keys.keys[created.ID].ExpiresAt = &future
+	repo.users[0].IsActive = false
+	if _, err := userService.AuthenticateAPIKey(context.Background(), created.Key); err != pkg.ErrInvalidAPIKey {
+		t.Errorf("AuthenticateAPIKey should reject keys of suspended users: %v", err)
+	}
+}
+
+func TestUserService_OAuth_NewUser(t *testing.T) {
+	// Test a first sign-in creates a verified user linked to the identity
+	idp := &mockIdentityProvider{identity: &domain.ExternalIdentity{Subject: "sub-1", Email: "Jane.Doe+shop@example.com", EmailVerified: true, GivenName: "Jane"}}
+	identities := &mockUserIdentityRepository{}
+	states := &mockOAuthStateRepository{states: map[string]*domain.OAuthState{}}
+	userService := NewUserService(&mockUserRepository{}, &mockTokenProvider{token: "token"},
+		WithOAuth(identities, states, map[string]ports.IdentityProvider{"google": idp}))
+	start, err := userService.StartOAuth(context.Background(), "google")
+	if err != nil {
+		t.Fatalf("StartOAuth failed: %v", err)
+	}
+	if start.State == "" || idp.verifier == "" || idp.nonce == "" {
+		t.Fatalf("StartOAuth should generate state, PKCE verifier and nonce")
+	}
+	// The state belongs to the provider it was started for
+	if _, err := userService.CompleteOAuth(context.Background(), &domain.OAuthCallbackRequest{Provider: "github", State: start.State, Code: "good-code"}); err != pkg.ErrOAuthProviderNotFound {
+		t.Errorf("CompleteOAuth should reject unknown providers: %v", err)
+	}
+	resp, err := userService.CompleteOAuth(context.Background(), &domain.OAuthCallbackRequest{Provider: "google", State: start.State, Code: "good-code"})
+	if err != nil {
+		t.Fatalf("CompleteOAuth failed: %v", err)
+	}
+	if resp.Token != "token" {
+		t.Errorf("CompleteOAuth should issue tokens: %+v", resp)
+	}
+	if len(identities.created) != 1 || identities.identities[0].LastLoginAt == nil {
+		t.Fatalf("CompleteOAuth should create and link one user")
+	}
+	created := identities.created[0]
+	if created.EmailVerifiedAt == nil || created.Role != domain.RoleUser || !strings.HasPrefix(created.Username, "jane.doeshop_") || created.FirstName != "Jane" {
+		t.Errorf("CompleteOAuth created an unexpected user: %+v", created)
+	}
+	if _, err := userService.CompleteOAuth(context.Background(), &domain.OAuthCallbackRequest{Provider: "google", State: start.State, Code: "good-code"}); err != pkg.ErrInvalidOAuthState {
+		t.Errorf("CompleteOAuth should not accept a state twice: %v", err)
+	}
+}
+
+func TestUserService_OAuth_LinkExistingUser(t *testing.T) {
+	// Test an existing account is only linked when the provider verified the email
+	repo := &mockUserRepository{users: []domain.User{{ID: 1, Email: "jane@example.com", Role: domain.RoleUser, IsActive: true}}}
+	idp := &mockIdentityProvider{identity: &domain.ExternalIdentity{Subject: "sub-1", Email: "jane@example.com"}}
+	identities := &mockUserIdentityRepository{}
+	states := &mockOAuthStateRepository{states: map[string]*domain.OAuthState{}}
+	userService := NewUserService(repo, &mockTokenProvider{token: "token"},
+		WithOAuth(identities, states, map[string]ports.IdentityProvider{"google": idp}))
+	start, _ := userService.StartOAuth(context.Background(), "google")
+	if _, err := userService.CompleteOAuth(context.Background(), &domain.OAuthCallbackRequest{Provider: "google", State: start.State, Code: "good-code"}); err != pkg.ErrUserAlreadyExists {
+		t.Errorf("CompleteOAuth should not link an unverified email: %v", err)
+	}
+	idp.identity.EmailVerified = true
+	start, _ = userService.StartOAuth(context.Background(), "google")
+	if _, err := userService.CompleteOAuth(context.Background(), &domain.OAuthCallbackRequest{Provider: "google", State: start.State, Code: "bad-code"}); err != pkg.ErrOAuthExchangeFailed {
+		t.Errorf("CompleteOAuth should fail when the code is rejected: %v", err)
+	}
+	start, _ = userService.StartOAuth(context.Background(), "google")
+	if _, err := userService.CompleteOAuth(context.Background(), &domain.OAuthCallbackRequest{Provider: "google", State: start.State, Code: "good-code"}); err != nil {
+		t.Fatalf("CompleteOAuth failed: %v", err)
+	}
+	if len(identities.created) != 0 || len(identities.identities) != 1 || identities.identities[0].UserID != 1 {
+		t.Errorf("CompleteOAuth should link the existing user")
+	}
+	if repo.users[0].EmailVerifiedAt == nil {
+		t.Errorf("CompleteOAuth should mark the email as verified")
+	}
+	// Suspended users cannot sign in through a provider either
+	repo.users[0].IsActive = false
+	start, _ = userService.StartOAuth(context.Background(), "google")
+	if _, err := userService.CompleteOAuth(context.Background(), &domain.OAuthCallbackRequest{Provider: "google", State: start.State, Code: "good-code"}); err != pkg.ErrAccountSuspended {
+		t.Errorf("CompleteOAuth should reject suspended users: %v", err)
+	}
+}
+
+func TestUserService_Sessions(t *testing.T) {
+	// Test a login starts a session that the token refers to and that can be ended
+	passwordHash, _ := pkg.HashPassword("password123")
+	repo := &mockUserRepository{
+		users: []domain.User{{ID: 1, Email: "test@example.com", Password: passwordHash, IsActive: true, Role: domain.RoleUser}},
+	}
+	provider := jwtadapter.NewProvider([]byte("secret"), time.Minute)
+	sessions := newMockSessionRepository()
+	refreshRepo := newMockRefreshTokenRepository()
+	userService := NewUserService(repo, provider, WithRefreshTokens(refreshRepo, time.Hour), WithSessions(sessions))
+	resp, err := userService.Login(context.Background(), &domain.AuthRequest{
+		Email: "test@example.com", Password: "password123", ClientIP: "203.0.113.7", UserAgent: "Firefox",
+	})
+	if err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+	claims, err := provider.ValidateToken(resp.Token)
+	if err != nil {
+		t.Fatalf("ValidateToken failed: %v", err)
+	}
+	session := sessions.sessions[claims.SessionID]
+	if session == nil || session.UserID != 1 || session.IP != "203.0.113.7" || session.UserAgent != "Firefox" {
+		t.Fatalf("Login should record the session in the token: %+v", session)
+	}
+	if refreshRepo.tokens[pkg.HashToken(resp.RefreshToken)].FamilyID != session.ID {
+		t.Errorf("refresh tokens should belong to the session")
+	}
+	if active, err := userService.CheckSession(context.Background(), 1, session.ID); err != nil || !active {
+		t.Errorf("CheckSession should accept the new session: %v, %v", active, err)
+	}
+	if active, _ := userService.CheckSession(context.Background(), 2, session.ID); active {
+		t.Errorf("CheckSession should not accept another user's session")
+	}
+	actor := &domain.Principal{UserID: 1, Role: domain.RoleUser, SessionID: session.ID}
+	list, err := userService.ListSessions(context.Background(), actor)
+	if err != nil || len(list) != 1 || !list[0].Current {
+		t.Fatalf("ListSessions should return the current session: %+v, %v", list, err)
+	}
+	refreshed, err := userService.RefreshToken(context.Background(), &domain.RefreshRequest{RefreshToken: resp.RefreshToken})
+	if err != nil {
+		t.Fatalf("RefreshToken failed: %v", err)
+	}
+	if claims, _ := provider.ValidateToken(refreshed.Token); claims.SessionID != session.ID {
+		t.Errorf("RefreshToken should keep the session")
+	}
+	if err := userService.RevokeSession(context.Background(), &domain.Principal{UserID: 2, Role: domain.RoleUser}, session.ID); err != pkg.ErrSessionNotFound {
+		t.Errorf("RevokeSession should not end another user's session: %v", err)
+	}
+	if err := userService.RevokeSession(context.Background(), actor, session.ID); err != nil {
+		t.Fatalf("RevokeSession failed: %v", err)
+	}
+	if active, _ := userService.CheckSession(context.Background(), 1, session.ID); active {
+		t.Errorf("CheckSession should reject an ended session")
+	}
+	if _, err := userService.RefreshToken(context.Background(), &domain.RefreshRequest{RefreshToken: refreshed.RefreshToken}); err != pkg.ErrInvalidRefreshToken {
+		t.Errorf("RefreshToken should fail for an ended session: %v", err)
+	}
+}
+
+func TestUserService_Sessions_LegacyRefreshFamily(t *testing.T) {
+	// Test refresh tokens issued before sessions were enabled get a session
+	repo := &mockUserRepository{users: []domain.User{{ID: 1, Email: "test@example.com", IsActive: true}}}
+	refreshRepo := newMockRefreshTokenRepository()
+	before := NewUserService(repo, &mockTokenProvider{token: "token"}, WithRefreshTokens(refreshRepo, time.Hour))
+	first, _ := before.issueTokens(context.Background(), &repo.users[0], "")
+	sessions := newMockSessionRepository()
+	userService := NewUserService(repo, &mockTokenProvider{token: "token"}, WithRefreshTokens(refreshRepo, time.Hour), WithSessions(sessions))
+	if _, err := userService.RefreshToken(context.Background(), &domain.RefreshRequest{RefreshToken: first.RefreshToken, UserAgent: "curl"}); err != nil {
+		t.Fatalf("RefreshToken failed: %v", err)
+	}
+	familyID := refreshRepo.tokens[pkg.HashToken(first.RefreshToken)].FamilyID
+	if session := sessions.sessions[familyID]; session == nil || session.UserAgent != "curl" {
+		t.Errorf("RefreshToken should create a session for the family: %+v", session)
+	}
+}
+
+func TestUserService_Sessions_RefreshReuseEndsSession(t *testing.T) {
+	// Test replaying a rotated refresh token also ends its session
+	repo := &mockUserRepository{users: []domain.User{{ID: 1, Email: "test@example.com", IsActive: true}}}
+	refreshRepo := newMockRefreshTokenRepository()
+	sessions := newMockSessionRepository()
+	userService := NewUserService(repo, &mockTokenProvider{token: "token"}, WithRefreshTokens(refreshRepo, time.Hour), WithSessions(sessions))
+	first, err := userService.startSession(context.Background(), &repo.users[0], domain.Client{})
+	if err != nil {
+		t.Fatalf("startSession failed: %v", err)
+	}
+	if _, err := userService.RefreshToken(context.Background(), &domain.RefreshRequest{RefreshToken: first.RefreshToken}); err != nil {
+		t.Fatalf("RefreshToken failed: %v", err)
+	}
+	if _, err := userService.RefreshToken(context.Background(), &domain.RefreshRequest{RefreshToken: first.RefreshToken}); err != pkg.ErrRefreshTokenReused {
+		t.Fatalf("RefreshToken should have failed with reuse detected: %v", err)
+	}
+	familyID := refreshRepo.tokens[pkg.HashToken(first.RefreshToken)].FamilyID
+	if session := sessions.sessions[familyID]; session == nil || session.RevokedAt == nil {
+		t.Errorf("RefreshToken reuse should have ended the session: %+v", session)
+	}
+}
+
+func TestUserService_Login_RehashesOutdatedHash(t *testing.T) {
+	bcryptHash, _ := pkg.HashPassword("password123")
+	repo := &mockUserRepository{
+		users: []domain.User{{ID: 1, Email: "test@example.com", Password: bcryptHash, IsActive: true}},
+	}
+	hasher := password.NewArgon2idHasher(password.Argon2idParams{Memory: 8 * 1024, Iterations: 1, Parallelism: 1})
+	userService := NewUserService(repo, &mockTokenProvider{token: "token"}, WithPasswordHasher(hasher))
+
+	req := &domain.AuthRequest{Email: "test@example.com", Password: "password123"}
+	if _, err := userService.Login(context.Background(), req); err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+	upgraded := repo.users[0].Password
+	if !strings.HasPrefix(upgraded, "$argon2id$") {
+		t.Fatalf("expected argon2id hash after login, got %q", upgraded)
+	}
+	// The new hash is current and keeps working
+	if _, err := userService.Login(context.Background(), req); err != nil {
+		t.Fatalf("Login with upgraded hash failed: %v", err)
+	}
+	if repo.users[0].Password != upgraded {
+		t.Errorf("current hash should not be rehashed")
+	}
+	req.Password = "wrongpassword"
+	if _, err := userService.Login(context.Background(), req); err != pkg.ErrInvalidCredentials {
+		t.Errorf("expected ErrInvalidCredentials, got %v", err)
+	}
+}
+
+func TestUserService_Login_ArgonHashWithBcryptHasher(t *testing.T) {
+	argonHash, _ := password.NewArgon2idHasher(password.Argon2idParams{Memory: 8 * 1024, Iterations: 1, Parallelism: 1}).Hash("password123")
+	repo := &mockUserRepository{
+		users: []domain.User{{ID: 1, Email: "test@example.com", Password: argonHash, IsActive: true}},
+	}
+	userService := NewUserService(repo, &mockTokenProvider{token: "token"}, WithPasswordHasher(password.NewBcryptHasher(bcrypt.MinCost)))
+
+	if _, err := userService.Login(context.Background(), &domain.AuthRequest{Email: "test@example.com", Password: "password123"}); err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+	if !strings.HasPrefix(repo.users[0].Password, "$2") {
+		t.Errorf("expected bcrypt hash after login, got %q", repo.users[0].Password)
+	}
+}
+
+type mockPasswordHistoryRepository struct {
+	hashes map[int][]string
+}
+
+func (m *mockPasswordHistoryRepository) AddPasswordHistory(ctx context.Context, userID int, hash string, keep int) error {
+	if m.hashes == nil {
+		m.hashes = map[int][]string{}
+	}
+	hashes := append([]string{hash}, m.hashes[userID]...)
+	if len(hashes) > keep {
+		hashes = hashes[:keep]
+	}
+	m.hashes[userID] = hashes
+	return nil
+}
+
+func (m *mockPasswordHistoryRepository) ListPasswordHistory(ctx context.Context, userID int, limit int) ([]string, error) {
+	hashes := m.hashes[userID]
+	if len(hashes) > limit {
+		hashes = hashes[:limit]
+	}
+	return hashes, nil
+}
+
+func policyViolationCodes(t *testing.T, err error) []string {
+	t.Helper()
+	var policyErr *pkg.PasswordPolicyError
+	if !errors.As(err, &policyErr) {
+		t.Fatalf("expected a password policy error, got %v", err)
+	}
+	codes := []string{}
+	for _, v := range policyErr.Violations {
+		codes = append(codes, v.Code)
+	}
+	return codes
+}
+
+func TestUserService_PasswordPolicy_Register(t *testing.T) {
+	userService := NewUserService(&mockUserRepository{}, &mockTokenProvider{},
+		WithPasswordPolicy(domain.PasswordPolicy{MinLength: 10, RequireUpper: true, RequireDigit: true, RequireSymbol: true, ForbidPersonalInfo: true}, nil))
+	user := &domain.User{Email: "jane@example.com", Password: "jane123", Username: "jane_doe", FirstName: "Jane", LastName: "Doe"}
+	_, err := userService.Register(context.Background(), user)
+	codes := policyViolationCodes(t, err)
+	expected := []string{"too_short", "missing_upper", "missing_symbol", "personal_info"}
+	if strings.Join(codes, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected violations %v, got %v", expected, codes)
+	}
+	if !errors.Is(err, pkg.ErrWeakPassword) {
+		t.Errorf("policy errors should wrap ErrWeakPassword")
+	}
+
+	user.Password = "Correct-Horse-42"
+	if _, err := userService.Register(context.Background(), user); err != nil {
+		t.Errorf("Register with a compliant password failed: %v", err)
+	}
+}
+
+func TestUserService_PasswordPolicy_History(t *testing.T) {
+	current, _ := pkg.HashPassword("password-one")
+	repo := &mockUserRepository{
+		users: []domain.User{{ID: 1, Email: "test@example.com", Username: "tester", Password: current, IsActive: true}},
+	}
+	history := &mockPasswordHistoryRepository{}
+	userService := NewUserService(repo, &mockTokenProvider{},
+		WithPasswordPolicy(domain.PasswordPolicy{History: 3}, history))
+	actor := &domain.Principal{UserID: 1, Role: "user"}
+	change := func(from, to string) error {
+		return userService.UpdatePassword(context.Background(), actor, 1, domain.UpdatePasswordRequest{CurrentPassword: from, NewPassword: to})
+	}
+
+	if err := change("password-one", "password-one"); err == nil {
+		t.Fatalf("reusing the current password should fail")
+	}
+	for _, step := range [][2]string{{"password-one", "password-two"}, {"password-two", "password-three"}} {
+		if err := change(step[0], step[1]); err != nil {
+			t.Fatalf("UpdatePassword failed: %v", err)
+		}
+	}
+	codes := policyViolationCodes(t, change("password-three", "password-one"))
+	if len(codes) != 1 || codes[0] != "reused" {
+		t.Errorf("expected a reused violation, got %v", codes)
+	}
+	// Only the last three passwords are kept
+	if err := change("password-three", "password-four"); err != nil {
+		t.Fatalf("UpdatePassword failed: %v", err)
+	}
+	if err := change("password-four", "password-one"); err != nil {
+		t.Errorf("a password older than the history should be accepted: %v", err)
+	}
+}
+
+func TestUserService_PasswordPolicy_ResetKeepsTokenOnViolation(t *testing.T) {
+	repo := &mockUserRepository{
+		users: []domain.User{{ID: 1, Email: "test@example.com", Username: "tester"}},
+	}
+	breachedFile := filepath.Join(t.TempDir(), "pwned.txt")
+	lines := []string{}
+	for _, p := range []string{"password123", "letmein-please", "qwertyuiop"} {
+		sum := sha1.Sum([]byte(p))
+		lines = append(lines, strings.ToUpper(hex.EncodeToString(sum[:]))+":42")
+	}
+	sort.Strings(lines)
+	if err := os.WriteFile(breachedFile, []byte(strings.Join(lines, "\r\n")+"\r\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	breached, err := password.NewBreachedPasswordFile(breachedFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	notifier := &mockNotifier{}
+	userService := NewUserService(repo, &mockTokenProvider{},
+		WithNotifier(notifier),
+		WithPasswordReset(newMockPasswordResetRepository(), time.Hour, ""),
+		WithBreachedPasswordCheck(breached))
+	if err := userService.ForgotPassword(context.Background(), &domain.ForgotPasswordRequest{Email: "test@example.com"}); err != nil {
+		t.Fatalf("ForgotPassword failed: %v", err)
+	}
+	token := notifier.lastToken()
+
+	err = userService.ResetPassword(context.Background(), &domain.ResetPasswordRequest{Token: token, NewPassword: "letmein-please"})
+	codes := policyViolationCodes(t, err)
+	if len(codes) != 1 || codes[0] != "breached" {
+		t.Errorf("expected a breached violation, got %v", codes)
+	}
+	if err := userService.ResetPassword(context.Background(), &domain.ResetPasswordRequest{Token: token, NewPassword: "not-in-the-list"}); err != nil {
+		t.Errorf("the reset link should still work after a rejected password: %v", err)
+	}
+}
+
+type mockImpersonationAuditRepository struct {
+	entries []*domain.ImpersonationAuditEntry
+}
+
+func (m *mockImpersonationAuditRepository) CreateImpersonationAuditEntry(ctx context.Context, entry *domain.ImpersonationAuditEntry) error {
+	entry.ID = int64(len(m.entries) + 1)
+	m.entries = append(m.entries, entry)
+	return nil
+}
+
+func (m *mockImpersonationAuditRepository) ListImpersonationAuditEntries(ctx context.Context, userID int, limit int) ([]*domain.ImpersonationAuditEntry, error) {
+	entries := []*domain.ImpersonationAuditEntry{}
+	for i := len(m.entries) - 1; i >= 0 && len(entries) < limit; i-- {
+		if m.entries[i].UserID == userID {
+			entries = append(entries, m.entries[i])
+		}
+	}
+	return entries, nil
+}
+
+func TestUserService_Impersonate(t *testing.T) {
+	repo := &mockUserRepository{
+		users: []domain.User{{ID: 2, Email: "customer@example.com", Role: domain.RoleUser, IsActive: true}},
+	}
+	provider := jwtadapter.NewProvider([]byte("secret"), time.Hour)
+	audit := &mockImpersonationAuditRepository{}
+	userService := NewUserService(repo, provider, WithImpersonation(audit, 10*time.Minute))
+	admin := &domain.Principal{UserID: 1, Role: domain.RoleAdmin}
+
+	resp, err := userService.Impersonate(context.Background(), admin, 2, &domain.ImpersonationRequest{Reason: "ticket 42", ClientIP: "10.0.0.1"})
+	if err != nil {
+		t.Fatalf("Impersonate failed: %v", err)
+	}
+	claims, err := provider.ValidateToken(resp.Token)
+	if err != nil {
+		t.Fatalf("ValidateToken failed: %v", err)
+	}
+	if claims.Subject != "2" || claims.Actor != "1" || claims.Role != domain.RoleUser {
+		t.Errorf("unexpected claims: %+v", claims)
+	}
+	if time.Until(claims.ExpiresAt) > 10*time.Minute {
+		t.Errorf("impersonation token should be short-lived, expires at %v", claims.ExpiresAt)
+	}
+	entries, _ := userService.ListImpersonations(context.Background(), admin, 2)
+	if len(entries) != 1 || entries[0].Action != domain.ImpersonationActionStart || entries[0].TokenID != claims.ID || entries[0].Reason != "ticket 42" {
+		t.Fatalf("expected the start to be audited with the token ID, got %+v", entries)
+	}
+
+	impersonating := &domain.Principal{UserID: 2, Role: domain.RoleUser, ActorID: 1}
+	if _, err := userService.Impersonate(context.Background(), impersonating, 3, &domain.ImpersonationRequest{Reason: "nested"}); err != pkg.ErrForbidden {
+		t.Errorf("expected ErrForbidden for nested impersonation, got %v", err)
+	}
+	if _, err := userService.Impersonate(context.Background(), admin, 2, &domain.ImpersonationRequest{}); err != pkg.ErrValidationError {
+		t.Errorf("expected ErrValidationError without a reason, got %v", err)
+	}
+	repo.users[0].Role = domain.RoleAdmin
+	if _, err := userService.Impersonate(context.Background(), admin, 2, &domain.ImpersonationRequest{Reason: "ticket 43"}); err != pkg.ErrForbidden {
+		t.Errorf("expected ErrForbidden when impersonating an admin, got %v", err)
+	}
+}
+
+func TestUserService_Impersonate_NotEnabled(t *testing.T) {
+	userService := NewUserService(&mockUserRepository{}, &mockTokenProvider{})
+	admin := &domain.Principal{UserID: 1, Role: domain.RoleAdmin}
+	if _, err := userService.Impersonate(context.Background(), admin, 2, &domain.ImpersonationRequest{Reason: "ticket 42"}); err != pkg.ErrFeatureDisabled {
+		t.Errorf("expected ErrFeatureDisabled, got %v", err)
+	}
+}
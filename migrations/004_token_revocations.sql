@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+    token_id    VARCHAR(64)  PRIMARY KEY,
+    expires_at  TIMESTAMPTZ  NOT NULL,
+    revoked_at  TIMESTAMPTZ  NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_revoked_tokens_expires_at ON revoked_tokens (expires_at);
+
+-- Tokens of user_id issued at or before revoked_before are rejected.
+CREATE TABLE IF NOT EXISTS user_token_revocations (
+    user_id         BIGINT       PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    revoked_before  TIMESTAMPTZ  NOT NULL,
+    expires_at      TIMESTAMPTZ  NOT NULL
+);
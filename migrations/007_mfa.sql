@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS user_mfa (
+    user_id         BIGINT       PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    secret          VARCHAR(64)  NOT NULL,
+    enabled_at      TIMESTAMPTZ,
+    last_used_step  BIGINT       NOT NULL DEFAULT 0,
+    created_at      TIMESTAMPTZ  NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS mfa_recovery_codes (
+    id          BIGSERIAL PRIMARY KEY,
+    user_id     BIGINT       NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash   VARCHAR(64)  NOT NULL,
+    used_at     TIMESTAMPTZ,
+    created_at  TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, code_hash)
+);
@@ -0,0 +1,9 @@
+-- Failed login counters keyed by "account:<email>" or "ip:<address>".
+CREATE TABLE IF NOT EXISTS login_attempts (
+    attempt_key      VARCHAR(320) PRIMARY KEY,
+    failures         INT          NOT NULL DEFAULT 0,
+    last_failure_at  TIMESTAMPTZ  NOT NULL,
+    locked_until     TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_attempts_last_failure_at ON login_attempts (last_failure_at);
@@ -0,0 +1,14 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS suspended_at TIMESTAMPTZ;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS suspension_reason TEXT;
+
+-- Every suspension and reactivation, with who did it and why.
+CREATE TABLE IF NOT EXISTS user_status_changes (
+    id          BIGSERIAL PRIMARY KEY,
+    user_id     BIGINT       NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    active      BOOLEAN      NOT NULL,
+    reason      TEXT         NOT NULL,
+    changed_by  BIGINT       REFERENCES users(id) ON DELETE SET NULL,
+    changed_at  TIMESTAMPTZ  NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_status_changes_user_id ON user_status_changes (user_id);
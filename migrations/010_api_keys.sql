@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS api_keys (
+    id            BIGSERIAL PRIMARY KEY,
+    user_id       BIGINT        NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name          VARCHAR(100)  NOT NULL,
+    prefix        VARCHAR(16)   NOT NULL UNIQUE,
+    key_hash      VARCHAR(64)   NOT NULL,
+    scopes        TEXT[]        NOT NULL,
+    expires_at    TIMESTAMPTZ,
+    last_used_at  TIMESTAMPTZ,
+    revoked_at    TIMESTAMPTZ,
+    created_at    TIMESTAMPTZ   NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON api_keys (user_id);
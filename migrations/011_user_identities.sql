@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS user_identities (
+    id             BIGSERIAL PRIMARY KEY,
+    user_id        BIGINT       NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    provider       VARCHAR(50)  NOT NULL,
+    subject        VARCHAR(255) NOT NULL,
+    email          VARCHAR(255),
+    created_at     TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    last_login_at  TIMESTAMPTZ,
+    UNIQUE (provider, subject)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_identities_user_id ON user_identities (user_id);
+
+CREATE TABLE IF NOT EXISTS oauth_states (
+    state_hash     VARCHAR(64)  PRIMARY KEY,
+    provider       VARCHAR(50)  NOT NULL,
+    code_verifier  VARCHAR(128) NOT NULL,
+    nonce          VARCHAR(64)  NOT NULL,
+    expires_at     TIMESTAMPTZ  NOT NULL,
+    created_at     TIMESTAMPTZ  NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_oauth_states_expires_at ON oauth_states (expires_at);
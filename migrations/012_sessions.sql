@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS user_sessions (
+    id            VARCHAR(64)  PRIMARY KEY,
+    user_id       BIGINT       NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    user_agent    VARCHAR(512) NOT NULL DEFAULT '',
+    ip            VARCHAR(45)  NOT NULL DEFAULT '',
+    created_at    TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    last_seen_at  TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    revoked_at    TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_sessions_user_id ON user_sessions (user_id);
+CREATE INDEX IF NOT EXISTS idx_user_sessions_last_seen_at ON user_sessions (last_seen_at);
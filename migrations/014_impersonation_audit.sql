@@ -0,0 +1,16 @@
+-- No foreign keys: the trail must outlive the admin and the user it names.
+CREATE TABLE IF NOT EXISTS impersonation_audit (
+    id          BIGSERIAL     PRIMARY KEY,
+    actor_id    BIGINT        NOT NULL,
+    user_id     BIGINT        NOT NULL,
+    token_id    VARCHAR(64)   NOT NULL,
+    action      VARCHAR(16)   NOT NULL,
+    reason      VARCHAR(500)  NOT NULL DEFAULT '',
+    method      VARCHAR(16)   NOT NULL DEFAULT '',
+    path        VARCHAR(2048) NOT NULL DEFAULT '',
+    ip          VARCHAR(45)   NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ   NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_impersonation_audit_user_id ON impersonation_audit (user_id, id DESC);
+CREATE INDEX IF NOT EXISTS idx_impersonation_audit_token_id ON impersonation_audit (token_id);
@@ -0,0 +1,5 @@
+-- Keyset pagination orders by (sort column, id); each sort gets a matching index
+CREATE INDEX IF NOT EXISTS idx_books_created_at_id ON books (created_at, id);
+CREATE INDEX IF NOT EXISTS idx_books_price_id ON books ((COALESCE(price, 0)), id);
+CREATE INDEX IF NOT EXISTS idx_books_title_id ON books (title, id);
+CREATE INDEX IF NOT EXISTS idx_books_author_lower ON books (LOWER(author));
@@ -0,0 +1,13 @@
+-- Prices are exact amounts in an ISO 4217 currency. Existing prices are
+-- assumed to be in USD. Three decimal places fit every supported currency.
+ALTER TABLE books ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'USD';
+
+UPDATE books SET price = 0 WHERE price IS NULL;
+
+ALTER TABLE books
+    ALTER COLUMN price TYPE NUMERIC(19,3),
+    ALTER COLUMN price SET DEFAULT 0,
+    ALTER COLUMN price SET NOT NULL,
+    ADD CONSTRAINT books_price_non_negative CHECK (price >= 0);
+
+ALTER TABLE books ALTER COLUMN currency DROP DEFAULT;
@@ -0,0 +1,9 @@
+-- published already exists since 001_init_books.sql
+ALTER TABLE books
+    ADD COLUMN IF NOT EXISTS isbn13     CHAR(13),
+    ADD COLUMN IF NOT EXISTS publisher  VARCHAR(255) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS language   VARCHAR(35)  NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS page_count INTEGER      NOT NULL DEFAULT 0 CHECK (page_count >= 0);
+
+-- Books without an ISBN store NULL, which the index does not compare
+CREATE UNIQUE INDEX IF NOT EXISTS idx_books_isbn13 ON books (isbn13);
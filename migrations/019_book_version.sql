@@ -0,0 +1,3 @@
+-- Every update bumps version, which clients send back in If-Match
+ALTER TABLE books
+    ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1 CHECK (version > 0);
@@ -0,0 +1,18 @@
+-- Deleted books and users stay in the trash until the purge job removes
+-- them, so rows that reference them are kept as well
+ALTER TABLE books ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+
+-- Only live rows need unique emails, usernames and ISBNs: deleting an
+-- account frees its email for a new one, and restoring it fails while the
+-- email is taken again
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_email_key;
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_username_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (email) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username ON users (username) WHERE deleted_at IS NULL;
+
+DROP INDEX IF EXISTS idx_books_isbn13;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_books_isbn13 ON books (isbn13) WHERE deleted_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_books_deleted_at ON books (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at) WHERE deleted_at IS NOT NULL;
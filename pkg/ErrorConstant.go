@@ -3,8 +3,67 @@ package pkg
 import "errors"
 
 var (
-	ErrUserNotFound      = errors.New("user not found")
-	ErrUserAlreadyExists = errors.New("user already exists")
-	ErrEmptyPassword     = errors.New("password cannot be empty")
-	ErrValidationError   = errors.New("validation error")
+	ErrUserNotFound             = errors.New("user not found")
+	ErrUserAlreadyExists        = errors.New("user already exists")
+	ErrEmptyPassword            = errors.New("password cannot be empty")
+	ErrValidationError          = errors.New("validation error")
+	ErrInvalidRefreshToken      = errors.New("invalid refresh token")
+	ErrRefreshTokenReused       = errors.New("refresh token reuse detected")
+	ErrForbidden                = errors.New("forbidden")
+	ErrInvalidCurrentPassword   = errors.New("current password is incorrect")
+	ErrInvalidResetToken        = errors.New("invalid or expired reset token")
+	ErrFeatureDisabled          = errors.New("feature is not enabled")
+	ErrInvalidVerificationToken = errors.New("invalid or expired verification token")
+	ErrEmailNotVerified         = errors.New("email address is not verified")
+	ErrInvalidMFAToken          = errors.New("invalid or expired mfa token")
+	ErrInvalidMFACode           = errors.New("invalid two-factor code")
+	ErrMFAAlreadyEnabled        = errors.New("two-factor authentication is already enabled")
+	ErrMFANotEnabled            = errors.New("two-factor authentication is not enabled")
+	ErrMFARequired              = errors.New("two-factor authentication is required for this account")
+	ErrInvalidCredentials       = errors.New("invalid email or password")
+	ErrAccountLocked            = errors.New("account is temporarily locked, try again later")
+	ErrTooManyLoginAttempts     = errors.New("too many login attempts, try again later")
+	ErrAccountSuspended         = errors.New("account is suspended")
+	ErrInvalidAPIKey            = errors.New("invalid api key")
+	ErrInvalidAPIKeyScope       = errors.New("invalid api key scope")
+	ErrAPIKeyNotFound           = errors.New("api key not found")
+	ErrOAuthProviderNotFound    = errors.New("unknown identity provider")
+	ErrInvalidOAuthState        = errors.New("invalid or expired oauth state")
+	ErrOAuthDenied              = errors.New("sign-in was cancelled or denied by the identity provider")
+	ErrOAuthExchangeFailed      = errors.New("could not verify sign-in with the identity provider")
+	ErrOAuthEmailRequired       = errors.New("identity provider did not share an email address")
+	ErrSessionNotFound          = errors.New("session not found")
+	ErrWeakPassword             = errors.New("password does not meet the password policy")
+	ErrInvalidCursor            = errors.New("invalid cursor")
+	ErrInvalidMoney             = errors.New("invalid amount or currency")
+	ErrCurrencyMismatch         = errors.New("amounts are in different currencies")
+	ErrMoneyOverflow            = errors.New("amount is out of range")
+	ErrInvalidISBN              = errors.New("invalid isbn")
+	ErrBookAlreadyExists        = errors.New("a book with this isbn already exists")
+	ErrBookNotFound             = errors.New("book not found")
+	ErrConflict                 = errors.New("the resource was changed since it was read")
+	ErrEditConflict             = errors.New("the resource is being changed concurrently, try again")
+	ErrInvalidPatch             = errors.New("invalid patch document")
+	ErrPatchFailed              = errors.New("patch cannot be applied to the resource")
+	ErrUnsupportedPatch         = errors.New("unsupported patch media type")
 )
+
+// PasswordViolation is one password policy rule a password breaks.
+type PasswordViolation struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// PasswordPolicyError lists every rule a new password breaks. It wraps
+// ErrWeakPassword.
+type PasswordPolicyError struct {
+	Violations []PasswordViolation
+}
+
+func (e *PasswordPolicyError) Error() string {
+	return ErrWeakPassword.Error()
+}
+
+func (e *PasswordPolicyError) Unwrap() error {
+	return ErrWeakPassword
+}
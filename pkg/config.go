@@ -2,6 +2,7 @@ package pkg
 
 import (
 	"os"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -20,9 +21,11 @@ func LoadConfig() (*Config, error) {
 }
 
 type Config struct {
-	App  AppConfig  `yaml:"app"`
-	DB   DBConfig   `yaml:"db"`
-	Auth AuthConfig `yaml:"auth"`
+	App      AppConfig      `yaml:"app"`
+	DB       DBConfig       `yaml:"db"`
+	Auth     AuthConfig     `yaml:"auth"`
+	Notifier NotifierConfig `yaml:"notifier"`
+	Trash    TrashConfig    `yaml:"trash"`
 }
 
 type AppConfig struct {
@@ -40,9 +43,154 @@ type DBConfig struct {
 }
 
 type AuthConfig struct {
-	JWT JWTConfig `yaml:"jwt"`
+	JWT               JWTConfig               `yaml:"jwt"`
+	RBAC              RBACConfig              `yaml:"rbac"`
+	Revocation        RevocationConfig        `yaml:"revocation"`
+	PasswordReset     PasswordResetConfig     `yaml:"password_reset"`
+	EmailVerification EmailVerificationConfig `yaml:"email_verification"`
+	MFA               MFAConfig               `yaml:"mfa"`
+	Lockout           LockoutConfig           `yaml:"lockout"`
+	OIDC              OIDCConfig              `yaml:"oidc"`
+	PasswordHashing   PasswordHashingConfig   `yaml:"password_hashing"`
+	PasswordPolicy    PasswordPolicyConfig    `yaml:"password_policy"`
+	Impersonation     ImpersonationConfig     `yaml:"impersonation"`
 }
 
+// JWTConfig selects how tokens are signed. Without a signing key, tokens are
+// signed with Secret (HS256). A signing key file switches to RS256 or EdDSA
+// depending on the key type; VerificationKeys are older public keys that are
+// still accepted while rotating.
 type JWTConfig struct {
-	Secret string `yaml:"secret"`
+	Secret           string         `yaml:"secret"`
+	AccessTTL        time.Duration  `yaml:"access_ttl"`
+	RefreshTTL       time.Duration  `yaml:"refresh_ttl"`
+	Issuer           string         `yaml:"issuer"`
+	Audience         string         `yaml:"audience"`
+	SigningKey       JWTKeyConfig   `yaml:"signing_key"`
+	VerificationKeys []JWTKeyConfig `yaml:"verification_keys"`
+}
+
+// JWTKeyConfig points at a PEM file. ID is the "kid"; when empty the key's
+// RFC 7638 thumbprint is used.
+type JWTKeyConfig struct {
+	ID   string `yaml:"id"`
+	File string `yaml:"file"`
+}
+
+// RBACConfig maps each role to the permissions it is granted, e.g.
+// "books:read". The special permission "*" grants everything.
+type RBACConfig struct {
+	Roles map[string][]string `yaml:"roles"`
+}
+
+// RevocationConfig selects where revoked tokens are tracked: "postgres"
+// (default) or "memory".
+type RevocationConfig struct {
+	Store           string        `yaml:"store"`
+	CleanupInterval time.Duration `yaml:"cleanup_interval"`
+}
+
+type PasswordResetConfig struct {
+	TTL time.Duration `yaml:"ttl"`
+	URL string        `yaml:"url"`
+}
+
+// EmailVerificationConfig controls verification links sent on registration.
+// Required blocks login until the address is verified.
+type EmailVerificationConfig struct {
+	Required bool          `yaml:"required"`
+	TTL      time.Duration `yaml:"ttl"`
+	URL      string        `yaml:"url"`
+}
+
+// MFAConfig controls TOTP two-factor authentication. Issuer is the account
+// label shown in authenticator apps; users with one of RequiredRoles must
+// enroll before they can log in.
+type MFAConfig struct {
+	Issuer        string   `yaml:"issuer"`
+	RequiredRoles []string `yaml:"required_roles"`
+}
+
+// LockoutConfig throttles logins. Store is "postgres" (default) or
+// "memory". After MaxAttempts failures for an account (IPMaxAttempts for a
+// client IP) within Window, logins are locked for BaseLockout, doubling with
+// each further failure up to MaxLockout.
+type LockoutConfig struct {
+	Store         string        `yaml:"store"`
+	MaxAttempts   int           `yaml:"max_attempts"`
+	IPMaxAttempts int           `yaml:"ip_max_attempts"`
+	Window        time.Duration `yaml:"window"`
+	BaseLockout   time.Duration `yaml:"base_lockout"`
+	MaxLockout    time.Duration `yaml:"max_lockout"`
+}
+
+// PasswordPolicyConfig sets the rules for new passwords. History is how many
+// of the latest passwords may not be reused. BreachedPasswordsFile is a
+// sorted "SHA1:COUNT" Pwned Passwords file or a directory of hash-prefix
+// range files; the check is off when empty.
+type PasswordPolicyConfig struct {
+	MinLength             int    `yaml:"min_length"`
+	MaxLength             int    `yaml:"max_length"`
+	RequireUpper          bool   `yaml:"require_upper"`
+	RequireLower          bool   `yaml:"require_lower"`
+	RequireDigit          bool   `yaml:"require_digit"`
+	RequireSymbol         bool   `yaml:"require_symbol"`
+	ForbidPersonalInfo    bool   `yaml:"forbid_personal_info"`
+	History               int    `yaml:"history"`
+	BreachedPasswordsFile string `yaml:"breached_passwords_file"`
+}
+
+// ImpersonationConfig sets the lifetime of impersonation tokens, 15 minutes
+// by default and never longer than access tokens.
+type ImpersonationConfig struct {
+	TTL time.Duration `yaml:"ttl"`
+}
+
+// OIDCConfig lists the OpenID Connect providers users can sign in with,
+// keyed by the name used in /v1/oauth/:provider routes.
+type OIDCConfig struct {
+	Providers map[string]OIDCProviderConfig `yaml:"providers"`
+}
+
+// OIDCProviderConfig is a client registered at a provider. RedirectURL must
+// point at /v1/oauth/:provider/callback.
+type OIDCProviderConfig struct {
+	Issuer       string   `yaml:"issuer"`
+	ClientID     string   `yaml:"client_id"`
+	ClientSecret string   `yaml:"client_secret"`
+	RedirectURL  string   `yaml:"redirect_url"`
+	Scopes       []string `yaml:"scopes"`
+}
+
+// PasswordHashingConfig selects the algorithm for new password hashes:
+// "bcrypt" (default) or "argon2id". Hashes made with another algorithm or
+// other parameters are upgraded on the next successful login.
+type PasswordHashingConfig struct {
+	Algorithm  string         `yaml:"algorithm"`
+	BcryptCost int            `yaml:"bcrypt_cost"`
+	Argon2id   Argon2idConfig `yaml:"argon2id"`
+}
+
+// Argon2idConfig holds the Argon2id parameters. Memory is in KiB; zero fields
+// use the adapter defaults.
+type Argon2idConfig struct {
+	Memory      uint32 `yaml:"memory"`
+	Iterations  uint32 `yaml:"iterations"`
+	Parallelism uint8  `yaml:"parallelism"`
+	SaltLength  uint32 `yaml:"salt_length"`
+	KeyLength   uint32 `yaml:"key_length"`
+}
+
+// NotifierConfig controls where notifications are written. An empty File
+// writes them to stdout.
+type NotifierConfig struct {
+	File string `yaml:"file"`
+}
+
+// TrashConfig controls the purge job for soft-deleted books and users. Rows
+// stay in the trash for Retention (30 days by default) and the job runs
+// every PurgeInterval (hourly by default).
+type TrashConfig struct {
+	Retention     time.Duration `yaml:"retention"`
+	PurgeInterval time.Duration `yaml:"purge_interval"`
 }
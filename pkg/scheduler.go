@@ -0,0 +1,21 @@
+package pkg
+
+import (
+	"context"
+	"time"
+)
+
+// Every runs fn every interval until ctx is cancelled. It is meant to be
+// started in its own goroutine for background maintenance jobs.
+func Every(ctx context.Context, interval time.Duration, fn func(ctx context.Context)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			fn(ctx)
+		}
+	}
+}
@@ -1,14 +1,27 @@
 package pkg
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"reflect"
+	"strings"
 
 	"github.com/go-playground/validator/v10"
 )
 
 func ValidateStruct(ctx context.Context, obj any) error {
 	var validate = validator.New()
+	// Report fields by the names clients send
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
 	if err := validate.StructCtx(ctx, obj); err != nil {
 		var validationErrors validator.ValidationErrors
 		if errors.As(err, &validationErrors) {
@@ -18,3 +31,111 @@ func ValidateStruct(ctx context.Context, obj any) error {
 	}
 	return nil
 }
+
+// FieldError is one invalid field of a request.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists every invalid field of a request. It wraps
+// ErrValidationError.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	return ErrValidationError.Error()
+}
+
+func (e *ValidationError) Unwrap() error {
+	return ErrValidationError
+}
+
+// Add records that field broke rule.
+func (e *ValidationError) Add(field, rule, message string) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Rule: rule, Message: message})
+}
+
+// AddValidatorErrors records the field errors of err, an error returned by
+// ValidateStruct. It returns err when err has no field errors.
+func (e *ValidationError) AddValidatorErrors(err error) error {
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return err
+	}
+	for _, fe := range validationErrors {
+		e.Add(fe.Field(), fe.Tag(), fieldMessage(fe))
+	}
+	return nil
+}
+
+// OrNil returns e when it lists any field, and nil otherwise.
+func (e *ValidationError) OrNil() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+// DecodeJSON decodes data into v like json.Unmarshal, but reports members v
+// has no field for and values of the wrong JSON type as a *ValidationError.
+// Other decoding errors are reported as ErrValidationError.
+func DecodeJSON(data []byte, v any) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(v)
+	if err == nil {
+		return nil
+	}
+	invalid := &ValidationError{}
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		invalid.Add(typeErr.Field, "type", "must be a JSON "+jsonType(typeErr.Type))
+		return invalid
+	}
+	// encoding/json has no error type for unknown fields
+	if name, ok := strings.CutPrefix(err.Error(), `json: unknown field "`); ok {
+		invalid.Add(strings.TrimSuffix(name, `"`), "unknown", "is not a field of this resource")
+		return invalid
+	}
+	return ErrValidationError
+}
+
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.String:
+		return "string"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Map, reflect.Struct:
+		return "object"
+	case reflect.Pointer:
+		return jsonType(t.Elem())
+	}
+	return "number"
+}
+
+func fieldMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "max":
+		return fmt.Sprintf("must be at most %s characters long", fe.Param())
+	case "min":
+		return fmt.Sprintf("must be at least %s characters long", fe.Param())
+	case "gte":
+		if fe.Param() == "0" {
+			return "must not be negative"
+		}
+		return "must be at least " + fe.Param()
+	case "email":
+		return "must be an email address"
+	case "bcp47_language_tag":
+		return "must be a BCP 47 language tag such as en or pt-BR"
+	}
+	return "is invalid"
+}